@@ -1,57 +1,223 @@
 package api
 
 import (
+	"chat/config"
 	"chat/controllers"
+	"chat/health"
+	"chat/linkpreview"
+	"chat/metrics"
 	"chat/middlewares"
+	"chat/openapi"
+	"chat/origins"
+	"chat/ratelimit"
 	"chat/repositories"
 	"chat/services"
+	"log/slog"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(db *gorm.DB) (*gin.Engine, services.WebSocketService) {
-	r := gin.Default()
+// v1 API を廃止予定とした日（Deprecation ヘッダーで通知する）
+var v1DeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
 
-	// CORS ミドルウェアを適用
-	r.Use(middlewares.CORSConfig())
+// SSE のルート。接続が長時間続くため、処理時間の上限とリクエストのスパンの対象外にする
+const sseRoute = "/api/v2/spaces/:spaceId/events"
+
+// main で起動・停止するバックグラウンド処理
+type Workers struct {
+	WebSocket services.WebSocketService
+	Webhooks  services.WebhookDispatcher
+	Scheduler services.MessageScheduler
+}
+
+func RegisterRoutes(db *gorm.DB, cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) (*gin.Engine, Workers) {
+	r := gin.New()
+
+	// 最も外側でスパンを開始し、以降のログ・サービス・クエリをその子にする
+	r.Use(middlewares.Tracing(cfg.Tracing.ServiceName, "/api/ws", "/api/v2/ws", "/health", cfg.Metrics.Path, sseRoute))
+
+	// gin 標準のロガーの代わりに、リクエスト ID 付きの構造化ログを出力する
+	// panic 時も 500 として記録されるよう、Recovery より外側に置く
+	r.Use(middlewares.RequestLogger(logger))
+	r.Use(middlewares.Metrics(m))
+	r.Use(middlewares.Recovery(logger))
+
+	// X-Forwarded-For は信頼するプロキシからのものだけ使う（config で検証済み）
+	_ = r.SetTrustedProxies(cfg.Server.TrustedProxies)
+
+	// CORS ミドルウェアを適用（許可するオリジンは WebSocket と共通）
+	allowOrigins := origins.MustNew(cfg.CORS.AllowOrigins)
+	r.Use(middlewares.CORSConfig(allowOrigins))
+
+	// レスポンス言語の決定とエラーレスポンスの共通化
+	r.Use(middlewares.Language())
+	r.Use(middlewares.ErrorHandler(logger))
+
+	// クライアントの切断と同様に、処理時間の上限でも DB の処理を打ち切る
+	r.Use(middlewares.Timeout(cfg.Server.RequestTimeout, sseRoute))
 
 	// DIの実装
 	userRepo := repositories.NewUserRepository(db)
-	userService := services.NewUserService(userRepo)
+	loginEventRepo := repositories.NewLoginEventRepository(db)
+	userService := services.NewUserService(userRepo, loginEventRepo, cfg.JWT, cfg.Login, logger)
 	userController := controllers.NewUserController(userService)
 
+	spaceRepo := repositories.NewSpaceRepository(db)
 	messageRepo := repositories.NewMessageRepository(db)
-	messageService := services.NewMessageService(messageRepo)
-	messageController := controllers.NewMessageController(messageService)
 
-	spaceRepo := repositories.NewSpaceRepository(db)
-	spaceService := services.NewSpaceService(spaceRepo)
+	// Webhook の DI 設定（メッセージの保存・削除を通知する）
+	webhookRepo := repositories.NewWebhookRepository(db)
+	webhookDispatcher := services.NewWebhookDispatcher(webhookRepo, cfg.Webhook, logger, m)
+	webhookService := services.NewWebhookService(webhookRepo, spaceRepo, userRepo, webhookDispatcher, logger)
+	webhookController := controllers.NewWebhookController(webhookService)
+
+	// WebSocket・SSE の DI 設定（REST API で投稿したメッセージもハブから配信する）
+	webSocketService := services.NewWebSocketService(cfg.WebSocket, cfg.SSE, logger, m)
+
+	messageService := services.NewMessageService(messageRepo, spaceRepo, userRepo, webSocketService, webhookService, m)
+
+	// スラッシュコマンド（REST API・WebSocket のどちらの投稿も保存する前に実行する）
+	botRepo := repositories.NewBotRepository(db)
+	commandService := services.NewCommandService(messageService, spaceRepo, userRepo, botRepo, cfg.Webhook, logger)
+	botController := controllers.NewBotController(services.NewBotService(botRepo, userRepo, commandService))
+
+	// 予約投稿・リマインダー（送信時刻になったらメッセージサービス経由で投稿する）
+	scheduledMessageRepo := repositories.NewScheduledMessageRepository(db)
+	scheduledMessageService := services.NewScheduledMessageService(scheduledMessageRepo, spaceRepo, userRepo, cfg.Scheduler)
+	commandService.Register("remind", services.RemindUsage, scheduledMessageService.Remind)
+	scheduledMessageController := controllers.NewScheduledMessageController(scheduledMessageService)
+	messageScheduler := services.NewMessageScheduler(scheduledMessageRepo, messageService, cfg.Scheduler, logger, m)
+
+	// メッセージの保存（メッセージを削除すると保存も外部キーで削除される）
+	bookmarkService := services.NewBookmarkService(repositories.NewBookmarkRepository(db), messageRepo, userRepo)
+	bookmarkController := controllers.NewBookmarkController(bookmarkService)
+
+	// メッセージ中のリンクのプレビュー（取得結果はプロセス内にキャッシュする）
+	linkPreviewService := services.NewLinkPreviewService(messageRepo, linkpreview.New(cfg.Preview), cfg.Preview)
+	linkPreviewController := controllers.NewLinkPreviewController(linkPreviewService)
+
+	spaceService := services.NewSpaceService(spaceRepo, userRepo)
+	messageController := controllers.NewMessageController(messageService, commandService, logger)
+	webSocketController := controllers.NewWebSocketController(webSocketService, spaceService, messageService, commandService, cfg.WebSocket, cfg.RateLimit, allowOrigins, logger)
+
+	// 受信用 Webhook（外部のシステムからの投稿もメッセージサービス経由で配信する）
+	incomingWebhookService := services.NewIncomingWebhookService(repositories.NewIncomingWebhookRepository(db), spaceRepo, userRepo, messageService)
+	incomingWebhookController := controllers.NewIncomingWebhookController(incomingWebhookService)
+
 	spaceController := controllers.NewSpaceController(spaceService)
 
-	// WebSocket の DI 設定
-	webSocketService := services.NewWebSocketService(messageRepo)
-	webSocketController := controllers.NewWebSocketController(webSocketService)
+	eventController := controllers.NewEventController(spaceService, messageService, webSocketService, cfg.SSE, logger)
+
+	// トークンがあればユーザーを識別（レート制限のキーに使う）
+	r.Use(middlewares.Authenticate(userService))
+
+	// ルートごとのレート制限（v1 と v2 で同じバケットを使う）
+	limit := func(policy config.RatePolicy) gin.HandlerFunc {
+		if !cfg.RateLimit.Enabled {
+			return func(ctx *gin.Context) { ctx.Next() }
+		}
+		return middlewares.RateLimit(ratelimit.New(policy))
+	}
+	defaultLimit := limit(cfg.RateLimit.Default)
+	registerLimit := limit(cfg.RateLimit.Register)
+	loginLimit := limit(cfg.RateLimit.Login)
+	messagesLimit := limit(cfg.RateLimit.Messages)
+
+	// v2 API（リソース単位のパス）
+	v2 := r.Group("/api/v2", defaultLimit)
+
+	v2.POST("/users", registerLimit, userController.RegisterUser)
+	v2.POST("/sessions", loginLimit, userController.CreateSession)
+
+	v2.GET("/spaces", spaceController.GetSpaces)
+	v2.POST("/spaces", spaceController.PostSpace)
+	v2.GET("/spaces/:spaceId", spaceController.GetSpace)
+
+	v2.GET("/spaces/:spaceId/messages", messageController.ListSpaceMessages)
+	v2.POST("/spaces/:spaceId/messages", messagesLimit, messageController.PostSpaceMessage)
+	v2.GET("/spaces/:spaceId/messages/:messageId", messageController.GetSpaceMessage)
+	v2.DELETE("/spaces/:spaceId/messages/:messageId", messageController.DeleteSpaceMessage)
+	v2.GET("/spaces/:spaceId/messages/:messageId/previews", linkPreviewController.ListMessagePreviews)
+
+	// ピン留め（変更はスペースの所有者と管理者のみ。権限はサービス層で確認する）
+	v2.GET("/spaces/:spaceId/pins", messageController.ListPinnedMessages)
+	v2.PUT("/spaces/:spaceId/pins/:messageId", middlewares.RequireAuth(), messageController.PutPin)
+	v2.DELETE("/spaces/:spaceId/pins/:messageId", middlewares.RequireAuth(), messageController.DeletePin)
+
+	// メッセージの保存（保存は本人にしか見えない。一覧は全スペース分）
+	v2.PUT("/spaces/:spaceId/messages/:messageId/bookmark", middlewares.RequireAuth(), bookmarkController.PutBookmark)
+	v2.DELETE("/spaces/:spaceId/messages/:messageId/bookmark", middlewares.RequireAuth(), bookmarkController.DeleteBookmark)
+	v2.GET("/bookmarks", middlewares.RequireAuth(), bookmarkController.ListBookmarks)
+
+	// 予約投稿（自分の予約のみ操作できる）
+	v2.POST("/spaces/:spaceId/scheduled-messages", middlewares.RequireAuth(), scheduledMessageController.PostScheduledMessage)
+	v2.GET("/spaces/:spaceId/scheduled-messages", middlewares.RequireAuth(), scheduledMessageController.ListScheduledMessages)
+	v2.DELETE("/spaces/:spaceId/scheduled-messages/:scheduledId", middlewares.RequireAuth(), scheduledMessageController.DeleteScheduledMessage)
+
+	// WebSocket を使えないクライアント向けの配信（送信は上の POST で行う）
+	v2.GET("/spaces/:spaceId/events", eventController.Stream)
+
+	// スペースの Webhook（所有者と管理者のみ。権限はサービス層で確認する）
+	v2.POST("/spaces/:spaceId/webhooks", middlewares.RequireAuth(), webhookController.PostWebhook)
+	v2.GET("/spaces/:spaceId/webhooks", middlewares.RequireAuth(), webhookController.ListWebhooks)
+	v2.DELETE("/spaces/:spaceId/webhooks/:webhookId", middlewares.RequireAuth(), webhookController.DeleteWebhook)
+	v2.GET("/spaces/:spaceId/webhooks/:webhookId/deliveries", middlewares.RequireAuth(), webhookController.ListDeliveries)
+
+	v2.POST("/spaces/:spaceId/incoming-webhooks", middlewares.RequireAuth(), incomingWebhookController.PostIncomingWebhook)
+	v2.GET("/spaces/:spaceId/incoming-webhooks", middlewares.RequireAuth(), incomingWebhookController.ListIncomingWebhooks)
+	v2.DELETE("/spaces/:spaceId/incoming-webhooks/:hookId", middlewares.RequireAuth(), incomingWebhookController.DeleteIncomingWebhook)
+
+	// 受信用 Webhook への投稿（ユーザーではなく X-Hook-Token で認証する）
+	v2.POST("/hooks/:hookId", messagesLimit, incomingWebhookController.PostHookMessage)
+
+	v2.GET("/ws", webSocketController.HandleConnections)
+
+	// 管理者用 API（権限はサービス層で確認する）
+	v2.DELETE("/admin/users/:username/lock", middlewares.RequireAuth(), userController.DeleteLock)
+
+	// スラッシュコマンドに応答するボット（管理者のみ）
+	v2.POST("/bots", middlewares.RequireAuth(), botController.PostBot)
+	v2.GET("/bots", middlewares.RequireAuth(), botController.ListBots)
+	v2.DELETE("/bots/:botId", middlewares.RequireAuth(), botController.DeleteBot)
+
+	// v1 API（廃止予定。既存のクライアントのために v2 と同じ処理を残す）
+	api := r.Group("/api", defaultLimit, middlewares.Deprecated(v1DeprecatedAt, "/docs"))
+
+	api.POST("/register", registerLimit, userController.RegisterUser)
+	api.POST("/login", loginLimit, userController.LoginUser)
+
+	api.GET("/messages", messageController.GetMessages)
+	api.POST("/messages/create", messagesLimit, messageController.CreateMessage)
+	api.DELETE("/messages", messageController.DeleteMessage)
+
+	api.POST("/spaces", spaceController.CreateSpace)
+	api.GET("/spaces/list", spaceController.GetSpaces)
+
+	api.GET("/ws", webSocketController.HandleConnections)
 
-	// API ルート
-	// ルーティング設定
-	r.POST("/api/register", userController.RegisterUser)
-	r.POST("/api/login", userController.LoginUser)
+	api.POST("/admin/users/:username/unlock", middlewares.RequireAuth(), userController.UnlockUser)
 
-	r.GET("/api/messages", messageController.GetMessages)
-	r.POST("/api/messages/create", messageController.CreateMessage)
-	r.DELETE("/api/messages", messageController.DeleteMessage)
+	// メトリクス
+	if cfg.Metrics.Enabled {
+		r.GET(cfg.Metrics.Path, gin.WrapH(m.Handler()))
+	}
 
-	r.POST("/api/spaces", spaceController.CreateSpace)
-	r.GET("/api/spaces/list", spaceController.GetSpaces)
+	// ヘルスチェック（/health は既存のロードバランサー設定のため readiness と同じ）
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Register("database", health.Database(db))
+	checker.Register("migrations", health.Migrations(db))
+	checker.Register("websocket_hub", webSocketService.Ping)
+	healthController := controllers.NewHealthController(checker)
 
-	// WebSocket
-	r.GET("/api/ws", webSocketController.HandleConnections)
+	r.GET("/health/live", healthController.Live)
+	r.GET("/health/ready", healthController.Ready)
+	r.GET("/health", healthController.Ready)
 
-	// ヘルスチェック
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy"})
-	})
+	// API ドキュメント
+	r.GET("/docs", gin.WrapH(openapi.UIHandler()))
+	r.GET("/docs/openapi.yaml", gin.WrapH(openapi.SpecHandler()))
 
-	return r, webSocketService
+	return r, Workers{WebSocket: webSocketService, Webhooks: webhookDispatcher, Scheduler: messageScheduler}
 }
@@ -0,0 +1,196 @@
+package api_test
+
+import (
+	"chat/api"
+	"chat/config"
+	"chat/logging"
+	"chat/metrics"
+	"chat/openapi"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	r, _ := setupRouterDB(t)
+	return r
+}
+
+func setupRouterDB(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{})
+	require.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.JWT.Secret = "secret"
+	r, _ := api.RegisterRoutes(db, cfg, logging.Discard(), metrics.New())
+	return r, mock
+}
+
+type spec struct {
+	Paths map[string]map[string]any `yaml:"paths"`
+}
+
+// gin のパラメーター（:username, *path）を OpenAPI の形式（{username}）にする
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// 登録したルートがすべて仕様に書かれていて、仕様にあるルートがすべて登録されていること
+func TestOpenAPI_CoversRoutes(t *testing.T) {
+	var doc spec
+	require.NoError(t, yaml.Unmarshal(openapi.Spec, &doc))
+
+	registered := map[string]bool{}
+	for _, route := range setupRouter(t).Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		_, ok := doc.Paths[path][method]
+		assert.True(t, ok, "%s %s が openapi.yaml にありません", route.Method, path)
+	}
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			assert.True(t, registered[method+" "+path], "openapi.yaml の %s %s は登録されていません", strings.ToUpper(method), path)
+		}
+	}
+}
+
+// $ref の参照先がすべて定義されていること
+func TestOpenAPI_Refs(t *testing.T) {
+	var doc map[string]any
+	require.NoError(t, yaml.Unmarshal(openapi.Spec, &doc))
+
+	var walk func(node any)
+	walk = func(node any) {
+		switch v := node.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				assert.True(t, resolve(doc, ref), "参照先がありません: %s", ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// "#/components/schemas/Message" のような参照を辿る
+func resolve(doc map[string]any, ref string) bool {
+	var node any = doc
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return false
+		}
+		if node, ok = m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDocs(t *testing.T) {
+	r := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/docs/openapi.yaml", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, string(openapi.Spec), w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `spec-url="/docs/openapi.yaml"`)
+}
+
+// v1 のルートだけが Deprecation ヘッダーを返し、仕様でも deprecated になっていること
+func TestV1Deprecated(t *testing.T) {
+	r := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/spaces", strings.NewReader("{")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Regexp(t, `^@\d+$`, w.Header().Get("Deprecation"))
+	assert.Equal(t, `</docs>; rel="deprecation"; type="text/html"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/v2/spaces", strings.NewReader("{")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Link"))
+
+	var doc spec
+	require.NoError(t, yaml.Unmarshal(openapi.Spec, &doc))
+	for path, operations := range doc.Paths {
+		if !strings.HasPrefix(path, "/api/") {
+			continue
+		}
+		v1 := !strings.HasPrefix(path, "/api/v2/")
+		for method, op := range operations {
+			if method == "parameters" {
+				continue
+			}
+			deprecated, _ := op.(map[string]any)["deprecated"].(bool)
+			assert.Equal(t, v1, deprecated, "%s %s の deprecated", strings.ToUpper(method), path)
+		}
+	}
+}
+
+// 期限切れのトークンを送り続けるクライアントもログインし直せる（ログインが必要なルートだけが 401 を返す）
+func TestExpiredToken_Login(t *testing.T) {
+	r, mock := setupRouterDB(t)
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": "alice",
+		"exp":      time.Now().Add(-time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1`).
+		WithArgs("alice", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password"}).AddRow(7, "alice", "password1"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "login_events"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v2/sessions", strings.NewReader(`{"username":"alice","password":"password1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+expired)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"token"`)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v2/bookmarks", nil)
+	req.Header.Set("Authorization", "Bearer "+expired)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_token"`)
+}
@@ -0,0 +1,89 @@
+package apperrors
+
+import (
+	"errors"
+	"time"
+)
+
+// エラーの種類（HTTP ステータスとの対応はミドルウェアで行う）
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrInternal     = errors.New("internal error")
+	ErrTimeout      = errors.New("timeout")  // 処理時間の上限を超えた
+	ErrCanceled     = errors.New("canceled") // クライアントが切断し、処理を打ち切った
+)
+
+// 機械可読なコードと利用者向けメッセージを持つドメインエラー
+//
+// errors.Is で Kind（ErrNotFound など）とも、同じ Code の *Error とも一致する。
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Err     error
+	// 入力項目ごとのエラー（バリデーションエラーのみ）
+	Fields []FieldError
+	// 再試行できるまでの時間（レート制限・ロックのみ。Retry-After ヘッダーになる）
+	RetryAfter time.Duration
+}
+
+// 入力項目ごとのバリデーションエラー
+type FieldError struct {
+	// リクエスト上の項目名（JSON のキーやクエリ名）
+	Field string
+	// 機械可読な理由（"required", "too_long" など）
+	Code string
+	// ルールの引数（最大文字数など）
+	Param string
+}
+
+func New(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// 同じコードのエラーは同一とみなす
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// 項目ごとのエラーを付けたコピーを返す
+func (e *Error) WithFields(fields []FieldError) *Error {
+	withFields := *e
+	withFields.Fields = fields
+	return &withFields
+}
+
+// 再試行までの時間を付けたコピーを返す
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	withRetry := *e
+	withRetry.RetryAfter = d
+	return &withRetry
+}
+
+// 原因となったエラーを付けたコピーを返す
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
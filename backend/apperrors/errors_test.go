@@ -0,0 +1,42 @@
+package apperrors_test
+
+import (
+	"chat/apperrors"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errSample = apperrors.New(apperrors.ErrNotFound, "sample_not_found", "見つかりません")
+
+func TestError_Is(t *testing.T) {
+	assert.ErrorIs(t, errSample, apperrors.ErrNotFound)
+	assert.NotErrorIs(t, errSample, apperrors.ErrConflict)
+
+	cause := errors.New("db error")
+	wrapped := errSample.Wrap(cause)
+	assert.ErrorIs(t, wrapped, errSample)
+	assert.ErrorIs(t, wrapped, apperrors.ErrNotFound)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.Equal(t, "見つかりません: db error", wrapped.Error())
+
+	// fmt.Errorf でラップされていても判定できる
+	assert.ErrorIs(t, fmt.Errorf("context: %w", wrapped), errSample)
+}
+
+func TestError_As(t *testing.T) {
+	var appErr *apperrors.Error
+	assert.True(t, errors.As(fmt.Errorf("context: %w", errSample), &appErr))
+	assert.Equal(t, "sample_not_found", appErr.Code)
+	assert.Equal(t, "見つかりません", errSample.Error())
+}
+
+func TestError_WithRetryAfter(t *testing.T) {
+	limited := errSample.WithRetryAfter(30 * time.Second)
+	assert.Equal(t, 30*time.Second, limited.RetryAfter)
+	assert.Zero(t, errSample.RetryAfter)
+	assert.ErrorIs(t, limited, errSample)
+}
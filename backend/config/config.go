@@ -0,0 +1,728 @@
+package config
+
+import (
+	"bufio"
+	"chat/origins"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// アプリケーション全体の設定
+//
+// 値は「デフォルト値 → YAML ファイル(CONFIG_FILE) → .env → 環境変数」の順に上書きされる。
+type Config struct {
+	Server    ServerConfig     `yaml:"server"`
+	Database  DatabaseConfig   `yaml:"database"`
+	CORS      CORSConfig       `yaml:"cors"`
+	JWT       JWTConfig        `yaml:"jwt"`
+	WebSocket WebSocketConfig  `yaml:"websocket"`
+	SSE       SSEConfig        `yaml:"sse"`
+	Webhook   WebhookConfig    `yaml:"webhook"`
+	Scheduler SchedulerConfig  `yaml:"scheduler"`
+	Preview   PreviewConfig    `yaml:"link_preview"`
+	RateLimit RateLimitConfig  `yaml:"rate_limit"`
+	Login     LoginGuardConfig `yaml:"login_guard"`
+	Log       LogConfig        `yaml:"log"`
+	Metrics   MetricsConfig    `yaml:"metrics"`
+	Health    HealthConfig     `yaml:"health"`
+	Tracing   TracingConfig    `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// REST API 1リクエストあたりの処理時間の上限（超えると DB の処理を打ち切る）
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// X-Forwarded-For を信頼するプロキシ（未指定なら接続元アドレスをそのまま使う）
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	// 起動時に未適用のマイグレーションを実行するか
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type CORSConfig struct {
+	// REST API と WebSocket の両方で許可するオリジン（"https://*.example.com" でサブドメインを許可）
+	AllowOrigins []string `yaml:"allow_origins"`
+}
+
+type JWTConfig struct {
+	Secret string        `yaml:"secret"`
+	TTL    time.Duration `yaml:"ttl"`
+}
+
+type WebSocketConfig struct {
+	// 1フレームあたりの最大バイト数
+	MaxMessageSize int64 `yaml:"max_message_size"`
+	// クライアントへの書き込みタイムアウト
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// pong を待つ時間（これを超えると切断）
+	PongTimeout time.Duration `yaml:"pong_timeout"`
+	// Broadcast チャネルのバッファサイズ
+	BroadcastBuffer int `yaml:"broadcast_buffer"`
+	// 受信した1メッセージの保存にかける時間の上限
+	MessageTimeout time.Duration `yaml:"message_timeout"`
+}
+
+// Server-Sent Events（WebSocket を使えない環境向けの配信）の設定
+type SSEConfig struct {
+	// 無通信で切断するプロキシ対策にコメント行を送る間隔
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// 接続ごとの未送信イベントの上限（超えると切断し、クライアントの再接続に任せる）
+	Buffer int `yaml:"buffer"`
+	// 再接続時（Last-Event-ID）に DB から再送するメッセージ数の上限
+	ReplayLimit int `yaml:"replay_limit"`
+}
+
+// スペースのイベントを通知する Webhook の送信設定
+type WebhookConfig struct {
+	// 1回の送信のタイムアウト
+	Timeout time.Duration `yaml:"timeout"`
+	// この回数送信に失敗すると配信を諦める
+	MaxAttempts int `yaml:"max_attempts"`
+	// 再送までの待ち時間（失敗のたびに倍になる）と上限
+	BaseBackoff time.Duration `yaml:"base_backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	// 送信待ちの配信を確認する間隔（新しいイベントはすぐに送る）
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// 同時に送信する数
+	Concurrency int `yaml:"concurrency"`
+	// ループバック・プライベートアドレスへの送信を許可する（社内ツール向け・開発用）
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// 予約投稿・リマインダーの送信設定
+type SchedulerConfig struct {
+	// 送信時刻になった予約を確認する間隔
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// 1回に取り出す予約の数
+	BatchSize int `yaml:"batch_size"`
+	// 取り出した予約を他のインスタンスが取り出さない時間（送信中に落ちた場合はこの後に再送する）
+	Lease time.Duration `yaml:"lease"`
+	// 1件の投稿の上限。lease が切れて他のインスタンスが再送する前に送信済みを記録できるよう、lease の半分以下にする
+	SendTimeout time.Duration `yaml:"send_timeout"`
+	// この回数送信に失敗すると諦める
+	MaxAttempts int `yaml:"max_attempts"`
+	// 再送までの待ち時間（失敗のたびに倍になる）と上限
+	BaseBackoff time.Duration `yaml:"base_backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	// 予約できる最も先の日時（現在からの時間）
+	MaxDelay time.Duration `yaml:"max_delay"`
+}
+
+// メッセージ中のリンクのプレビュー（Open Graph）の取得設定
+type PreviewConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 1つの URL の取得のタイムアウト（リダイレクトを含む）
+	Timeout time.Duration `yaml:"timeout"`
+	// 取得結果（失敗を含む）をキャッシュする時間と件数
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+	CacheSize int           `yaml:"cache_size"`
+	// 1つのメッセージでプレビューを取得する URL の数
+	MaxURLs int `yaml:"max_urls"`
+	// ループバック・プライベートアドレスからの取得を許可する（開発用）
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// レート制限の設定（キーは認証済みユーザー、未認証ならクライアント IP）
+type RateLimitConfig struct {
+	Enabled  bool       `yaml:"enabled"`
+	Default  RatePolicy `yaml:"default"`
+	Login    RatePolicy `yaml:"login"`
+	Register RatePolicy `yaml:"register"`
+	Messages RatePolicy `yaml:"messages"`
+	// WebSocket 1接続あたりの受信メッセージ数
+	WebSocket RatePolicy `yaml:"websocket"`
+}
+
+// Per あたり Requests 回まで（バーストも Requests 回まで）許可するトークンバケット
+type RatePolicy struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+}
+
+// ログイン失敗によるバックオフとアカウントロックの設定
+//
+// 失敗回数・ロックは各インスタンスのメモリで数え、インスタンス間で共有しない（再起動で消える）。
+// ロードバランサーの配下で N 台動かすと、総当たりは最大で MaxFailures・IPMaxFailures の N 倍まで試せる。
+// 失敗は login_events にも記録されるため、監査・監視はそちらで行う。
+type LoginGuardConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ユーザー名ごとの、ロックまでの連続失敗回数
+	MaxFailures int `yaml:"max_failures"`
+	// IP ごとの、ロックまでの連続失敗回数（複数アカウントへの総当たり対策）
+	IPMaxFailures int `yaml:"ip_max_failures"`
+	// 1回目の失敗後に待たせる時間（失敗のたびに倍になる）
+	BaseDelay time.Duration `yaml:"base_delay"`
+	// 最初のロック時間（ロック後も失敗が続くと倍になる）
+	LockoutDuration time.Duration `yaml:"lockout_duration"`
+	// ロック時間の上限
+	MaxLockout time.Duration `yaml:"max_lockout"`
+}
+
+type LogConfig struct {
+	// debug / info / warn / error
+	Level string `yaml:"level"`
+	// text / json
+	Format string `yaml:"format"`
+	// これより時間のかかったクエリを warn で記録する
+	SlowQuery time.Duration `yaml:"slow_query"`
+}
+
+// Prometheus 形式のメトリクスの公開設定
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+type HealthConfig struct {
+	// readiness で依存先1つの確認にかける時間の上限
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// OpenTelemetry のトレース出力の設定
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// otlp（OTLP/HTTP でコレクターへ送信）/ stdout（ローカル確認用に標準出力へ書き出す）
+	Exporter string `yaml:"exporter"`
+	// OTLP の送信先 URL（未指定なら OTEL_EXPORTER_OTLP_ENDPOINT か http://localhost:4318）
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"service_name"`
+	// 親スパンがないリクエストを記録する割合（0〜1）
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// PostgreSQL 接続文字列
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		c.Host, c.User, c.Password, c.Name, c.Port, c.SSLMode)
+}
+
+// ping を送る間隔（pong 待ち時間より短くする）
+func (c WebSocketConfig) PingInterval() time.Duration {
+	return c.PongTimeout * 9 / 10
+}
+
+// デフォルト設定
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ShutdownTimeout: 10 * time.Second,
+			RequestTimeout:  10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Port:        5432,
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{
+				"http://localhost:3000",
+				"https://www.echo-talk.com",
+			},
+		},
+		JWT: JWTConfig{
+			TTL: time.Hour,
+		},
+		WebSocket: WebSocketConfig{
+			MaxMessageSize:  4096,
+			WriteTimeout:    10 * time.Second,
+			PongTimeout:     60 * time.Second,
+			BroadcastBuffer: 256,
+			MessageTimeout:  5 * time.Second,
+		},
+		SSE: SSEConfig{
+			Heartbeat:   15 * time.Second,
+			Buffer:      64,
+			ReplayLimit: 500,
+		},
+		Webhook: WebhookConfig{
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			BaseBackoff:  30 * time.Second,
+			MaxBackoff:   time.Hour,
+			PollInterval: 5 * time.Second,
+			Concurrency:  4,
+		},
+		Scheduler: SchedulerConfig{
+			PollInterval: 5 * time.Second,
+			BatchSize:    50,
+			Lease:        time.Minute,
+			SendTimeout:  10 * time.Second,
+			MaxAttempts:  5,
+			BaseBackoff:  time.Minute,
+			MaxBackoff:   time.Hour,
+			MaxDelay:     365 * 24 * time.Hour,
+		},
+		Preview: PreviewConfig{
+			Enabled:   true,
+			Timeout:   5 * time.Second,
+			CacheTTL:  time.Hour,
+			CacheSize: 1000,
+			MaxURLs:   3,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:   true,
+			Default:   RatePolicy{Requests: 120, Per: time.Minute},
+			Login:     RatePolicy{Requests: 10, Per: time.Minute},
+			Register:  RatePolicy{Requests: 5, Per: time.Hour},
+			Messages:  RatePolicy{Requests: 30, Per: time.Minute},
+			WebSocket: RatePolicy{Requests: 20, Per: 10 * time.Second},
+		},
+		Login: LoginGuardConfig{
+			Enabled:         true,
+			MaxFailures:     5,
+			IPMaxFailures:   20,
+			BaseDelay:       time.Second,
+			LockoutDuration: 15 * time.Minute,
+			MaxLockout:      24 * time.Hour,
+		},
+		Log: LogConfig{
+			Level:     "info",
+			Format:    "text",
+			SlowQuery: 200 * time.Millisecond,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Health: HealthConfig{
+			Timeout: 2 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "otlp",
+			ServiceName: "chat-backend",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// 設定を読み込んで検証する
+func Load() (*Config, error) {
+	envFile := os.Getenv("ENV_FILE")
+	if envFile == "" {
+		envFile = ".env"
+	}
+	dotenv, err := readDotEnv(envFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// 環境変数を優先し、なければ .env の値を使う（空文字は未設定扱い）
+	lookup := func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			return v, true
+		}
+		v, ok := dotenv[key]
+		return v, ok && v != ""
+	}
+
+	cfg := Default()
+	if path, ok := lookup("CONFIG_FILE"); ok {
+		if err := loadYAML(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg, lookup); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// 必須項目と値の範囲を検証する
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("LISTEN_ADDR が設定されていません"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT は正の値で指定してください"))
+	}
+	if c.Server.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("REQUEST_TIMEOUT は正の値で指定してください"))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES に不正なアドレスがあります: %q", proxy))
+			}
+		}
+	}
+	if c.Database.Host == "" {
+		errs = append(errs, errors.New("DATABASE_HOST が設定されていません"))
+	}
+	if c.Database.User == "" {
+		errs = append(errs, errors.New("DATABASE_USER が設定されていません"))
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, errors.New("DATABASE_NAME が設定されていません"))
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("DATABASE_PORT が不正です: %d", c.Database.Port))
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("DATABASE_SSLMODE が不正です: %q", c.Database.SSLMode))
+	}
+	if len(c.CORS.AllowOrigins) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOW_ORIGINS が設定されていません"))
+	} else if _, err := origins.New(c.CORS.AllowOrigins); err != nil {
+		errs = append(errs, err)
+	}
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET が設定されていません"))
+	}
+	if c.JWT.TTL <= 0 {
+		errs = append(errs, errors.New("JWT_TTL は正の値で指定してください"))
+	}
+	if c.WebSocket.MaxMessageSize <= 0 {
+		errs = append(errs, errors.New("WS_MAX_MESSAGE_SIZE は正の値で指定してください"))
+	}
+	if c.WebSocket.WriteTimeout <= 0 {
+		errs = append(errs, errors.New("WS_WRITE_TIMEOUT は正の値で指定してください"))
+	}
+	if c.WebSocket.PongTimeout <= 0 {
+		errs = append(errs, errors.New("WS_PONG_TIMEOUT は正の値で指定してください"))
+	}
+	if c.WebSocket.BroadcastBuffer < 0 {
+		errs = append(errs, errors.New("WS_BROADCAST_BUFFER は0以上で指定してください"))
+	}
+	if c.WebSocket.MessageTimeout <= 0 {
+		errs = append(errs, errors.New("WS_MESSAGE_TIMEOUT は正の値で指定してください"))
+	}
+	if c.SSE.Heartbeat <= 0 {
+		errs = append(errs, errors.New("SSE_HEARTBEAT は正の値で指定してください"))
+	}
+	if c.SSE.Buffer <= 0 {
+		errs = append(errs, errors.New("SSE_BUFFER は正の値で指定してください"))
+	}
+	if c.SSE.ReplayLimit <= 0 {
+		errs = append(errs, errors.New("SSE_REPLAY_LIMIT は正の値で指定してください"))
+	}
+	if c.Webhook.Timeout <= 0 || c.Webhook.PollInterval <= 0 {
+		errs = append(errs, errors.New("WEBHOOK_TIMEOUT・WEBHOOK_POLL_INTERVAL は正の値で指定してください"))
+	}
+	if c.Webhook.MaxAttempts <= 0 || c.Webhook.Concurrency <= 0 {
+		errs = append(errs, errors.New("WEBHOOK_MAX_ATTEMPTS・WEBHOOK_CONCURRENCY は正の値で指定してください"))
+	}
+	if c.Webhook.BaseBackoff <= 0 || c.Webhook.MaxBackoff < c.Webhook.BaseBackoff {
+		errs = append(errs, errors.New("WEBHOOK_BASE_BACKOFF は正の値、WEBHOOK_MAX_BACKOFF は WEBHOOK_BASE_BACKOFF 以上で指定してください"))
+	}
+	if c.Scheduler.PollInterval <= 0 || c.Scheduler.Lease <= 0 || c.Scheduler.MaxDelay <= 0 {
+		errs = append(errs, errors.New("SCHEDULER_POLL_INTERVAL・SCHEDULER_LEASE・SCHEDULER_MAX_DELAY は正の値で指定してください"))
+	}
+	if c.Scheduler.BatchSize <= 0 || c.Scheduler.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("SCHEDULER_BATCH_SIZE・SCHEDULER_MAX_ATTEMPTS は正の値で指定してください"))
+	}
+	if c.Scheduler.BaseBackoff <= 0 || c.Scheduler.MaxBackoff < c.Scheduler.BaseBackoff {
+		errs = append(errs, errors.New("SCHEDULER_BASE_BACKOFF は正の値、SCHEDULER_MAX_BACKOFF は SCHEDULER_BASE_BACKOFF 以上で指定してください"))
+	}
+	if c.Scheduler.SendTimeout <= 0 || c.Scheduler.SendTimeout*2 > c.Scheduler.Lease {
+		errs = append(errs, errors.New("SCHEDULER_SEND_TIMEOUT は SCHEDULER_LEASE の半分以下の正の値で指定してください"))
+	}
+	if c.Preview.Enabled && (c.Preview.Timeout <= 0 || c.Preview.CacheTTL <= 0 || c.Preview.CacheSize <= 0 || c.Preview.MaxURLs <= 0) {
+		errs = append(errs, errors.New("LINK_PREVIEW_TIMEOUT・LINK_PREVIEW_CACHE_TTL・LINK_PREVIEW_CACHE_SIZE・LINK_PREVIEW_MAX_URLS は正の値で指定してください"))
+	}
+	for name, p := range map[string]RatePolicy{
+		"RATE_LIMIT_DEFAULT":   c.RateLimit.Default,
+		"RATE_LIMIT_LOGIN":     c.RateLimit.Login,
+		"RATE_LIMIT_REGISTER":  c.RateLimit.Register,
+		"RATE_LIMIT_MESSAGES":  c.RateLimit.Messages,
+		"RATE_LIMIT_WEBSOCKET": c.RateLimit.WebSocket,
+	} {
+		if p.Requests <= 0 || p.Per <= 0 {
+			errs = append(errs, fmt.Errorf("%s は正の回数と期間で指定してください", name))
+		}
+	}
+	if c.Login.MaxFailures <= 0 {
+		errs = append(errs, errors.New("LOGIN_MAX_FAILURES は正の値で指定してください"))
+	}
+	if c.Login.IPMaxFailures <= 0 {
+		errs = append(errs, errors.New("LOGIN_IP_MAX_FAILURES は正の値で指定してください"))
+	}
+	if c.Login.BaseDelay <= 0 || c.Login.LockoutDuration <= 0 || c.Login.MaxLockout < c.Login.LockoutDuration {
+		errs = append(errs, errors.New("LOGIN_BASE_DELAY・LOGIN_LOCKOUT_DURATION は正の値、LOGIN_MAX_LOCKOUT は LOGIN_LOCKOUT_DURATION 以上で指定してください"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL が不正です: %q", c.Log.Level))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT は text または json で指定してください: %q", c.Log.Format))
+	}
+	if c.Log.SlowQuery <= 0 {
+		errs = append(errs, errors.New("LOG_SLOW_QUERY は正の値で指定してください"))
+	}
+	if c.Health.Timeout <= 0 {
+		errs = append(errs, errors.New("HEALTH_TIMEOUT は正の値で指定してください"))
+	}
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("METRICS_PATH は / から始まるパスで指定してください: %q", c.Metrics.Path))
+	}
+	if c.Tracing.Enabled {
+		if c.Tracing.Exporter != "otlp" && c.Tracing.Exporter != "stdout" {
+			errs = append(errs, fmt.Errorf("TRACING_EXPORTER は otlp または stdout で指定してください: %q", c.Tracing.Exporter))
+		}
+		if c.Tracing.ServiceName == "" {
+			errs = append(errs, errors.New("TRACING_SERVICE_NAME が設定されていません"))
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO は0〜1で指定してください: %v", c.Tracing.SampleRatio))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// YAML ファイルの内容で設定を上書きする
+func loadYAML(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("設定ファイルの解析に失敗しました (%s): %w", path, err)
+	}
+	return nil
+}
+
+// 環境変数（または .env）の値で設定を上書きする
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+
+	setString := func(key string, dst *string) {
+		if v, ok := lookup(key); ok {
+			*dst = v
+		}
+	}
+	setInt := func(key string, dst *int) {
+		if v, ok := lookup(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s が数値ではありません: %q", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	setBool := func(key string, dst *bool) {
+		if v, ok := lookup(key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s が真偽値ではありません: %q", key, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	setInt64 := func(key string, dst *int64) {
+		if v, ok := lookup(key); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s が数値ではありません: %q", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	setFloat := func(key string, dst *float64) {
+		if v, ok := lookup(key); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s が数値ではありません: %q", key, v))
+				return
+			}
+			*dst = f
+		}
+	}
+	setDuration := func(key string, dst *time.Duration) {
+		if v, ok := lookup(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s が時間の形式ではありません: %q", key, v))
+				return
+			}
+			*dst = d
+		}
+	}
+	setList := func(key string, dst *[]string) {
+		if v, ok := lookup(key); ok {
+			*dst = splitList(v)
+		}
+	}
+	setPolicy := func(key string, dst *RatePolicy) {
+		if v, ok := lookup(key); ok {
+			p, err := parseRatePolicy(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s は \"回数/期間\"（例: 10/1m）で指定してください: %q", key, v))
+				return
+			}
+			*dst = p
+		}
+	}
+
+	setString("LISTEN_ADDR", &cfg.Server.Addr)
+	setDuration("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	setDuration("REQUEST_TIMEOUT", &cfg.Server.RequestTimeout)
+	setList("TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+
+	setString("DATABASE_HOST", &cfg.Database.Host)
+	setInt("DATABASE_PORT", &cfg.Database.Port)
+	setString("DATABASE_USER", &cfg.Database.User)
+	setString("DATABASE_PASSWORD", &cfg.Database.Password)
+	setString("DATABASE_NAME", &cfg.Database.Name)
+	setString("DATABASE_SSLMODE", &cfg.Database.SSLMode)
+	setBool("DATABASE_AUTO_MIGRATE", &cfg.Database.AutoMigrate)
+
+	setList("CORS_ALLOW_ORIGINS", &cfg.CORS.AllowOrigins)
+
+	setString("JWT_SECRET", &cfg.JWT.Secret)
+	setDuration("JWT_TTL", &cfg.JWT.TTL)
+
+	setInt64("WS_MAX_MESSAGE_SIZE", &cfg.WebSocket.MaxMessageSize)
+	setDuration("WS_WRITE_TIMEOUT", &cfg.WebSocket.WriteTimeout)
+	setDuration("WS_PONG_TIMEOUT", &cfg.WebSocket.PongTimeout)
+	setInt("WS_BROADCAST_BUFFER", &cfg.WebSocket.BroadcastBuffer)
+	setDuration("WS_MESSAGE_TIMEOUT", &cfg.WebSocket.MessageTimeout)
+
+	setDuration("SSE_HEARTBEAT", &cfg.SSE.Heartbeat)
+	setInt("SSE_BUFFER", &cfg.SSE.Buffer)
+	setInt("SSE_REPLAY_LIMIT", &cfg.SSE.ReplayLimit)
+
+	setDuration("WEBHOOK_TIMEOUT", &cfg.Webhook.Timeout)
+	setInt("WEBHOOK_MAX_ATTEMPTS", &cfg.Webhook.MaxAttempts)
+	setDuration("WEBHOOK_BASE_BACKOFF", &cfg.Webhook.BaseBackoff)
+	setDuration("WEBHOOK_MAX_BACKOFF", &cfg.Webhook.MaxBackoff)
+	setDuration("WEBHOOK_POLL_INTERVAL", &cfg.Webhook.PollInterval)
+	setInt("WEBHOOK_CONCURRENCY", &cfg.Webhook.Concurrency)
+	setBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", &cfg.Webhook.AllowPrivateNetworks)
+
+	setDuration("SCHEDULER_POLL_INTERVAL", &cfg.Scheduler.PollInterval)
+	setInt("SCHEDULER_BATCH_SIZE", &cfg.Scheduler.BatchSize)
+	setDuration("SCHEDULER_LEASE", &cfg.Scheduler.Lease)
+	setDuration("SCHEDULER_SEND_TIMEOUT", &cfg.Scheduler.SendTimeout)
+	setInt("SCHEDULER_MAX_ATTEMPTS", &cfg.Scheduler.MaxAttempts)
+	setDuration("SCHEDULER_BASE_BACKOFF", &cfg.Scheduler.BaseBackoff)
+	setDuration("SCHEDULER_MAX_BACKOFF", &cfg.Scheduler.MaxBackoff)
+	setDuration("SCHEDULER_MAX_DELAY", &cfg.Scheduler.MaxDelay)
+
+	setBool("LINK_PREVIEW_ENABLED", &cfg.Preview.Enabled)
+	setDuration("LINK_PREVIEW_TIMEOUT", &cfg.Preview.Timeout)
+	setDuration("LINK_PREVIEW_CACHE_TTL", &cfg.Preview.CacheTTL)
+	setInt("LINK_PREVIEW_CACHE_SIZE", &cfg.Preview.CacheSize)
+	setInt("LINK_PREVIEW_MAX_URLS", &cfg.Preview.MaxURLs)
+	setBool("LINK_PREVIEW_ALLOW_PRIVATE_NETWORKS", &cfg.Preview.AllowPrivateNetworks)
+
+	setBool("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	setPolicy("RATE_LIMIT_DEFAULT", &cfg.RateLimit.Default)
+	setPolicy("RATE_LIMIT_LOGIN", &cfg.RateLimit.Login)
+	setPolicy("RATE_LIMIT_REGISTER", &cfg.RateLimit.Register)
+	setPolicy("RATE_LIMIT_MESSAGES", &cfg.RateLimit.Messages)
+	setPolicy("RATE_LIMIT_WEBSOCKET", &cfg.RateLimit.WebSocket)
+
+	setBool("LOGIN_GUARD_ENABLED", &cfg.Login.Enabled)
+	setInt("LOGIN_MAX_FAILURES", &cfg.Login.MaxFailures)
+	setInt("LOGIN_IP_MAX_FAILURES", &cfg.Login.IPMaxFailures)
+	setDuration("LOGIN_BASE_DELAY", &cfg.Login.BaseDelay)
+	setDuration("LOGIN_LOCKOUT_DURATION", &cfg.Login.LockoutDuration)
+	setDuration("LOGIN_MAX_LOCKOUT", &cfg.Login.MaxLockout)
+
+	setString("LOG_LEVEL", &cfg.Log.Level)
+	setString("LOG_FORMAT", &cfg.Log.Format)
+	setDuration("LOG_SLOW_QUERY", &cfg.Log.SlowQuery)
+
+	setBool("METRICS_ENABLED", &cfg.Metrics.Enabled)
+	setString("METRICS_PATH", &cfg.Metrics.Path)
+
+	setDuration("HEALTH_TIMEOUT", &cfg.Health.Timeout)
+
+	setBool("TRACING_ENABLED", &cfg.Tracing.Enabled)
+	setString("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	setString("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	setString("TRACING_SERVICE_NAME", &cfg.Tracing.ServiceName)
+	setFloat("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+
+	return errors.Join(errs...)
+}
+
+// "10/1m" 形式のレート制限を解析する
+func parseRatePolicy(v string) (RatePolicy, error) {
+	countStr, perStr, ok := strings.Cut(v, "/")
+	if !ok {
+		return RatePolicy{}, errors.New("invalid format")
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if err != nil {
+		return RatePolicy{}, err
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(perStr))
+	if err != nil {
+		return RatePolicy{}, err
+	}
+	return RatePolicy{Requests: count, Per: per}, nil
+}
+
+// カンマ区切りの値をスライスに変換（空要素は除外）
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// .env ファイルを KEY=VALUE の map として読み込む（ファイルがなければ空）
+func readDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(".env の読み込みに失敗しました: %w", err)
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf(".env の %d 行目が不正です", lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf(".env の読み込みに失敗しました: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,304 @@
+package config_test
+
+import (
+	"chat/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// テストごとに環境変数と .env の場所を初期化する
+func setupEnv(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, key := range []string{
+		"CONFIG_FILE", "LISTEN_ADDR", "SHUTDOWN_TIMEOUT", "REQUEST_TIMEOUT",
+		"DATABASE_HOST", "DATABASE_PORT", "DATABASE_USER", "DATABASE_PASSWORD", "DATABASE_NAME", "DATABASE_SSLMODE", "DATABASE_AUTO_MIGRATE",
+		"CORS_ALLOW_ORIGINS", "JWT_SECRET", "JWT_TTL",
+		"WS_MAX_MESSAGE_SIZE", "WS_WRITE_TIMEOUT", "WS_PONG_TIMEOUT", "WS_BROADCAST_BUFFER", "WS_MESSAGE_TIMEOUT",
+		"SSE_HEARTBEAT", "SSE_BUFFER", "SSE_REPLAY_LIMIT",
+		"WEBHOOK_TIMEOUT", "WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_BASE_BACKOFF", "WEBHOOK_MAX_BACKOFF",
+		"WEBHOOK_POLL_INTERVAL", "WEBHOOK_CONCURRENCY", "WEBHOOK_ALLOW_PRIVATE_NETWORKS",
+		"SCHEDULER_POLL_INTERVAL", "SCHEDULER_BATCH_SIZE", "SCHEDULER_LEASE", "SCHEDULER_SEND_TIMEOUT", "SCHEDULER_MAX_ATTEMPTS", "SCHEDULER_BASE_BACKOFF", "SCHEDULER_MAX_BACKOFF", "SCHEDULER_MAX_DELAY",
+		"LINK_PREVIEW_ENABLED", "LINK_PREVIEW_TIMEOUT", "LINK_PREVIEW_CACHE_TTL", "LINK_PREVIEW_CACHE_SIZE",
+		"LINK_PREVIEW_MAX_URLS", "LINK_PREVIEW_ALLOW_PRIVATE_NETWORKS",
+		"TRUSTED_PROXIES", "RATE_LIMIT_ENABLED", "RATE_LIMIT_DEFAULT", "RATE_LIMIT_LOGIN",
+		"RATE_LIMIT_REGISTER", "RATE_LIMIT_MESSAGES", "RATE_LIMIT_WEBSOCKET",
+		"LOGIN_GUARD_ENABLED", "LOGIN_MAX_FAILURES", "LOGIN_IP_MAX_FAILURES",
+		"LOGIN_BASE_DELAY", "LOGIN_LOCKOUT_DURATION", "LOGIN_MAX_LOCKOUT",
+		"LOG_LEVEL", "LOG_FORMAT", "LOG_SLOW_QUERY", "METRICS_ENABLED", "METRICS_PATH",
+		"HEALTH_TIMEOUT", "TRACING_ENABLED", "TRACING_EXPORTER", "TRACING_ENDPOINT",
+		"TRACING_SERVICE_NAME", "TRACING_SAMPLE_RATIO",
+	} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("ENV_FILE", filepath.Join(dir, ".env"))
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoad_FromEnv(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("DATABASE_HOST", "db")
+	t.Setenv("DATABASE_PORT", "15432")
+	t.Setenv("DATABASE_USER", "chat")
+	t.Setenv("DATABASE_PASSWORD", "secret")
+	t.Setenv("DATABASE_NAME", "chatdb")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("WS_PONG_TIMEOUT", "30s")
+	t.Setenv("DATABASE_AUTO_MIGRATE", "false")
+	t.Setenv("RATE_LIMIT_LOGIN", "3/30s")
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "5m")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("SCHEDULER_POLL_INTERVAL", "1s")
+	t.Setenv("LINK_PREVIEW_TIMEOUT", "2s")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, 15432, cfg.Database.Port)
+	assert.Equal(t, "host=db user=chat password=secret dbname=chatdb port=15432 sslmode=disable", cfg.Database.DSN())
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORS.AllowOrigins)
+	assert.Equal(t, 30*time.Second, cfg.WebSocket.PongTimeout)
+	assert.Equal(t, time.Hour, cfg.JWT.TTL)
+	assert.False(t, cfg.Database.AutoMigrate)
+	assert.Equal(t, config.RatePolicy{Requests: 3, Per: 30 * time.Second}, cfg.RateLimit.Login)
+	assert.Equal(t, 3, cfg.Login.MaxFailures)
+	assert.Equal(t, 5*time.Minute, cfg.Login.LockoutDuration)
+	assert.Equal(t, 24*time.Hour, cfg.Login.MaxLockout)
+	assert.Equal(t, config.LogConfig{Level: "debug", Format: "json", SlowQuery: 200 * time.Millisecond}, cfg.Log)
+	assert.Equal(t, time.Second, cfg.Scheduler.PollInterval)
+	assert.Equal(t, time.Minute, cfg.Scheduler.Lease)
+	assert.Equal(t, 2*time.Second, cfg.Preview.Timeout)
+	assert.True(t, cfg.Preview.Enabled)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := setupEnv(t)
+
+	yamlPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, yamlPath, `
+server:
+  addr: ":9000"
+  shutdown_timeout: 5s
+database:
+  host: yaml-host
+  user: yaml-user
+  name: yaml-db
+jwt:
+  secret: yaml-secret
+  ttl: 2h
+`)
+	writeFile(t, filepath.Join(dir, ".env"), `
+# コメント行
+CONFIG_FILE=`+yamlPath+`
+DATABASE_HOST="dotenv-host"
+export DATABASE_NAME=dotenv-db
+`)
+	// 環境変数は .env より優先される
+	t.Setenv("DATABASE_NAME", "env-db")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "dotenv-host", cfg.Database.Host)
+	assert.Equal(t, "yaml-user", cfg.Database.User)
+	assert.Equal(t, "env-db", cfg.Database.Name)
+	assert.Equal(t, "yaml-secret", cfg.JWT.Secret)
+	assert.Equal(t, 2*time.Hour, cfg.JWT.TTL)
+}
+
+func TestLoad_MissingRequired(t *testing.T) {
+	setupEnv(t)
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DATABASE_HOST")
+	assert.Contains(t, err.Error(), "DATABASE_USER")
+	assert.Contains(t, err.Error(), "DATABASE_NAME")
+	assert.Contains(t, err.Error(), "JWT_SECRET")
+}
+
+func TestLoad_InvalidValues(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("DATABASE_PORT", "abc")
+	t.Setenv("JWT_TTL", "forever")
+	t.Setenv("RATE_LIMIT_MESSAGES", "lots")
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DATABASE_PORT")
+	assert.Contains(t, err.Error(), "JWT_TTL")
+	assert.Contains(t, err.Error(), "RATE_LIMIT_MESSAGES")
+}
+
+func TestLoad_MissingConfigFile(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("CONFIG_FILE", "/nonexistent/config.yaml")
+
+	_, err := config.Load()
+	assert.Error(t, err)
+}
+
+func TestValidate_InvalidSSLMode(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database.SSLMode = "sometimes"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Server.TrustedProxies = []string{"proxy.example.com"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_LoginGuard(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Login.MaxLockout = time.Minute
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_AllowOrigins(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+
+	cfg.CORS.AllowOrigins = []string{"https://*.echo-talk.com", "http://localhost:3000"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.CORS.AllowOrigins = []string{"*"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_Log(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+
+	cfg.Log.Level = "WARN"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Log.Level = "verbose"
+	assert.Error(t, cfg.Validate())
+
+	cfg.Log.Level = "info"
+	cfg.Log.Format = "xml"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_MetricsPath(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+
+	cfg.Metrics.Path = "metrics"
+	assert.Error(t, cfg.Validate())
+
+	// 無効なら検証しない
+	cfg.Metrics.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Tracing(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+
+	// 無効の間は出力先を検証しない
+	cfg.Tracing.Exporter = "jaeger"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Tracing.Enabled = true
+	assert.Error(t, cfg.Validate())
+
+	cfg.Tracing.Exporter = "stdout"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Tracing.SampleRatio = 1.5
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_Scheduler(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Scheduler.Lease = 0
+	assert.Error(t, cfg.Validate())
+
+	cfg.Scheduler.Lease = time.Minute
+	cfg.Scheduler.BatchSize = 0
+	assert.Error(t, cfg.Validate())
+
+	// 投稿の上限は lease の半分以下（送信済みを記録する前に再送されないように）
+	cfg.Scheduler.BatchSize = 50
+	cfg.Scheduler.SendTimeout = 45 * time.Second
+	assert.Error(t, cfg.Validate())
+	cfg.Scheduler.SendTimeout = 30 * time.Second
+	assert.NoError(t, cfg.Validate())
+
+	// 再送の待ち時間の上限は BaseBackoff 以上
+	cfg.Scheduler.MaxBackoff = 30 * time.Second
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_Preview(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host = "db"
+	cfg.Database.User = "chat"
+	cfg.Database.Name = "chatdb"
+	cfg.JWT.Secret = "secret"
+
+	cfg.Preview.MaxURLs = 0
+	assert.Error(t, cfg.Validate())
+
+	// 無効なら検証しない
+	cfg.Preview.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
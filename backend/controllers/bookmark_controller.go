@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"chat/dto"
+	"chat/middlewares"
+	"chat/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// メッセージの保存。保存はログイン中のユーザー本人にしか見えない
+type BookmarkController struct {
+	Service services.BookmarkService
+}
+
+func NewBookmarkController(service services.BookmarkService) *BookmarkController {
+	return &BookmarkController{Service: service}
+}
+
+// メッセージを保存する（保存済みでも 204 を返す）
+func (c *BookmarkController) PutBookmark(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.SaveMessage(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.MessageID); err != nil {
+		abortWithError(ctx, err, errBookmarkFailed)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// 保存の取り消し
+func (c *BookmarkController) DeleteBookmark(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.RemoveBookmark(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.MessageID); err != nil {
+		abortWithError(ctx, err, errBookmarkDeleteFailed)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// 全スペースの保存したメッセージ（新しく保存した順）
+func (c *BookmarkController) ListBookmarks(ctx *gin.Context) {
+	var query dto.ListBookmarksQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	page, err := c.Service.ListBookmarks(ctx.Request.Context(), middlewares.Username(ctx), query.Before, query.Limit)
+	if err != nil {
+		abortWithError(ctx, err, errBookmarkListFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
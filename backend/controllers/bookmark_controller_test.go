@@ -0,0 +1,110 @@
+package controllers_test
+
+import (
+	"chat/controllers"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBookmarkService struct {
+	mock.Mock
+}
+
+func (m *MockBookmarkService) SaveMessage(ctx context.Context, actor string, spaceID, messageID int) error {
+	args := m.Called(actor, spaceID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockBookmarkService) RemoveBookmark(ctx context.Context, actor string, spaceID, messageID int) error {
+	args := m.Called(actor, spaceID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockBookmarkService) ListBookmarks(ctx context.Context, actor string, before, limit int) (services.BookmarkPage, error) {
+	args := m.Called(actor, before, limit)
+	return args.Get(0).(services.BookmarkPage), args.Error(1)
+}
+
+func setupBookmarkRouter(service *MockBookmarkService) *gin.Engine {
+	users := new(MockUserService)
+	users.On("VerifyToken", "alice-token").Return("alice", nil)
+
+	controller := controllers.NewBookmarkController(service)
+	router := setupRouterSpace()
+	router.Use(middlewares.Authenticate(users))
+	router.PUT("/spaces/:spaceId/messages/:messageId/bookmark", middlewares.RequireAuth(), controller.PutBookmark)
+	router.DELETE("/spaces/:spaceId/messages/:messageId/bookmark", middlewares.RequireAuth(), controller.DeleteBookmark)
+	router.GET("/bookmarks", middlewares.RequireAuth(), controller.ListBookmarks)
+	return router
+}
+
+func TestBookmarkController_PutAndDelete(t *testing.T) {
+	service := new(MockBookmarkService)
+	router := setupBookmarkRouter(service)
+
+	service.On("SaveMessage", "alice", 1, 10).Return(nil).Once()
+	service.On("SaveMessage", "alice", 1, 11).Return(services.ErrMessageNotFound).Once()
+	service.On("RemoveBookmark", "alice", 1, 10).Return(nil).Once()
+	service.On("RemoveBookmark", "alice", 1, 12).Return(services.ErrBookmarkNotFound).Once()
+	// メッセージが指定したスペースにない
+	service.On("RemoveBookmark", "alice", 999, 10).Return(services.ErrBookmarkNotFound).Once()
+
+	w := webhookRequest(router, "PUT", "/spaces/1/messages/10/bookmark", "alice-token", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = webhookRequest(router, "PUT", "/spaces/1/messages/11/bookmark", "alice-token", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"message_not_found"`)
+
+	w = webhookRequest(router, "DELETE", "/spaces/1/messages/10/bookmark", "alice-token", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = webhookRequest(router, "DELETE", "/spaces/1/messages/12/bookmark", "alice-token", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"bookmark_not_found"`)
+
+	// パスのスペースで絞り込む
+	w = webhookRequest(router, "DELETE", "/spaces/999/messages/10/bookmark", "alice-token", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"bookmark_not_found"`)
+
+	// ログインが必要
+	w = webhookRequest(router, "PUT", "/spaces/1/messages/10/bookmark", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	service.AssertExpectations(t)
+}
+
+func TestBookmarkController_ListBookmarks(t *testing.T) {
+	service := new(MockBookmarkService)
+	router := setupBookmarkRouter(service)
+
+	page := services.BookmarkPage{
+		Bookmarks:  []models.Bookmark{{ID: 30, MessageID: 10, Message: &models.Message{ID: 10, SpaceID: 2, Text: "議事録"}}},
+		NextBefore: 30,
+	}
+	service.On("ListBookmarks", "alice", 0, 0).Return(page, nil).Once()
+	service.On("ListBookmarks", "alice", 30, 1).Return(services.BookmarkPage{Bookmarks: []models.Bookmark{}}, nil).Once()
+
+	w := webhookRequest(router, "GET", "/bookmarks", "alice-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"text":"議事録"`)
+	assert.Contains(t, w.Body.String(), `"next_before":30`)
+
+	w = webhookRequest(router, "GET", "/bookmarks?before=30&limit=1", "alice-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"bookmarks":[]}`, w.Body.String())
+
+	w = webhookRequest(router, "GET", "/bookmarks?limit=500", "alice-token", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	service.AssertExpectations(t)
+}
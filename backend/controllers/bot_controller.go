@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"chat/dto"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// スラッシュコマンドに応答するボットの管理。管理者のみ操作できる
+type BotController struct {
+	Service services.BotService
+}
+
+func NewBotController(service services.BotService) *BotController {
+	return &BotController{Service: service}
+}
+
+// ボットのレスポンス（署名の鍵は登録時のみ返す）
+type botResponse struct {
+	models.Bot
+	Secret string `json:"secret,omitempty"`
+}
+
+// ボット登録
+func (c *BotController) PostBot(ctx *gin.Context) {
+	var req dto.CreateBotRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	bot, err := c.Service.CreateBot(ctx.Request.Context(), middlewares.Username(ctx), req.ToModel())
+	if err != nil {
+		abortWithError(ctx, err, errBotCreateFailed)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v2/bots/%d", bot.ID))
+	ctx.JSON(http.StatusCreated, botResponse{Bot: bot, Secret: bot.Secret})
+}
+
+// ボット一覧
+func (c *BotController) ListBots(ctx *gin.Context) {
+	bots, err := c.Service.ListBots(ctx.Request.Context(), middlewares.Username(ctx))
+	if err != nil {
+		abortWithError(ctx, err, errBotListFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, bots)
+}
+
+// ボット削除
+func (c *BotController) DeleteBot(ctx *gin.Context) {
+	var path dto.BotPath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.DeleteBot(ctx.Request.Context(), middlewares.Username(ctx), path.BotID); err != nil {
+		abortWithError(ctx, err, errBotDeleteFailed)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"chat/controllers"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBotService struct {
+	mock.Mock
+}
+
+func (m *MockBotService) CreateBot(ctx context.Context, actor string, bot models.Bot) (models.Bot, error) {
+	args := m.Called(actor, bot)
+	return args.Get(0).(models.Bot), args.Error(1)
+}
+
+func (m *MockBotService) ListBots(ctx context.Context, actor string) ([]models.Bot, error) {
+	args := m.Called(actor)
+	return args.Get(0).([]models.Bot), args.Error(1)
+}
+
+func (m *MockBotService) DeleteBot(ctx context.Context, actor string, botID int) error {
+	args := m.Called(actor, botID)
+	return args.Error(0)
+}
+
+func setupBotRouter(service *MockBotService) *gin.Engine {
+	users := new(MockUserService)
+	users.On("VerifyToken", "admin-token").Return("admin", nil)
+	users.On("VerifyToken", "bob-token").Return("bob", nil)
+
+	controller := controllers.NewBotController(service)
+	router := setupRouterSpace()
+	router.Use(middlewares.Authenticate(users))
+	router.POST("/bots", middlewares.RequireAuth(), controller.PostBot)
+	router.GET("/bots", middlewares.RequireAuth(), controller.ListBots)
+	router.DELETE("/bots/:botId", middlewares.RequireAuth(), controller.DeleteBot)
+	return router
+}
+
+// 登録時のみ署名の鍵を返す
+func TestBotController_PostBot(t *testing.T) {
+	service := new(MockBotService)
+	router := setupBotRouter(service)
+
+	input := models.Bot{Name: "Deploy", Command: "deploy", CallbackURL: "https://ci.example.com/hook"}
+	bot := input
+	bot.ID = 3
+	bot.Secret = "s3cret"
+	service.On("CreateBot", "admin", input).Return(bot, nil).Once()
+	service.On("CreateBot", "bob", input).Return(models.Bot{}, services.ErrForbidden).Once()
+	service.On("ListBots", "admin").Return([]models.Bot{bot}, nil).Once()
+
+	body := `{"name":"Deploy","command":"deploy","callback_url":"https://ci.example.com/hook"}`
+	w := webhookRequest(router, "POST", "/bots", "admin-token", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/bots/3", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"secret":"s3cret"`)
+
+	w = webhookRequest(router, "GET", "/bots", "admin-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"command":"deploy"`)
+	assert.NotContains(t, w.Body.String(), "s3cret")
+
+	w = webhookRequest(router, "POST", "/bots", "bob-token", body)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// コマンド名は英小文字で始まる
+	w = webhookRequest(router, "POST", "/bots", "admin-token", `{"name":"Deploy","command":"/Deploy","callback_url":"https://ci.example.com/hook"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"command":{"code":"command"`)
+
+	service.AssertExpectations(t)
+}
+
+func TestBotController_DeleteBot(t *testing.T) {
+	service := new(MockBotService)
+	router := setupBotRouter(service)
+
+	service.On("DeleteBot", "admin", 3).Return(nil).Once()
+	service.On("DeleteBot", "admin", 9).Return(services.ErrBotNotFound).Once()
+
+	w := webhookRequest(router, "DELETE", "/bots/3", "admin-token", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = webhookRequest(router, "DELETE", "/bots/9", "admin-token", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"bot_not_found"`)
+
+	service.AssertExpectations(t)
+}
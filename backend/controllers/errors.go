@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"chat/apperrors"
+	"chat/dto"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストの形式・入力値に関するエラー
+var (
+	errInvalidRequest   = apperrors.New(apperrors.ErrBadRequest, "invalid_request", "リクエストのパースに失敗しました")
+	errValidationFailed = apperrors.New(apperrors.ErrValidation, "validation_failed", "入力内容に誤りがあります")
+	errAuthRequired     = apperrors.New(apperrors.ErrUnauthorized, "auth_required", "ログインが必要です")
+)
+
+// ドメインエラー以外（DB障害など）のときに返すエラー
+var (
+	errMessageFetchFailed   = apperrors.New(apperrors.ErrInternal, "message_fetch_failed", "メッセージ取得失敗")
+	errMessageSaveFailed    = apperrors.New(apperrors.ErrInternal, "message_save_failed", "メッセージの保存に失敗しました")
+	errMessageDeleteFailed  = apperrors.New(apperrors.ErrInternal, "message_delete_failed", "メッセージの削除に失敗しました")
+	errSpaceCreateFailed    = apperrors.New(apperrors.ErrInternal, "space_create_failed", "スペースの作成に失敗しました")
+	errSpaceListFailed      = apperrors.New(apperrors.ErrInternal, "space_list_failed", "スペース一覧の取得に失敗しました")
+	errSpaceFetchFailed     = apperrors.New(apperrors.ErrInternal, "space_fetch_failed", "スペースの取得に失敗しました")
+	errRegisterFailed       = apperrors.New(apperrors.ErrInternal, "register_failed", "ユーザー登録に失敗しました")
+	errLoginFailed          = apperrors.New(apperrors.ErrInternal, "login_failed", "ログイン処理に失敗しました")
+	errUnlockFailed         = apperrors.New(apperrors.ErrInternal, "unlock_failed", "ロックの解除に失敗しました")
+	errWebhookCreateFailed  = apperrors.New(apperrors.ErrInternal, "webhook_create_failed", "Webhook の登録に失敗しました")
+	errWebhookListFailed    = apperrors.New(apperrors.ErrInternal, "webhook_list_failed", "Webhook の取得に失敗しました")
+	errWebhookDeleteFailed  = apperrors.New(apperrors.ErrInternal, "webhook_delete_failed", "Webhook の削除に失敗しました")
+	errCommandFailed        = apperrors.New(apperrors.ErrInternal, "command_failed", "コマンドの実行に失敗しました")
+	errBotCreateFailed      = apperrors.New(apperrors.ErrInternal, "bot_create_failed", "ボットの登録に失敗しました")
+	errBotListFailed        = apperrors.New(apperrors.ErrInternal, "bot_list_failed", "ボットの取得に失敗しました")
+	errBotDeleteFailed      = apperrors.New(apperrors.ErrInternal, "bot_delete_failed", "ボットの削除に失敗しました")
+	errScheduleFailed       = apperrors.New(apperrors.ErrInternal, "schedule_failed", "メッセージの予約に失敗しました")
+	errScheduleListFailed   = apperrors.New(apperrors.ErrInternal, "schedule_list_failed", "予約の取得に失敗しました")
+	errScheduleCancelFailed = apperrors.New(apperrors.ErrInternal, "schedule_cancel_failed", "予約の取り消しに失敗しました")
+	errPinFailed            = apperrors.New(apperrors.ErrInternal, "pin_failed", "ピン留めに失敗しました")
+	errUnpinFailed          = apperrors.New(apperrors.ErrInternal, "unpin_failed", "ピン留めの解除に失敗しました")
+	errBookmarkFailed       = apperrors.New(apperrors.ErrInternal, "bookmark_failed", "メッセージの保存に失敗しました")
+	errBookmarkListFailed   = apperrors.New(apperrors.ErrInternal, "bookmark_list_failed", "保存したメッセージの取得に失敗しました")
+	errBookmarkDeleteFailed = apperrors.New(apperrors.ErrInternal, "bookmark_delete_failed", "保存の取り消しに失敗しました")
+	errPreviewFailed        = apperrors.New(apperrors.ErrInternal, "preview_failed", "リンクのプレビューの取得に失敗しました")
+)
+
+// リクエストのタイムアウト・クライアントの切断で DB の処理を打ち切ったときのエラー
+var (
+	errRequestTimeout  = apperrors.New(apperrors.ErrTimeout, "request_timeout", "処理がタイムアウトしました")
+	errRequestCanceled = apperrors.New(apperrors.ErrCanceled, "request_canceled", "リクエストが中断されました")
+)
+
+// サービスのエラーをエラーハンドラーに渡す。ドメインエラーでなければ fallback として扱う
+func abortWithError(ctx *gin.Context, err error, fallback *apperrors.Error) {
+	var appErr *apperrors.Error
+	switch {
+	case errors.As(err, &appErr):
+	case errors.Is(err, apperrors.ErrTimeout):
+		err = errRequestTimeout.Wrap(err)
+	case errors.Is(err, apperrors.ErrCanceled):
+		err = errRequestCanceled.Wrap(err)
+	default:
+		err = fallback.Wrap(err)
+	}
+	ctx.Error(err)
+}
+
+// JSON ボディを DTO にバインドして検証する。失敗時はエラーを登録して false を返す
+func bindJSON(ctx *gin.Context, req any) bool {
+	dto.RegisterValidators()
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.Error(bindError(err))
+		return false
+	}
+	return true
+}
+
+// クエリを DTO にバインドして検証する。失敗時はエラーを登録して false を返す
+func bindQuery(ctx *gin.Context, req any) bool {
+	dto.RegisterValidators()
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.Error(bindError(err))
+		return false
+	}
+	return true
+}
+
+// パスパラメーターを DTO にバインドして検証する。失敗時はエラーを登録して false を返す
+func bindURI(ctx *gin.Context, req any) bool {
+	dto.RegisterValidators()
+	if err := ctx.ShouldBindUri(req); err != nil {
+		ctx.Error(bindError(err))
+		return false
+	}
+	return true
+}
+
+// 入力値の検証エラーは 422（項目ごとの理由付き）、形式の誤りは 400 にする
+func bindError(err error) error {
+	if fields, ok := dto.FieldErrors(err); ok {
+		return errValidationFailed.WithFields(fields).Wrap(err)
+	}
+	return errInvalidRequest.Wrap(err)
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"chat/config"
+	"chat/dto"
+	"chat/logging"
+	"chat/models"
+	"chat/services"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// WebSocket の代わりに Server-Sent Events でスペースのメッセージを配信する
+// （WebSocket のアップグレードを通さないプロキシの配下にいるユーザー向け）。送信は REST API で行う。
+type EventController struct {
+	Spaces   services.SpaceService
+	Messages services.MessageService
+	Hub      services.WebSocketService
+	Config   config.SSEConfig
+	Logger   *slog.Logger
+}
+
+func NewEventController(spaces services.SpaceService, messages services.MessageService, hub services.WebSocketService, cfg config.SSEConfig, logger *slog.Logger) *EventController {
+	return &EventController{Spaces: spaces, Messages: messages, Hub: hub, Config: cfg, Logger: logger}
+}
+
+// ブラウザの EventSource が再接続までに待つ時間
+const sseRetry = 3 * time.Second
+
+// スペースのイベントを配信する
+//
+// Last-Event-ID（初回接続では ?lastEventId=）より後のメッセージを DB から再送してから、
+// ハブに届いたメッセージを流す。イベントの id はメッセージ ID。再送と配信の境目では
+// 同じメッセージが重複しうるため、クライアントは id で重複を除く。
+func (c *EventController) Stream(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+	lastID, ok := lastEventID(ctx)
+	if !ok {
+		ctx.Error(errInvalidRequest)
+		return
+	}
+
+	reqCtx := ctx.Request.Context()
+	if _, err := c.Spaces.GetSpace(reqCtx, path.SpaceID); err != nil {
+		abortWithError(ctx, err, errSpaceFetchFailed)
+		return
+	}
+
+	// 再送分の取得中に届いたメッセージを取りこぼさないよう、先に購読する
+	events, unsubscribe := c.Hub.Subscribe(path.SpaceID)
+	defer unsubscribe()
+
+	var backlog []models.Message
+	reset := false
+	if lastID > 0 {
+		messages, err := c.Messages.GetMessagesAfter(reqCtx, path.SpaceID, lastID, c.Config.ReplayLimit+1)
+		if err != nil {
+			abortWithError(ctx, err, errMessageFetchFailed)
+			return
+		}
+		// 再送しきれないほど離れていた場合は、一覧の取り直しを促す
+		if len(messages) > c.Config.ReplayLimit {
+			reset = true
+		} else {
+			backlog = messages
+		}
+	}
+
+	connID := logging.NewID()
+	logCtx := logging.WithAttrs(reqCtx, slog.String("conn_id", connID))
+	c.Logger.InfoContext(logCtx, "SSE接続", "client_ip", ctx.ClientIP(), "space_id", path.SpaceID, "last_event_id", lastID)
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", sse.ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// nginx などのバッファリングを無効にする
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", sseRetry.Milliseconds())
+
+	if reset {
+		ctx.Render(-1, sse.Event{Event: "reset", Data: gin.H{}})
+	}
+	replayed := make(map[int]bool, len(backlog))
+	for _, msg := range backlog {
+		c.writeMessage(ctx, msg)
+		replayed[msg.ID] = true
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(c.Config.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				// 配信に追いつけなかったか、サーバーの停止
+				c.Logger.InfoContext(logCtx, "SSE切断", "reason", "unsubscribed")
+				return
+			}
+			// 保存されていない（ID のない）メッセージは再送できないため流さない
+			if msg.ID == 0 || replayed[msg.ID] {
+				continue
+			}
+			c.writeMessage(ctx, msg)
+			ctx.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(ctx.Writer, ": keep-alive\n\n")
+			ctx.Writer.Flush()
+		case <-reqCtx.Done():
+			c.Logger.InfoContext(logCtx, "SSE切断", "reason", reqCtx.Err())
+			return
+		}
+	}
+}
+
+func (c *EventController) writeMessage(ctx *gin.Context, msg models.Message) {
+	ctx.Render(-1, sse.Event{Id: strconv.Itoa(msg.ID), Event: "message", Data: msg})
+}
+
+// Last-Event-ID ヘッダー（EventSource の再接続）か ?lastEventId=（初回接続）の値。未指定なら 0
+func lastEventID(ctx *gin.Context) (int, bool) {
+	v := ctx.GetHeader("Last-Event-ID")
+	if v == "" {
+		v = ctx.Query("lastEventId")
+	}
+	if v == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(v)
+	return id, err == nil && id >= 0
+}
@@ -0,0 +1,147 @@
+package controllers_test
+
+import (
+	"bufio"
+	"chat/config"
+	"chat/controllers"
+	"chat/logging"
+	"chat/metrics"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEventServer(t *testing.T, spaces *MockSpaceService, messages *MockMessageService, cfg config.SSEConfig) (*httptest.Server, services.WebSocketService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	hub := services.NewWebSocketService(config.Default().WebSocket, cfg, logging.Discard(), metrics.New())
+	controller := controllers.NewEventController(spaces, messages, hub, cfg, logging.Discard())
+
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.GET("/spaces/:spaceId/events", controller.Stream)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, hub
+}
+
+// SSE のストリームを開き、行を読むための Reader を返す
+func openStream(t *testing.T, url, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// 次のイベント（空行まで）を読む。コメント行と retry だけのブロックは読み飛ばす
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	event := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if _, ok := event["event"]; ok {
+				return event
+			}
+			event = map[string]string{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, ":")
+		event[key] = strings.TrimSpace(value)
+	}
+}
+
+func TestEventController_Stream(t *testing.T) {
+	spaces := new(MockSpaceService)
+	messages := new(MockMessageService)
+	server, hub := setupEventServer(t, spaces, messages, config.Default().SSE)
+
+	spaces.On("GetSpace", 1).Return(models.Space{ID: 1, Name: "general"}, nil)
+	messages.On("GetMessagesAfter", 1, 5, 501).Return([]models.Message{
+		{ID: 6, SpaceID: 1, Username: "alice", Text: "missed 1"},
+		{ID: 7, SpaceID: 1, Username: "bob", Text: "missed 2"},
+	}, nil)
+
+	resp, r := openStream(t, server.URL+"/spaces/1/events", "5")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Last-Event-ID より後のメッセージを再送する
+	event := readEvent(t, r)
+	assert.Equal(t, "6", event["id"])
+	assert.Equal(t, "message", event["event"])
+	assert.Contains(t, event["data"], `"text":"missed 1"`)
+	assert.Equal(t, "7", readEvent(t, r)["id"])
+
+	// 再送済み・ID のない・他のスペースのメッセージは流さない
+	hub.BroadcastMessage(context.Background(), models.Message{ID: 7, SpaceID: 1, Text: "missed 2"})
+	hub.BroadcastMessage(context.Background(), models.Message{SpaceID: 1, Text: "unsaved"})
+	hub.BroadcastMessage(context.Background(), models.Message{ID: 8, SpaceID: 2, Text: "other space"})
+	hub.BroadcastMessage(context.Background(), models.Message{ID: 9, SpaceID: 1, Text: "live"})
+
+	event = readEvent(t, r)
+	assert.Equal(t, "9", event["id"])
+	assert.Contains(t, event["data"], `"text":"live"`)
+
+	spaces.AssertExpectations(t)
+	messages.AssertExpectations(t)
+}
+
+func TestEventController_Stream_Reset(t *testing.T) {
+	spaces := new(MockSpaceService)
+	messages := new(MockMessageService)
+	cfg := config.Default().SSE
+	cfg.ReplayLimit = 1
+	server, _ := setupEventServer(t, spaces, messages, cfg)
+
+	spaces.On("GetSpace", 1).Return(models.Space{ID: 1}, nil)
+	messages.On("GetMessagesAfter", 1, 5, 2).Return([]models.Message{{ID: 6, SpaceID: 1}, {ID: 7, SpaceID: 1}}, nil)
+
+	// 再送しきれない場合は一覧の取り直しを促す
+	_, r := openStream(t, server.URL+"/spaces/1/events", "5")
+	assert.Equal(t, "reset", readEvent(t, r)["event"])
+}
+
+func TestEventController_Stream_Errors(t *testing.T) {
+	spaces := new(MockSpaceService)
+	messages := new(MockMessageService)
+	cfg := config.Default().SSE
+	cfg.Heartbeat = 10 * time.Millisecond
+	server, _ := setupEventServer(t, spaces, messages, cfg)
+
+	spaces.On("GetSpace", 99).Return(models.Space{}, services.ErrSpaceNotFound)
+
+	resp, _ := openStream(t, server.URL+"/spaces/99/events", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = openStream(t, server.URL+"/spaces/1/events", "abc")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	messages.AssertNotCalled(t, "GetMessagesAfter")
+}
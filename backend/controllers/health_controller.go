@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"chat/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	Checker *health.Checker
+}
+
+func NewHealthController(checker *health.Checker) *HealthController {
+	return &HealthController{Checker: checker}
+}
+
+// liveness: プロセスが応答できれば常に 200（依存先の障害では再起動させない）
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// readiness: DB・マイグレーション・WebSocket ハブを確認し、1つでも異常なら 503
+func (c *HealthController) Ready(ctx *gin.Context) {
+	report := c.Checker.Run(ctx.Request.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
@@ -0,0 +1,56 @@
+package controllers_test
+
+import (
+	"chat/controllers"
+	"chat/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dbErr := error(nil)
+	checker := health.NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) error { return dbErr })
+	checker.Register("websocket_hub", func(ctx context.Context) error { return nil })
+
+	controller := controllers.NewHealthController(checker)
+	router := gin.New()
+	router.GET("/health/live", controller.Live)
+	router.GET("/health/ready", controller.Ready)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/health/ready")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// DB が落ちると readiness だけが失敗する
+	dbErr = errors.New("connection refused")
+	w = get("/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, health.StatusOK, report.Checks["websocket_hub"].Status)
+
+	w = get("/health/live")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+}
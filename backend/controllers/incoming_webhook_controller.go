@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"chat/dto"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 受信用 Webhook のトークンを送るヘッダー（URL に含めるとアクセスログに残るため）
+const hookTokenHeader = "X-Hook-Token"
+
+// 外部のシステム（CI・監視など）がユーザーなしでスペースに投稿するための受信用 Webhook
+type IncomingWebhookController struct {
+	Service services.IncomingWebhookService
+}
+
+func NewIncomingWebhookController(service services.IncomingWebhookService) *IncomingWebhookController {
+	return &IncomingWebhookController{Service: service}
+}
+
+// 受信用 Webhook のレスポンス（トークンは作成時のみ返す）
+type incomingWebhookResponse struct {
+	models.IncomingWebhook
+	// 投稿先のパス
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"`
+}
+
+func toIncomingWebhookResponse(hook models.IncomingWebhook, token string) incomingWebhookResponse {
+	return incomingWebhookResponse{IncomingWebhook: hook, URL: fmt.Sprintf("/api/v2/hooks/%d", hook.ID), Token: token}
+}
+
+// 受信用 Webhook 作成
+func (c *IncomingWebhookController) PostIncomingWebhook(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+	var req dto.CreateIncomingWebhookRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	hook, token, err := c.Service.CreateIncomingWebhook(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, req.Name)
+	if err != nil {
+		abortWithError(ctx, err, errWebhookCreateFailed)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v2/spaces/%d/incoming-webhooks/%d", hook.SpaceID, hook.ID))
+	ctx.JSON(http.StatusCreated, toIncomingWebhookResponse(hook, token))
+}
+
+// 受信用 Webhook 一覧
+func (c *IncomingWebhookController) ListIncomingWebhooks(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	hooks, err := c.Service.ListIncomingWebhooks(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errWebhookListFailed)
+		return
+	}
+
+	res := make([]incomingWebhookResponse, len(hooks))
+	for i, hook := range hooks {
+		res[i] = toIncomingWebhookResponse(hook, "")
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// 受信用 Webhook 削除
+func (c *IncomingWebhookController) DeleteIncomingWebhook(ctx *gin.Context) {
+	var path dto.IncomingWebhookPath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.DeleteIncomingWebhook(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.HookID); err != nil {
+		abortWithError(ctx, err, errWebhookDeleteFailed)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// 受信用 Webhook への投稿。保存したメッセージはユーザーの投稿と同じく配信される
+func (c *IncomingWebhookController) PostHookMessage(ctx *gin.Context) {
+	var path dto.HookPath
+	if !bindURI(ctx, &path) {
+		return
+	}
+	var req dto.HookMessageRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	msg, err := c.Service.PostMessage(ctx.Request.Context(), path.HookID, ctx.GetHeader(hookTokenHeader), req.Username, req.Text)
+	if err != nil {
+		abortWithError(ctx, err, errMessageSaveFailed)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v2/spaces/%d/messages/%d", msg.SpaceID, msg.ID))
+	ctx.JSON(http.StatusCreated, msg)
+}
@@ -0,0 +1,111 @@
+package controllers_test
+
+import (
+	"bytes"
+	"chat/controllers"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIncomingWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockIncomingWebhookService) CreateIncomingWebhook(ctx context.Context, actor string, spaceID int, name string) (models.IncomingWebhook, string, error) {
+	args := m.Called(actor, spaceID, name)
+	return args.Get(0).(models.IncomingWebhook), args.String(1), args.Error(2)
+}
+
+func (m *MockIncomingWebhookService) ListIncomingWebhooks(ctx context.Context, actor string, spaceID int) ([]models.IncomingWebhook, error) {
+	args := m.Called(actor, spaceID)
+	return args.Get(0).([]models.IncomingWebhook), args.Error(1)
+}
+
+func (m *MockIncomingWebhookService) DeleteIncomingWebhook(ctx context.Context, actor string, spaceID, hookID int) error {
+	args := m.Called(actor, spaceID, hookID)
+	return args.Error(0)
+}
+
+func (m *MockIncomingWebhookService) PostMessage(ctx context.Context, hookID int, token, username, text string) (models.Message, error) {
+	args := m.Called(hookID, token, username, text)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+func TestIncomingWebhookController_PostIncomingWebhook(t *testing.T) {
+	service := new(MockIncomingWebhookService)
+	users := new(MockUserService)
+	controller := controllers.NewIncomingWebhookController(service)
+	router := setupRouterSpace()
+	router.Use(middlewares.Authenticate(users))
+	router.POST("/spaces/:spaceId/incoming-webhooks", middlewares.RequireAuth(), controller.PostIncomingWebhook)
+	router.GET("/spaces/:spaceId/incoming-webhooks", middlewares.RequireAuth(), controller.ListIncomingWebhooks)
+
+	hook := models.IncomingWebhook{ID: 2, SpaceID: 1, Name: "CI", TokenHash: "hash"}
+	users.On("VerifyToken", "alice-token").Return("alice", nil)
+	service.On("CreateIncomingWebhook", "alice", 1, "CI").Return(hook, "t0ken", nil).Once()
+	service.On("ListIncomingWebhooks", "alice", 1).Return([]models.IncomingWebhook{hook}, nil).Once()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/spaces/1/incoming-webhooks", bytes.NewBufferString(`{"name":"CI"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer alice-token")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/spaces/1/incoming-webhooks/2", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"url":"/api/v2/hooks/2","token":"t0ken"`)
+	assert.NotContains(t, w.Body.String(), "hash")
+
+	// トークンは作成時のみ返す
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/spaces/1/incoming-webhooks", nil)
+	req.Header.Set("Authorization", "Bearer alice-token")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "t0ken")
+
+	service.AssertExpectations(t)
+}
+
+func TestIncomingWebhookController_PostHookMessage(t *testing.T) {
+	service := new(MockIncomingWebhookService)
+	controller := controllers.NewIncomingWebhookController(service)
+	router := setupRouterSpace()
+	router.POST("/hooks/:hookId", controller.PostHookMessage)
+
+	service.On("PostMessage", 2, "t0ken", "", "build passed").
+		Return(models.Message{ID: 10, SpaceID: 1, Username: "CI", Bot: true, Text: "build passed"}, nil).Once()
+	service.On("PostMessage", 2, "wrong", "", "build passed").
+		Return(models.Message{}, services.ErrHookTokenInvalid).Once()
+
+	post := func(token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/hooks/2", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Hook-Token", token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("t0ken", `{"text":"build passed"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/spaces/1/messages/10", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"username":"CI","bot":true,"text":"build passed"`)
+
+	w = post("wrong", `{"text":"build passed"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"code":"hook_token_invalid","error":"Webhook のトークンが無効です"}`, w.Body.String())
+
+	w = post("t0ken", `{"text":"   "}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	service.AssertExpectations(t)
+}
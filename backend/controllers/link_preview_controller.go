@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"chat/dto"
+	"chat/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// メッセージ中のリンクのプレビュー
+type LinkPreviewController struct {
+	Service services.LinkPreviewService
+}
+
+func NewLinkPreviewController(service services.LinkPreviewService) *LinkPreviewController {
+	return &LinkPreviewController{Service: service}
+}
+
+// メッセージのリンクのプレビュー一覧（取得できたもののみ）
+func (c *LinkPreviewController) ListMessagePreviews(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	previews, err := c.Service.GetMessagePreviews(ctx.Request.Context(), path.SpaceID, path.MessageID)
+	if err != nil {
+		abortWithError(ctx, err, errPreviewFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, previews)
+}
@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"chat/controllers"
+	"chat/linkpreview"
+	"chat/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLinkPreviewService struct {
+	mock.Mock
+}
+
+func (m *MockLinkPreviewService) GetMessagePreviews(ctx context.Context, spaceID, messageID int) ([]linkpreview.Preview, error) {
+	args := m.Called(spaceID, messageID)
+	return args.Get(0).([]linkpreview.Preview), args.Error(1)
+}
+
+func TestLinkPreviewController_ListMessagePreviews(t *testing.T) {
+	service := new(MockLinkPreviewService)
+	controller := controllers.NewLinkPreviewController(service)
+	router := setupRouterSpace()
+	router.GET("/spaces/:spaceId/messages/:messageId/previews", controller.ListMessagePreviews)
+
+	service.On("GetMessagePreviews", 1, 10).Return([]linkpreview.Preview{{URL: "https://example.com", Title: "Example", Image: "https://example.com/og.png"}}, nil).Once()
+	service.On("GetMessagePreviews", 1, 11).Return([]linkpreview.Preview(nil), services.ErrMessageNotFound).Once()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/spaces/1/messages/10/previews", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"url":"https://example.com","title":"Example","image":"https://example.com/og.png"}]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/spaces/1/messages/11/previews", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"message_not_found"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/spaces/1/messages/abc/previews", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	service.AssertExpectations(t)
+}
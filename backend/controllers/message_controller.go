@@ -1,35 +1,36 @@
 package controllers
 
 import (
-	"chat/models"
+	"chat/dto"
+	"chat/i18n"
+	"chat/middlewares"
 	"chat/services"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type MessageController struct {
-	Service services.MessageService
+	Service  services.MessageService
+	Commands services.CommandService
+	Logger   *slog.Logger
 }
 
-func NewMessageController(service services.MessageService) *MessageController {
-	return &MessageController{Service: service}
+func NewMessageController(service services.MessageService, commands services.CommandService, logger *slog.Logger) *MessageController {
+	return &MessageController{Service: service, Commands: commands, Logger: logger}
 }
 
 func (c *MessageController) GetMessages(ctx *gin.Context) {
-	spaceIdStr := ctx.Query("spaceId")
-	spaceId, err := strconv.Atoi(spaceIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無効な spaceId"})
+	var query dto.GetMessagesQuery
+	if !bindQuery(ctx, &query) {
 		return
 	}
 
-	messages, err := c.Service.GetMessages(spaceId)
+	messages, err := c.Service.GetMessages(ctx.Request.Context(), query.SpaceID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "メッセージ取得失敗"})
+		abortWithError(ctx, err, errMessageFetchFailed)
 		return
 	}
 
@@ -38,41 +39,174 @@ func (c *MessageController) GetMessages(ctx *gin.Context) {
 
 // メッセージ作成API
 func (c *MessageController) CreateMessage(ctx *gin.Context) {
-	fmt.Println("メッセージ作成エンドポイントにリクエストが来ました")
-
-	var msg models.Message // `models.Message` を使用
+	var req dto.CreateMessageRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
 
-	if err := ctx.ShouldBindJSON(&msg); err != nil {
-		log.Printf("リクエストパースエラー: %v", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "リクエストのパースに失敗しました"})
+	msg := req.ToModel()
+	if call, ok := services.ParseCommand(msg, middlewares.Username(ctx)); ok {
+		c.runCommand(ctx, call)
+		return
+	}
+	// 保存したメッセージ（ID・HTML を含む）を返す
+	saved, err := c.Service.CreateMessage(ctx.Request.Context(), msg)
+	if err != nil {
+		abortWithError(ctx, err, errMessageSaveFailed)
 		return
 	}
-	log.Printf("受信したメッセージ: %+v", msg)
-	id, err := c.Service.CreateMessage(msg)
+
+	c.Logger.DebugContext(ctx.Request.Context(), "メッセージを作成しました", "message_id", saved.ID, "space_id", saved.SpaceID)
+	ctx.JSON(http.StatusCreated, saved)
+}
+
+// "/" で始まるメッセージはコマンドとして実行し、保存せずに応答を返す（200）
+// 公開の応答は投稿したメッセージを含む。
+func (c *MessageController) runCommand(ctx *gin.Context, call services.CommandCall) {
+	call.Language = i18n.Language(ctx)
+	res, err := c.Commands.Execute(ctx.Request.Context(), call)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "メッセージの保存に失敗しました"})
+		abortWithError(ctx, err, errCommandFailed)
 		return
 	}
 
-	msg.ID = id
-	ctx.JSON(http.StatusCreated, msg)
+	c.Logger.DebugContext(ctx.Request.Context(), "コマンドを実行しました", "command", call.Name, "space_id", call.SpaceID, "response_type", res.ResponseType)
+	ctx.JSON(http.StatusOK, res)
 }
 
 func (c *MessageController) DeleteMessage(ctx *gin.Context) {
-	messageID, err1 := strconv.Atoi(ctx.Query("id"))
-	spaceID, err2 := strconv.Atoi(ctx.Query("spaceId"))
+	var query dto.DeleteMessageQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	if err := c.Service.DeleteMessage(ctx.Request.Context(), query.ID, query.SpaceID); err != nil {
+		abortWithError(ctx, err, errMessageDeleteFailed)
+		return
+	}
+
+	c.Logger.DebugContext(ctx.Request.Context(), "メッセージを削除しました", "message_id", query.ID, "space_id", query.SpaceID)
+	ctx.JSON(http.StatusOK, gin.H{"message": i18n.Translate(ctx, "message_deleted")})
+}
+
+// スペースのメッセージ一覧（v2）
+func (c *MessageController) ListSpaceMessages(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	messages, err := c.Service.GetMessages(ctx.Request.Context(), path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errMessageFetchFailed)
+		return
+	}
 
-	if err1 != nil || err2 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "メッセージIDまたはスペースIDが無効です"})
+	ctx.JSON(http.StatusOK, messages)
+}
+
+// メッセージ投稿（v2）
+func (c *MessageController) PostSpaceMessage(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+	var req dto.PostMessageRequest
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	log.Printf("受信した削除リクエスト - メッセージID: %d, スペースID: %d", messageID, spaceID)
+	msg := req.ToModel(path.SpaceID)
+	if call, ok := services.ParseCommand(msg, middlewares.Username(ctx)); ok {
+		c.runCommand(ctx, call)
+		return
+	}
+	saved, err := c.Service.CreateMessage(ctx.Request.Context(), msg)
+	if err != nil {
+		abortWithError(ctx, err, errMessageSaveFailed)
+		return
+	}
+
+	c.Logger.DebugContext(ctx.Request.Context(), "メッセージを作成しました", "message_id", saved.ID, "space_id", saved.SpaceID)
+	ctx.Header("Location", fmt.Sprintf("/api/v2/spaces/%d/messages/%d", saved.SpaceID, saved.ID))
+	ctx.JSON(http.StatusCreated, saved)
+}
+
+// メッセージ取得（v2）
+func (c *MessageController) GetSpaceMessage(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	msg, err := c.Service.GetMessage(ctx.Request.Context(), path.MessageID, path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errMessageFetchFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, msg)
+}
+
+// メッセージ削除（v2）。成功時は 204
+func (c *MessageController) DeleteSpaceMessage(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.DeleteMessage(ctx.Request.Context(), path.MessageID, path.SpaceID); err != nil {
+		abortWithError(ctx, err, errMessageDeleteFailed)
+		return
+	}
+
+	c.Logger.DebugContext(ctx.Request.Context(), "メッセージを削除しました", "message_id", path.MessageID, "space_id", path.SpaceID)
+	ctx.Status(http.StatusNoContent)
+}
+
+// スペースのピン留めされたメッセージ一覧（v2）
+func (c *MessageController) ListPinnedMessages(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	messages, err := c.Service.GetPinnedMessages(ctx.Request.Context(), path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errMessageFetchFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, messages)
+}
+
+// メッセージのピン留め（v2）。スペースの所有者と管理者のみ
+func (c *MessageController) PutPin(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	msg, err := c.Service.PinMessage(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.MessageID)
+	if err != nil {
+		abortWithError(ctx, err, errPinFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, msg)
+}
+
+// ピン留めの解除（v2）。成功時は 204
+func (c *MessageController) DeletePin(ctx *gin.Context) {
+	var path dto.MessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
 
-	if err := c.Service.DeleteMessage(messageID, spaceID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.Service.UnpinMessage(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.MessageID); err != nil {
+		abortWithError(ctx, err, errUnpinFailed)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "メッセージ削除成功"})
+	ctx.Status(http.StatusNoContent)
 }
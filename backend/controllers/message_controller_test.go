@@ -2,10 +2,16 @@ package controllers_test
 
 import (
 	"bytes"
+	"chat/apperrors"
 	"chat/controllers"
+	"chat/logging"
+	"chat/middlewares"
 	"chat/models"
+	"chat/services"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -20,29 +26,79 @@ type MockMessageService struct {
 	mock.Mock
 }
 
-func (m *MockMessageService) GetMessages(spaceID int) ([]models.Message, error) {
+func (m *MockMessageService) GetMessages(ctx context.Context, spaceID int) ([]models.Message, error) {
 	args := m.Called(spaceID)
 	return args.Get(0).([]models.Message), args.Error(1)
 }
 
-func (m *MockMessageService) CreateMessage(msg models.Message) (int, error) {
+func (m *MockMessageService) GetMessage(ctx context.Context, messageID, spaceID int) (models.Message, error) {
+	args := m.Called(messageID, spaceID)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) GetMessagesAfter(ctx context.Context, spaceID, afterID, limit int) ([]models.Message, error) {
+	args := m.Called(spaceID, afterID, limit)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) CreateMessage(ctx context.Context, msg models.Message) (models.Message, error) {
+	args := m.Called(msg)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) CreateBotMessage(ctx context.Context, msg models.Message) (models.Message, error) {
 	args := m.Called(msg)
-	return args.Int(0), args.Error(1)
+	return args.Get(0).(models.Message), args.Error(1)
 }
 
-func (m *MockMessageService) DeleteMessage(messageID, spaceID int) error {
+func (m *MockMessageService) DeleteMessage(ctx context.Context, messageID, spaceID int) error {
 	args := m.Called(messageID, spaceID)
 	return args.Error(0)
 }
 
+func (m *MockMessageService) PinMessage(ctx context.Context, actor string, spaceID, messageID int) (models.Message, error) {
+	args := m.Called(actor, spaceID, messageID)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) UnpinMessage(ctx context.Context, actor string, spaceID, messageID int) error {
+	args := m.Called(actor, spaceID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) GetPinnedMessages(ctx context.Context, spaceID int) ([]models.Message, error) {
+	args := m.Called(spaceID)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+// MockCommandService は CommandService をモックする
+type MockCommandService struct {
+	mock.Mock
+}
+
+func (m *MockCommandService) Register(name, usage string, fn services.CommandFunc) {
+	m.Called(name, usage)
+}
+
+func (m *MockCommandService) Builtin(name string) bool {
+	return m.Called(name).Bool(0)
+}
+
+func (m *MockCommandService) Execute(ctx context.Context, call services.CommandCall) (services.CommandResponse, error) {
+	args := m.Called(call)
+	return args.Get(0).(services.CommandResponse), args.Error(1)
+}
+
 func setupRouterMessage() *gin.Engine {
 	gin.SetMode(gin.TestMode)
-	return gin.Default()
+	router := gin.Default()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	return router
 }
 
 func TestMessageController_GetMessages(t *testing.T) {
 	mockService := new(MockMessageService)
-	controller := controllers.NewMessageController(mockService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
 	router := setupRouterMessage()
 	router.GET("/messages", controller.GetMessages)
 
@@ -78,12 +134,13 @@ func TestMessageController_GetMessages(t *testing.T) {
 
 func TestMessageController_CreateMessage(t *testing.T) {
 	mockService := new(MockMessageService)
-	controller := controllers.NewMessageController(mockService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
 	router := setupRouterMessage()
 	router.POST("/messages", controller.CreateMessage)
 
 	newMessage := models.Message{SpaceID: 1, Username: "user1", Text: "Hello"}
-	mockService.On("CreateMessage", mock.AnythingOfType("models.Message")).Return(1, nil)
+	// 保存したメッセージ（HTML を含む）を返す
+	mockService.On("CreateMessage", newMessage).Return(models.Message{ID: 1, SpaceID: 1, Username: "user1", Text: "Hello", HTML: "<p>Hello</p>"}, nil)
 
 	jsonData, _ := json.Marshal(newMessage)
 	w := httptest.NewRecorder()
@@ -92,6 +149,8 @@ func TestMessageController_CreateMessage(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":1`)
+	assert.Contains(t, w.Body.String(), `"html":"\u003cp\u003eHello\u003c/p\u003e"`)
 	mockService.AssertExpectations(t)
 
 	w = httptest.NewRecorder()
@@ -102,9 +161,28 @@ func TestMessageController_CreateMessage(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestMessageController_CreateMessage_SpaceNotFound(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.POST("/messages", controller.CreateMessage)
+
+	mockService.On("CreateMessage", mock.AnythingOfType("models.Message")).Return(models.Message{}, services.ErrSpaceNotFound)
+
+	jsonData, _ := json.Marshal(models.Message{SpaceID: 99, Username: "user1", Text: "Hello"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/messages", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"space_not_found","error":"スペースが見つかりません"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
 func TestMessageController_DeleteMessage(t *testing.T) {
 	mockService := new(MockMessageService)
-	controller := controllers.NewMessageController(mockService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
 	router := setupRouterMessage()
 	router.DELETE("/messages", controller.DeleteMessage)
 
@@ -134,3 +212,215 @@ func TestMessageController_DeleteMessage(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestMessageController_DeleteMessage_NotFound(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.DELETE("/messages", controller.DeleteMessage)
+
+	mockService.On("DeleteMessage", 3, 1).Return(services.ErrMessageNotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/messages?id=3&spaceId=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"message_not_found","error":"メッセージが見つかりませんでした"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestMessageController_CreateMessage_Validation(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.POST("/messages", controller.CreateMessage)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/messages", bytes.NewBufferString(`{"space_id": 0, "username": "user1", "text": ""}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{
+		"code": "validation_failed",
+		"error": "入力内容に誤りがあります",
+		"fields": {
+			"space_id": {"code": "required", "message": "必須項目です"},
+			"text": {"code": "required", "message": "必須項目です"}
+		}
+	}`, w.Body.String())
+
+	mockService.AssertNotCalled(t, "CreateMessage", mock.Anything)
+}
+
+// タイムアウト・切断で DB の処理が打ち切られた場合
+func TestMessageController_GetMessagesInterrupted(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.GET("/messages", controller.GetMessages)
+
+	mockService.On("GetMessages", 1).Return([]models.Message(nil), fmt.Errorf("%w: %w", apperrors.ErrTimeout, context.DeadlineExceeded))
+	mockService.On("GetMessages", 2).Return([]models.Message(nil), fmt.Errorf("%w: %w", apperrors.ErrCanceled, context.Canceled))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/messages?spaceId=1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"request_timeout"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/messages?spaceId=2", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 499, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"request_canceled"`)
+}
+
+// v2: パスのスペースに投稿し、201 と Location を返す
+func TestMessageController_PostSpaceMessage(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.POST("/api/v2/spaces/:spaceId/messages", controller.PostSpaceMessage)
+
+	mockService.On("CreateMessage", models.Message{SpaceID: 1, Username: "user1", Text: "Hello"}).Return(models.Message{ID: 42, SpaceID: 1, Username: "user1", Text: "Hello", HTML: "<p>Hello</p>"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v2/spaces/1/messages", bytes.NewBufferString(`{"username":"user1","text":"Hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/spaces/1/messages/42", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"id":42,"space_id":1`)
+	assert.Contains(t, w.Body.String(), `"html":"\u003cp\u003eHello\u003c/p\u003e"`)
+
+	// パスのスペース ID が 0
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v2/spaces/0/messages", bytes.NewBufferString(`{"username":"user1","text":"Hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"fields":{"spaceId"`)
+
+	mockService.AssertExpectations(t)
+}
+
+// "/" で始まる投稿はコマンドとして実行し、メッセージとしては保存しない
+func TestMessageController_PostSpaceMessage_Command(t *testing.T) {
+	mockService := new(MockMessageService)
+	commands := new(MockCommandService)
+	controller := controllers.NewMessageController(mockService, commands, logging.Discard())
+	router := setupRouterMessage()
+	router.Use(middlewares.Language())
+	router.POST("/api/v2/spaces/:spaceId/messages", controller.PostSpaceMessage)
+
+	// 自分だけに見える応答はリクエストの言語で返す
+	commands.On("Execute", services.CommandCall{Name: "topic", Args: "release", SpaceID: 1, Username: "user1", Language: "ja"}).
+		Return(services.CommandResponse{}, services.ErrForbidden).Once()
+	commands.On("Execute", services.CommandCall{Name: "help", SpaceID: 1, Username: "user1", Language: "en"}).
+		Return(services.CommandResponse{Command: "help", ResponseType: services.ResponseEphemeral, Text: "/help"}, nil).Once()
+
+	post := func(body, lang string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v2/spaces/1/messages", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", lang)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"username":"user1","text":"/help"}`, "en-US")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"command":"help","response_type":"ephemeral","text":"/help"}`, w.Body.String())
+
+	// 権限が必要なコマンドはエラーレスポンスになる
+	w = post(`{"username":"user1","text":"/topic release"}`, "ja")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	commands.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "CreateMessage", mock.Anything)
+}
+
+func TestMessageController_GetSpaceMessage(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.GET("/api/v2/spaces/:spaceId/messages/:messageId", controller.GetSpaceMessage)
+
+	mockService.On("GetMessage", 5, 1).Return(models.Message{ID: 5, SpaceID: 1, Username: "user1", Text: "Hello"}, nil)
+	mockService.On("GetMessage", 6, 1).Return(models.Message{}, services.ErrMessageNotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v2/spaces/1/messages/5", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"text":"Hello"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v2/spaces/1/messages/6", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"message_not_found","error":"メッセージが見つかりませんでした"}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestMessageController_DeleteSpaceMessage(t *testing.T) {
+	mockService := new(MockMessageService)
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.DELETE("/api/v2/spaces/:spaceId/messages/:messageId", controller.DeleteSpaceMessage)
+
+	mockService.On("DeleteMessage", 5, 1).Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v2/spaces/1/messages/5", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+// ピン留めの変更はログイン中のユーザーで権限を確認する
+func TestMessageController_Pins(t *testing.T) {
+	mockService := new(MockMessageService)
+	users := new(MockUserService)
+	users.On("VerifyToken", "alice-token").Return("alice", nil)
+	users.On("VerifyToken", "bob-token").Return("bob", nil)
+
+	controller := controllers.NewMessageController(mockService, nil, logging.Discard())
+	router := setupRouterMessage()
+	router.Use(middlewares.Authenticate(users))
+	router.GET("/api/v2/spaces/:spaceId/pins", controller.ListPinnedMessages)
+	router.PUT("/api/v2/spaces/:spaceId/pins/:messageId", middlewares.RequireAuth(), controller.PutPin)
+	router.DELETE("/api/v2/spaces/:spaceId/pins/:messageId", middlewares.RequireAuth(), controller.DeletePin)
+
+	pinned := models.Message{ID: 5, SpaceID: 1, Username: "user1", Text: "お知らせ", Pinned: true}
+	mockService.On("PinMessage", "alice", 1, 5).Return(pinned, nil).Once()
+	mockService.On("PinMessage", "bob", 1, 5).Return(models.Message{}, services.ErrForbidden).Once()
+	mockService.On("UnpinMessage", "alice", 1, 5).Return(nil).Once()
+	mockService.On("GetPinnedMessages", 1).Return([]models.Message{pinned}, nil).Once()
+
+	w := webhookRequest(router, "PUT", "/api/v2/spaces/1/pins/5", "alice-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"pinned":true`)
+
+	w = webhookRequest(router, "PUT", "/api/v2/spaces/1/pins/5", "bob-token", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = webhookRequest(router, "PUT", "/api/v2/spaces/1/pins/5", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = webhookRequest(router, "GET", "/api/v2/spaces/1/pins", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"text":"お知らせ"`)
+
+	w = webhookRequest(router, "DELETE", "/api/v2/spaces/1/pins/5", "alice-token", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"chat/dto"
+	"chat/middlewares"
+	"chat/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 予約投稿・リマインダーの管理。ログイン中のユーザーは自分の予約のみ操作できる
+type ScheduledMessageController struct {
+	Service services.ScheduledMessageService
+}
+
+func NewScheduledMessageController(service services.ScheduledMessageService) *ScheduledMessageController {
+	return &ScheduledMessageController{Service: service}
+}
+
+// メッセージの予約
+func (c *ScheduledMessageController) PostScheduledMessage(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+	var req dto.CreateScheduledMessageRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	sm, err := c.Service.Schedule(ctx.Request.Context(), middlewares.Username(ctx), req.ToModel(path.SpaceID))
+	if err != nil {
+		abortWithError(ctx, err, errScheduleFailed)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v2/spaces/%d/scheduled-messages/%d", sm.SpaceID, sm.ID))
+	ctx.JSON(http.StatusCreated, sm)
+}
+
+// 自分の送信待ちの予約一覧
+func (c *ScheduledMessageController) ListScheduledMessages(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	list, err := c.Service.ListScheduledMessages(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errScheduleListFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+// 予約の取り消し
+func (c *ScheduledMessageController) DeleteScheduledMessage(ctx *gin.Context) {
+	var path dto.ScheduledMessagePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.CancelScheduledMessage(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.ScheduledID); err != nil {
+		abortWithError(ctx, err, errScheduleCancelFailed)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
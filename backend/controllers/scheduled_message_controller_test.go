@@ -0,0 +1,114 @@
+package controllers_test
+
+import (
+	"chat/controllers"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockScheduledMessageService struct {
+	mock.Mock
+}
+
+func (m *MockScheduledMessageService) Schedule(ctx context.Context, actor string, sm models.ScheduledMessage) (models.ScheduledMessage, error) {
+	args := m.Called(actor, sm)
+	return args.Get(0).(models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageService) ListScheduledMessages(ctx context.Context, actor string, spaceID int) ([]models.ScheduledMessage, error) {
+	args := m.Called(actor, spaceID)
+	return args.Get(0).([]models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageService) CancelScheduledMessage(ctx context.Context, actor string, spaceID, scheduledID int) error {
+	args := m.Called(actor, spaceID, scheduledID)
+	return args.Error(0)
+}
+
+func (m *MockScheduledMessageService) Remind(ctx context.Context, call services.CommandCall) (services.CommandResponse, error) {
+	args := m.Called(call)
+	return args.Get(0).(services.CommandResponse), args.Error(1)
+}
+
+func setupScheduledMessageRouter(service *MockScheduledMessageService) *gin.Engine {
+	users := new(MockUserService)
+	users.On("VerifyToken", "alice-token").Return("alice", nil)
+
+	controller := controllers.NewScheduledMessageController(service)
+	router := setupRouterSpace()
+	router.Use(middlewares.Authenticate(users))
+	router.POST("/spaces/:spaceId/scheduled-messages", middlewares.RequireAuth(), controller.PostScheduledMessage)
+	router.GET("/spaces/:spaceId/scheduled-messages", middlewares.RequireAuth(), controller.ListScheduledMessages)
+	router.DELETE("/spaces/:spaceId/scheduled-messages/:scheduledId", middlewares.RequireAuth(), controller.DeleteScheduledMessage)
+	return router
+}
+
+func TestScheduledMessageController_PostScheduledMessage(t *testing.T) {
+	service := new(MockScheduledMessageService)
+	router := setupScheduledMessageRouter(service)
+
+	sendAt := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	input := models.ScheduledMessage{SpaceID: 1, Text: "おはよう", SendAt: sendAt, Kind: "reminder"}
+	created := input
+	created.ID = 5
+	created.Status = models.ScheduledPending
+	service.On("Schedule", "alice", input).Return(created, nil).Once()
+
+	w := webhookRequest(router, "POST", "/spaces/1/scheduled-messages", "alice-token", `{"text":"おはよう","send_at":"2026-10-20T09:00:00Z","kind":"reminder"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/spaces/1/scheduled-messages/5", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+
+	// 送信日時は必須、種類は message か reminder
+	w = webhookRequest(router, "POST", "/spaces/1/scheduled-messages", "alice-token", `{"text":"おはよう","kind":"email"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"send_at":{"code":"required"`)
+	assert.Contains(t, w.Body.String(), `"kind":{"code":"oneof"`)
+
+	// ログインが必要
+	w = webhookRequest(router, "POST", "/spaces/1/scheduled-messages", "", `{"text":"おはよう","send_at":"2026-10-20T09:00:00Z"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	service.AssertExpectations(t)
+}
+
+func TestScheduledMessageController_PostScheduledMessage_Invalid(t *testing.T) {
+	service := new(MockScheduledMessageService)
+	router := setupScheduledMessageRouter(service)
+	service.On("Schedule", "alice", mock.AnythingOfType("models.ScheduledMessage")).Return(models.ScheduledMessage{}, services.ErrScheduleInvalid)
+
+	w := webhookRequest(router, "POST", "/spaces/1/scheduled-messages", "alice-token", `{"text":"おはよう","send_at":"2020-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"schedule_invalid"`)
+}
+
+func TestScheduledMessageController_ListAndDelete(t *testing.T) {
+	service := new(MockScheduledMessageService)
+	router := setupScheduledMessageRouter(service)
+
+	service.On("ListScheduledMessages", "alice", 1).Return([]models.ScheduledMessage{{ID: 5, SpaceID: 1, Text: "おはよう"}}, nil).Once()
+	service.On("CancelScheduledMessage", "alice", 1, 5).Return(nil).Once()
+	service.On("CancelScheduledMessage", "alice", 1, 9).Return(services.ErrScheduleNotFound).Once()
+
+	w := webhookRequest(router, "GET", "/spaces/1/scheduled-messages", "alice-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"text":"おはよう"`)
+
+	w = webhookRequest(router, "DELETE", "/spaces/1/scheduled-messages/5", "alice-token", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = webhookRequest(router, "DELETE", "/spaces/1/scheduled-messages/9", "alice-token", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"schedule_not_found"`)
+
+	service.AssertExpectations(t)
+}
@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	"chat/dto"
+	"chat/i18n"
+	"chat/middlewares"
 	"chat/services"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,30 +21,60 @@ func NewSpaceController(service services.SpaceService) *SpaceController {
 
 // スペース作成エンドポイント
 func (c *SpaceController) CreateSpace(ctx *gin.Context) {
-	var data struct {
-		Name string `json:"name"`
-	}
-	if err := ctx.ShouldBindJSON(&data); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "リクエストのパースに失敗しました"})
+	var req dto.CreateSpaceRequest
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	err := c.Service.CreateSpace(data.Name)
+	_, err := c.Service.CreateSpace(ctx.Request.Context(), req.Name, middlewares.Username(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "スペースの作成に失敗しました"})
+		abortWithError(ctx, err, errSpaceCreateFailed)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "スペースが作成されました"})
+	ctx.JSON(http.StatusCreated, gin.H{"message": i18n.Translate(ctx, "space_created")})
 }
 
 // スペース一覧取得エンドポイント
 func (c *SpaceController) GetSpaces(ctx *gin.Context) {
-	spaces, err := c.Service.GetSpaces()
+	spaces, err := c.Service.GetSpaces(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "スペース一覧の取得に失敗しました"})
+		abortWithError(ctx, err, errSpaceListFailed)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, spaces)
 }
+
+// スペース作成（v2）。作成したスペースを返す
+func (c *SpaceController) PostSpace(ctx *gin.Context) {
+	var req dto.CreateSpaceRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	space, err := c.Service.CreateSpace(ctx.Request.Context(), req.Name, middlewares.Username(ctx))
+	if err != nil {
+		abortWithError(ctx, err, errSpaceCreateFailed)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v2/spaces/%d", space.ID))
+	ctx.JSON(http.StatusCreated, space)
+}
+
+// スペース取得（v2）
+func (c *SpaceController) GetSpace(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	space, err := c.Service.GetSpace(ctx.Request.Context(), path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errSpaceFetchFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, space)
+}
@@ -3,11 +3,16 @@ package controllers_test
 import (
 	"bytes"
 	"chat/controllers"
+	"chat/logging"
+	"chat/middlewares"
 	"chat/models"
+	"chat/services"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -20,13 +25,18 @@ type MockSpaceService struct {
 	mock.Mock
 }
 
-func (m *MockSpaceService) CreateSpace(name string) error {
-	args := m.Called(name)
-	return args.Error(0)
+func (m *MockSpaceService) CreateSpace(ctx context.Context, name, owner string) (models.Space, error) {
+	args := m.Called(name, owner)
+	return args.Get(0).(models.Space), args.Error(1)
+}
+
+func (m *MockSpaceService) GetSpace(ctx context.Context, id int) (models.Space, error) {
+	args := m.Called(id)
+	return args.Get(0).(models.Space), args.Error(1)
 }
 
 // 修正: `[]models.Space` を返すように変更
-func (m *MockSpaceService) GetSpaces() ([]models.Space, error) {
+func (m *MockSpaceService) GetSpaces(ctx context.Context) ([]models.Space, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Space), args.Error(1)
 }
@@ -35,6 +45,7 @@ func (m *MockSpaceService) GetSpaces() ([]models.Space, error) {
 func setupRouterSpace() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
 	return router
 }
 
@@ -44,7 +55,7 @@ func TestSpaceController_CreateSpace(t *testing.T) {
 	router := setupRouterSpace()
 	router.POST("/spaces", controller.CreateSpace)
 
-	mockService.On("CreateSpace", "NewSpace").Return(nil).Once()
+	mockService.On("CreateSpace", "NewSpace", "").Return(models.Space{ID: 1, Name: "NewSpace"}, nil).Once()
 
 	newSpace := map[string]string{"name": "NewSpace"}
 	jsonData, _ := json.Marshal(newSpace)
@@ -63,9 +74,9 @@ func TestSpaceController_CreateSpace(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.JSONEq(t, `{"error":"リクエストのパースに失敗しました"}`, w.Body.String())
+	assert.JSONEq(t, `{"code":"invalid_request","error":"リクエストのパースに失敗しました"}`, w.Body.String())
 
-	mockService.On("CreateSpace", "ErrorSpace").Return(errors.New("DBエラー")).Once()
+	mockService.On("CreateSpace", "ErrorSpace", "").Return(models.Space{}, errors.New("DBエラー")).Once()
 
 	errorSpace := map[string]string{"name": "ErrorSpace"}
 	jsonData, _ = json.Marshal(errorSpace)
@@ -76,7 +87,7 @@ func TestSpaceController_CreateSpace(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.JSONEq(t, `{"error":"スペースの作成に失敗しました"}`, w.Body.String())
+	assert.JSONEq(t, `{"code":"space_create_failed","error":"スペースの作成に失敗しました"}`, w.Body.String())
 
 	mockService.AssertExpectations(t)
 }
@@ -110,7 +121,83 @@ func TestSpaceController_GetSpaces(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.JSONEq(t, `{"error":"スペース一覧の取得に失敗しました"}`, w.Body.String())
+	assert.JSONEq(t, `{"code":"space_list_failed","error":"スペース一覧の取得に失敗しました"}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestSpaceController_CreateSpace_Validation(t *testing.T) {
+	mockService := new(MockSpaceService)
+	controller := controllers.NewSpaceController(mockService)
+	router := setupRouterSpace()
+	router.POST("/spaces", controller.CreateSpace)
+
+	for _, name := range []string{"", "   ", strings.Repeat("あ", 101)} {
+		jsonData, _ := json.Marshal(map[string]string{"name": name})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/spaces", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "name=%q", name)
+		assert.Contains(t, w.Body.String(), `"fields":{"name"`)
+	}
+
+	mockService.AssertNotCalled(t, "CreateSpace", mock.Anything, mock.Anything)
+}
+
+// v2: 作成したスペースを 201 と Location で返す。ログイン中のユーザーが所有者になる
+func TestSpaceController_PostSpace(t *testing.T) {
+	mockService := new(MockSpaceService)
+	userService := new(MockUserService)
+	controller := controllers.NewSpaceController(mockService)
+	router := setupRouterSpace()
+	router.Use(middlewares.Authenticate(userService))
+	router.POST("/api/v2/spaces", controller.PostSpace)
+
+	userService.On("VerifyToken", "alice-token").Return("alice", nil)
+	mockService.On("CreateSpace", "NewSpace", "alice").Return(models.Space{ID: 3, Name: "NewSpace"}, nil).Once()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v2/spaces", bytes.NewBufferString(`{"name":"NewSpace"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer alice-token")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/spaces/3", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"id":3,"name":"NewSpace"`)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestSpaceController_GetSpace(t *testing.T) {
+	mockService := new(MockSpaceService)
+	controller := controllers.NewSpaceController(mockService)
+	router := setupRouterSpace()
+	router.GET("/api/v2/spaces/:spaceId", controller.GetSpace)
+
+	mockService.On("GetSpace", 1).Return(models.Space{ID: 1, Name: "general"}, nil).Once()
+	mockService.On("GetSpace", 99).Return(models.Space{}, services.ErrSpaceNotFound).Once()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v2/spaces/1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":1,"name":"general"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v2/spaces/99", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"space_not_found","error":"スペースが見つかりません"}`, w.Body.String())
+
+	// パスの ID が数値でない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v2/spaces/abc", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 
 	mockService.AssertExpectations(t)
 }
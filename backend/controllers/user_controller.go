@@ -1,7 +1,9 @@
 package controllers
 
 import (
-	"chat/models"
+	"chat/dto"
+	"chat/i18n"
+	"chat/middlewares"
 	"chat/services"
 	"net/http"
 
@@ -18,33 +20,63 @@ func NewUserController(service services.UserService) *UserController {
 
 // ユーザー登録API
 func (c *UserController) RegisterUser(ctx *gin.Context) {
-	var user models.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "リクエストのパースに失敗しました"})
+	var req dto.RegisterUserRequest
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	if err := c.Service.RegisterUser(user); err != nil {
-		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	if err := c.Service.RegisterUser(ctx.Request.Context(), req.ToModel()); err != nil {
+		abortWithError(ctx, err, errRegisterFailed)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "ユーザー登録成功"})
+	ctx.JSON(http.StatusCreated, gin.H{"message": i18n.Translate(ctx, "user_registered")})
 }
 
 func (c *UserController) LoginUser(ctx *gin.Context) {
-	var user models.User
+	c.login(ctx, http.StatusOK)
+}
+
+// ログイン（v2: POST /sessions でセッションのトークンを作成する）
+func (c *UserController) CreateSession(ctx *gin.Context) {
+	c.login(ctx, http.StatusCreated)
+}
 
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "リクエストのパースに失敗しました"})
+func (c *UserController) login(ctx *gin.Context, status int) {
+	var req dto.LoginRequest
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	token, err := c.Service.AuthenticateUser(user)
+	token, err := c.Service.AuthenticateUser(ctx.Request.Context(), req.ToModel(), ctx.ClientIP())
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		abortWithError(ctx, err, errLoginFailed)
+		return
+	}
+
+	ctx.JSON(status, gin.H{"token": token})
+}
+
+// アカウントロック解除API（管理者のみ）
+func (c *UserController) UnlockUser(ctx *gin.Context) {
+	if !c.unlock(ctx) {
 		return
 	}
+	ctx.JSON(http.StatusOK, gin.H{"message": i18n.Translate(ctx, "account_unlocked")})
+}
 
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+// アカウントロック解除（v2: DELETE /admin/users/:username/lock）。成功時は 204
+func (c *UserController) DeleteLock(ctx *gin.Context) {
+	if !c.unlock(ctx) {
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *UserController) unlock(ctx *gin.Context) bool {
+	if err := c.Service.UnlockUser(ctx.Request.Context(), middlewares.Username(ctx), ctx.Param("username"), ctx.ClientIP()); err != nil {
+		abortWithError(ctx, err, errUnlockFailed)
+		return false
+	}
+	return true
 }
@@ -3,7 +3,11 @@ package controllers_test
 import (
 	"bytes"
 	"chat/controllers"
+	"chat/logging"
+	"chat/middlewares"
 	"chat/models"
+	"chat/services"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -19,19 +23,30 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) RegisterUser(user models.User) error {
+func (m *MockUserService) RegisterUser(ctx context.Context, user models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserService) AuthenticateUser(user models.User) (string, error) {
-	args := m.Called(user)
+func (m *MockUserService) AuthenticateUser(ctx context.Context, user models.User, clientIP string) (string, error) {
+	args := m.Called(user, clientIP)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserService) VerifyToken(token string) (string, error) {
+	args := m.Called(token)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockUserService) UnlockUser(ctx context.Context, actor, username, clientIP string) error {
+	args := m.Called(actor, username, clientIP)
+	return args.Error(0)
+}
+
 func setupRouterUser() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
 	return router
 }
 
@@ -41,7 +56,7 @@ func TestUserController_RegisterUser(t *testing.T) {
 	router := setupRouterUser()
 	router.POST("/register", controller.RegisterUser)
 
-	validUser := models.User{Username: "testuser", Password: "securepass"}
+	validUser := models.User{Username: "testuser", Password: "securepass1"}
 	mockService.On("RegisterUser", validUser).Return(nil).Once()
 
 	jsonData, _ := json.Marshal(validUser)
@@ -60,7 +75,7 @@ func TestUserController_RegisterUser(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	mockService.On("RegisterUser", validUser).Return(errors.New("ユーザーは既に存在します")).Once()
+	mockService.On("RegisterUser", validUser).Return(services.ErrUsernameTaken).Once()
 
 	w = httptest.NewRecorder()
 	req, _ = http.NewRequest("POST", "/register", bytes.NewBuffer(jsonData))
@@ -68,7 +83,18 @@ func TestUserController_RegisterUser(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusConflict, w.Code)
-	assert.JSONEq(t, `{"error": "ユーザーは既に存在します"}`, w.Body.String())
+	assert.JSONEq(t, `{"code": "username_taken", "error": "ユーザー名が既に使用されています"}`, w.Body.String())
+
+	// DB障害は 409 ではなく 500
+	mockService.On("RegisterUser", validUser).Return(errors.New("DBエラー")).Once()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"code": "register_failed", "error": "ユーザー登録に失敗しました"}`, w.Body.String())
 
 	mockService.AssertExpectations(t)
 }
@@ -80,12 +106,13 @@ func TestUserController_LoginUser(t *testing.T) {
 	router.POST("/login", controller.LoginUser)
 
 	validUser := models.User{Username: "testuser", Password: "securepass"}
-	mockService.On("AuthenticateUser", validUser).Return("valid-token", nil).Once()
+	mockService.On("AuthenticateUser", validUser, "192.0.2.1").Return("valid-token", nil).Once()
 
 	jsonData, _ := json.Marshal(validUser)
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
 	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:1234"
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -98,15 +125,86 @@ func TestUserController_LoginUser(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	mockService.On("AuthenticateUser", validUser).Return("", errors.New("認証失敗")).Once()
+	mockService.On("AuthenticateUser", validUser, "192.0.2.1").Return("", services.ErrInvalidCredentials).Once()
 
 	w = httptest.NewRecorder()
 	req, _ = http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
 	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:1234"
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
-	assert.JSONEq(t, `{"error": "認証失敗"}`, w.Body.String())
+	assert.JSONEq(t, `{"code": "invalid_credentials", "error": "認証失敗"}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUserController_RegisterUser_Validation(t *testing.T) {
+	mockService := new(MockUserService)
+	controller := controllers.NewUserController(mockService)
+	router := setupRouterUser()
+	router.POST("/register", controller.RegisterUser)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/register", bytes.NewBufferString(`{"username": "a b", "password": "short"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{
+		"code": "validation_failed",
+		"error": "入力内容に誤りがあります",
+		"fields": {
+			"username": {"code": "username", "message": "英数字と _ . - のみ使用できます"},
+			"password": {"code": "too_short", "message": "8文字以上で入力してください"}
+		}
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/register", bytes.NewBufferString(`{"username": "", "password": "password"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":{"code":"required"`)
+	assert.Contains(t, w.Body.String(), `"password":{"code":"password"`)
+
+	mockService.AssertNotCalled(t, "RegisterUser", mock.Anything)
+}
+
+func TestUserController_UnlockUser(t *testing.T) {
+	mockService := new(MockUserService)
+	controller := controllers.NewUserController(mockService)
+	router := setupRouterUser()
+	router.Use(middlewares.Authenticate(mockService))
+	router.POST("/admin/users/:username/unlock", middlewares.RequireAuth(), controller.UnlockUser)
+
+	mockService.On("VerifyToken", "admin-token").Return("admin", nil)
+	mockService.On("VerifyToken", "member-token").Return("member", nil)
+	mockService.On("UnlockUser", "admin", "testuser", "192.0.2.1").Return(nil).Once()
+	mockService.On("UnlockUser", "member", "testuser", "192.0.2.1").Return(services.ErrForbidden).Once()
+
+	unlock := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/users/testuser/unlock", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := unlock("admin-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message": "アカウントのロックを解除しました"}`, w.Body.String())
+
+	w = unlock("member-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"code": "forbidden", "error": "この操作を行う権限がありません"}`, w.Body.String())
+
+	w = unlock("")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
 
 	mockService.AssertExpectations(t)
 }
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"chat/dto"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// スペースの Webhook（イベントの通知先）の管理。スペースの所有者と管理者のみ操作できる
+type WebhookController struct {
+	Service services.WebhookService
+}
+
+func NewWebhookController(service services.WebhookService) *WebhookController {
+	return &WebhookController{Service: service}
+}
+
+// Webhook のレスポンス（署名の鍵は登録時のみ返す）
+type webhookResponse struct {
+	models.Webhook
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+func toWebhookResponse(hook models.Webhook, withSecret bool) webhookResponse {
+	res := webhookResponse{Webhook: hook, Events: hook.EventList()}
+	if withSecret {
+		res.Secret = hook.Secret
+	}
+	return res
+}
+
+// Webhook 登録
+func (c *WebhookController) PostWebhook(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+	var req dto.CreateWebhookRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	hook, err := c.Service.CreateWebhook(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, req.URL, req.Events)
+	if err != nil {
+		abortWithError(ctx, err, errWebhookCreateFailed)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v2/spaces/%d/webhooks/%d", hook.SpaceID, hook.ID))
+	ctx.JSON(http.StatusCreated, toWebhookResponse(hook, true))
+}
+
+// Webhook 一覧
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	var path dto.SpacePath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	hooks, err := c.Service.ListWebhooks(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID)
+	if err != nil {
+		abortWithError(ctx, err, errWebhookListFailed)
+		return
+	}
+
+	res := make([]webhookResponse, len(hooks))
+	for i, hook := range hooks {
+		res[i] = toWebhookResponse(hook, false)
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Webhook 削除
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	var path dto.WebhookPath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	if err := c.Service.DeleteWebhook(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.WebhookID); err != nil {
+		abortWithError(ctx, err, errWebhookDeleteFailed)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// Webhook の最近の配信と送信の記録
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	var path dto.WebhookPath
+	if !bindURI(ctx, &path) {
+		return
+	}
+
+	deliveries, err := c.Service.ListDeliveries(ctx.Request.Context(), middlewares.Username(ctx), path.SpaceID, path.WebhookID)
+	if err != nil {
+		abortWithError(ctx, err, errWebhookListFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
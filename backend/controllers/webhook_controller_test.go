@@ -0,0 +1,146 @@
+package controllers_test
+
+import (
+	"bytes"
+	"chat/controllers"
+	"chat/middlewares"
+	"chat/models"
+	"chat/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) Notify(ctx context.Context, event services.SpaceEvent) {
+	m.Called(event)
+}
+
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, actor string, spaceID int, url string, events []string) (models.Webhook, error) {
+	args := m.Called(actor, spaceID, url, events)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) ListWebhooks(ctx context.Context, actor string, spaceID int) ([]models.Webhook, error) {
+	args := m.Called(actor, spaceID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, actor string, spaceID, webhookID int) error {
+	args := m.Called(actor, spaceID, webhookID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, actor string, spaceID, webhookID int) ([]models.WebhookDelivery, error) {
+	args := m.Called(actor, spaceID, webhookID)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func setupWebhookRouter(service *MockWebhookService) *gin.Engine {
+	users := new(MockUserService)
+	users.On("VerifyToken", "alice-token").Return("alice", nil)
+	users.On("VerifyToken", "bob-token").Return("bob", nil)
+
+	controller := controllers.NewWebhookController(service)
+	router := setupRouterSpace()
+	router.Use(middlewares.Authenticate(users))
+	router.POST("/spaces/:spaceId/webhooks", middlewares.RequireAuth(), controller.PostWebhook)
+	router.GET("/spaces/:spaceId/webhooks", middlewares.RequireAuth(), controller.ListWebhooks)
+	router.DELETE("/spaces/:spaceId/webhooks/:webhookId", middlewares.RequireAuth(), controller.DeleteWebhook)
+	router.GET("/spaces/:spaceId/webhooks/:webhookId/deliveries", middlewares.RequireAuth(), controller.ListDeliveries)
+	return router
+}
+
+func webhookRequest(router *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 登録時のみ署名の鍵を返す
+func TestWebhookController_PostWebhook(t *testing.T) {
+	service := new(MockWebhookService)
+	router := setupWebhookRouter(service)
+
+	hook := models.Webhook{ID: 3, SpaceID: 1, URL: "https://example.com/hook", Secret: "s3cret", Events: "message.created"}
+	service.On("CreateWebhook", "alice", 1, "https://example.com/hook", []string{"message.created"}).Return(hook, nil).Once()
+	service.On("CreateWebhook", "bob", 1, "https://example.com/hook", []string{"message.created"}).Return(models.Webhook{}, services.ErrForbidden).Once()
+	service.On("ListWebhooks", "alice", 1).Return([]models.Webhook{hook}, nil).Once()
+
+	body := `{"url":"https://example.com/hook","events":["message.created"]}`
+	w := webhookRequest(router, "POST", "/spaces/1/webhooks", "alice-token", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/spaces/1/webhooks/3", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `"secret":"s3cret"`)
+	assert.Contains(t, w.Body.String(), `"events":["message.created"]`)
+
+	w = webhookRequest(router, "GET", "/spaces/1/webhooks", "alice-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"url":"https://example.com/hook"`)
+	assert.NotContains(t, w.Body.String(), "s3cret")
+
+	w = webhookRequest(router, "POST", "/spaces/1/webhooks", "bob-token", body)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = webhookRequest(router, "POST", "/spaces/1/webhooks", "", body)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	service.AssertExpectations(t)
+}
+
+func TestWebhookController_PostWebhook_Validation(t *testing.T) {
+	service := new(MockWebhookService)
+	router := setupWebhookRouter(service)
+
+	for body, field := range map[string]string{
+		`{"url":"ftp://example.com/hook","events":["message.created"]}`: `"url":{"code":"httpurl"`,
+		`{"url":"https://example.com/hook","events":[]}`:                `"events":{"code":"too_small"`,
+		`{"url":"https://example.com/hook","events":["member.joined"]}`: `"events[0]":{"code":"oneof"`,
+	} {
+		w := webhookRequest(router, "POST", "/spaces/1/webhooks", "alice-token", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, body)
+		assert.Contains(t, w.Body.String(), field, body)
+	}
+
+	service.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebhookController_DeleteAndDeliveries(t *testing.T) {
+	service := new(MockWebhookService)
+	router := setupWebhookRouter(service)
+
+	status := 500
+	service.On("DeleteWebhook", "alice", 1, 3).Return(nil).Once()
+	service.On("DeleteWebhook", "alice", 1, 9).Return(services.ErrWebhookNotFound).Once()
+	service.On("ListDeliveries", "alice", 1, 3).Return([]models.WebhookDelivery{{
+		ID: 10, WebhookID: 3, Event: models.EventMessageCreated, Status: models.DeliveryPending, Attempts: 1,
+		NextAttemptAt: time.Now(), AttemptLog: []models.WebhookAttempt{{Attempt: 1, StatusCode: &status, DurationMs: 12}},
+	}}, nil).Once()
+
+	w := webhookRequest(router, "GET", "/spaces/1/webhooks/3/deliveries", "alice-token", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"attempt_log":[{"id":0,"attempt":1,"status_code":500`)
+
+	w = webhookRequest(router, "DELETE", "/spaces/1/webhooks/3", "alice-token", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = webhookRequest(router, "DELETE", "/spaces/1/webhooks/9", "alice-token", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"webhook_not_found","error":"Webhook が見つかりません"}`, w.Body.String())
+
+	service.AssertExpectations(t)
+}
@@ -1,59 +1,240 @@
 package controllers
 
 import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/dto"
+	"chat/i18n"
+	"chat/logging"
+	"chat/middlewares"
 	"chat/models"
+	"chat/origins"
+	"chat/ratelimit"
 	"chat/services"
-	"log"
+	"chat/tracing"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 type WebSocketController struct {
-	Service  services.WebSocketService
-	Upgrader websocket.Upgrader
+	Service   services.WebSocketService
+	Spaces    services.SpaceService
+	Messages  services.MessageService
+	Commands  services.CommandService
+	Upgrader  websocket.Upgrader
+	Config    config.WebSocketConfig
+	RateLimit config.RateLimitConfig
+	Origins   *origins.Matcher
+	Logger    *slog.Logger
 }
 
-func NewWebSocketController(service services.WebSocketService) *WebSocketController {
-	return &WebSocketController{
-		Service: service,
-		Upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		},
+func NewWebSocketController(service services.WebSocketService, spaces services.SpaceService, messages services.MessageService, commands services.CommandService, cfg config.WebSocketConfig, rateLimit config.RateLimitConfig, allowOrigins *origins.Matcher, logger *slog.Logger) *WebSocketController {
+	c := &WebSocketController{
+		Service:   service,
+		Spaces:    spaces,
+		Messages:  messages,
+		Commands:  commands,
+		Config:    cfg,
+		RateLimit: rateLimit,
+		Origins:   allowOrigins,
+		Logger:    logger,
 	}
+	// トークンをサブプロトコルで送るブラウザには bearer を返す（トークン自体は返さない）
+	c.Upgrader = websocket.Upgrader{CheckOrigin: c.checkOrigin, Subprotocols: []string{middlewares.WebSocketTokenProtocol}}
+	return c
+}
+
+// CORS と同じオリジン一覧で接続元サイトを確認する
+// ブラウザは WebSocket に CORS を適用しないため、ここで拒否しないと任意のサイトから
+// ログイン中のユーザーとして接続できてしまう。Origin を送らないブラウザ以外のクライアントは許可する。
+func (c *WebSocketController) checkOrigin(r *http.Request) bool {
+	err := c.Origins.Check(r.Header.Get("Origin"))
+	if err == nil || errors.Is(err, origins.ErrMissing) {
+		return true
+	}
+	c.Logger.WarnContext(r.Context(), "WebSocket接続を拒否しました", "remote_addr", r.RemoteAddr, "reason", err)
+	return false
 }
 
 // **WebSocket接続を処理**
 func (c *WebSocketController) HandleConnections(ctx *gin.Context) {
+	// 表示中のスペース（フロントエンドは ?spaceId= を付けて接続する）
+	// メトリクスのラベルになるため、存在しないスペースはアップグレードする前に拒否する
+	var query dto.WebSocketQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+	if query.SpaceID > 0 {
+		if _, err := c.Spaces.GetSpace(ctx.Request.Context(), query.SpaceID); err != nil {
+			abortWithError(ctx, err, errSpaceFetchFailed)
+			return
+		}
+	}
+	spaceID := query.SpaceID
+
 	ws, err := c.Upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
 	if err != nil {
-		log.Println("WebSocket接続エラー:", err)
+		c.Logger.WarnContext(ctx.Request.Context(), "WebSocket接続エラー", "error", err)
 		return
 	}
 	defer ws.Close()
 
-	c.Service.AddClient(ws)
+	// 接続中のログにはすべて接続 ID を付ける
+	// Upgrade 後は切断してもリクエストのコンテキストが終了しないため、ループを抜けたら自分で終了する
+	connID := logging.NewID()
+	connCtx, cancel := context.WithCancel(logging.WithAttrs(ctx.Request.Context(), slog.String("conn_id", connID)))
+	defer cancel()
+	c.Logger.InfoContext(connCtx, "WebSocket接続", "client_ip", ctx.ClientIP(), "space_id", spaceID)
+
+	// フレームサイズと pong 待ち時間の制限
+	ws.SetReadLimit(c.Config.MaxMessageSize)
+	ws.SetReadDeadline(time.Now().Add(c.Config.PongTimeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(c.Config.PongTimeout))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.keepAlive(ws, done)
+
+	// コマンドは接続時に認証したユーザーの権限で実行する
+	client := services.Client{ConnID: connID, SpaceID: spaceID, Username: middlewares.Username(ctx), Language: i18n.Language(ctx)}
+	c.Service.AddClient(ws, client)
+
+	// 接続ごとの受信レート制限
+	var limiter *rate.Limiter
+	if c.RateLimit.Enabled {
+		limiter = ratelimit.NewBucket(c.RateLimit.WebSocket)
+	}
+	violations := 0
 
 	for {
 		var msg models.Message
 		err := ws.ReadJSON(&msg)
 		if err != nil {
+			c.Logger.InfoContext(connCtx, "WebSocket切断", "reason", err)
 			c.Service.RemoveClient(ws)
 			break
 		}
 
-		if msg.Username == "" {
-			msg.Username = "匿名ユーザー"
+		// 超過したメッセージは破棄し、連続して超過し続ける接続は切断する
+		if limiter != nil && !limiter.Allow() {
+			violations++
+			if violations > c.RateLimit.WebSocket.Requests {
+				c.Logger.WarnContext(connCtx, "WebSocketレート制限超過のため切断", "client_ip", ctx.ClientIP())
+				closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded")
+				ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(c.Config.WriteTimeout))
+				c.Service.DropClient(ws, "rate_limited")
+				break
+			}
+			continue
 		}
+		violations = 0
 
-		err = c.Service.SaveMessage(msg)
-		if err != nil {
-			log.Println("メッセージ保存エラー:", err)
-		}
+		c.handleMessage(connCtx, ws, client, msg)
+	}
+}
+
+// 受信したメッセージを保存する（受信→保存→配信を1つのトレースにする）
+// 接続は長時間続くため、接続のリクエストではなくメッセージごとに新しいトレースを始める。
+// "/" で始まるメッセージはコマンドとして実行し、保存しない。
+func (c *WebSocketController) handleMessage(ctx context.Context, ws *websocket.Conn, client services.Client, msg models.Message) {
+	ctx, span := tracing.Start(trace.ContextWithSpanContext(ctx, trace.SpanContext{}), "WebSocket.receive",
+		attribute.String("ws.conn_id", client.ConnID),
+		attribute.Int("space_id", msg.SpaceID),
+	)
+	defer span.End()
 
-		c.Service.BroadcastMessage(msg)
+	// DB が詰まっても受信ループが止まり続けないよう、保存に上限を設ける
+	ctx, cancel := context.WithTimeout(ctx, c.Config.MessageTimeout)
+	defer cancel()
+
+	// 投稿は接続時に認証したユーザーとして行い、送られてきた username は使わない
+	if client.Username == "" {
+		c.sendError(ctx, ws, client, errAuthRequired)
+		return
+	}
+	msg.Username = client.Username
+
+	if call, ok := services.ParseCommand(msg, client.Username); ok {
+		c.handleCommand(ctx, ws, client, call)
+		return
+	}
+
+	// REST と同じ検証を通して保存する。配信は MessageService がハブに送るため、ここでは行わない。
+	// 保存できなかったメッセージは配信せず、送信者にだけエラーを返す。
+	if _, err := c.Messages.CreateMessage(services.WithMessageSource(ctx, "websocket"), msg); err != nil {
+		c.sendError(ctx, ws, client, err)
+	}
+}
+
+// コマンドを実行する。公開の応答はメッセージとしてハブから配信されるため、
+// この接続には自分だけに見える応答とエラーだけを送る。
+func (c *WebSocketController) handleCommand(ctx context.Context, ws *websocket.Conn, client services.Client, call services.CommandCall) {
+	call.Language = client.Language
+	res, err := c.Commands.Execute(ctx, call)
+	if err != nil {
+		res = services.CommandResponse{Command: call.Name, ResponseType: services.ResponseEphemeral, Text: c.errorText(ctx, client, err)}
+	}
+	if res.ResponseType != services.ResponseEphemeral {
+		return
+	}
+
+	if err := c.Service.Send(ws, res); err != nil {
+		c.Logger.WarnContext(ctx, "コマンドの応答を送信できませんでした", "command", call.Name, "error", err)
+	}
+}
+
+// エラーをこの接続にだけ送る（REST のエラーレスポンスと同じ code・error に type を付ける）
+func (c *WebSocketController) sendError(ctx context.Context, ws *websocket.Conn, client services.Client, err error) {
+	code := "internal_error"
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		code = appErr.Code
+	}
+	frame := gin.H{"type": "error", "code": code, "error": c.errorText(ctx, client, err)}
+	if err := c.Service.Send(ws, frame); err != nil {
+		c.Logger.WarnContext(ctx, "エラーを送信できませんでした", "error", err)
+	}
+}
+
+// エラーを接続の言語の文言にする
+// エラーレスポンスと同じく、辞書にないコードはエラー自身のメッセージを使い、想定外のエラーはログに残す。
+func (c *WebSocketController) errorText(ctx context.Context, client services.Client, err error) string {
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		c.Logger.ErrorContext(ctx, "WebSocketメッセージの処理エラー", "space_id", client.SpaceID, "error", err)
+		return i18n.T(client.Language, "internal_error")
+	}
+	if i18n.Has(appErr.Code) {
+		return i18n.T(client.Language, appErr.Code)
+	}
+	return appErr.Message
+}
+
+// 接続が閉じられるまで定期的に ping を送る
+func (c *WebSocketController) keepAlive(ws *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(c.Config.PingInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(c.Config.WriteTimeout)
+			if err := ws.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
 	}
 }
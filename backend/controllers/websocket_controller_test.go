@@ -1,12 +1,28 @@
 package controllers
 
 import (
+	"chat/config"
+	"chat/logging"
+	"chat/middlewares"
 	"chat/models"
+	"chat/origins"
+	"chat/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Mock WebSocket Service
@@ -14,21 +30,44 @@ type MockWebSocketService struct {
 	mock.Mock
 }
 
-func (m *MockWebSocketService) AddClient(conn *websocket.Conn) {
-	m.Called(conn)
+func (m *MockWebSocketService) AddClient(conn *websocket.Conn, client services.Client) {
+	m.Called(conn, client)
+}
+
+func (m *MockWebSocketService) DropClient(conn *websocket.Conn, reason string) {
+	m.Called(conn, reason)
 }
 
 func (m *MockWebSocketService) RemoveClient(conn *websocket.Conn) {
 	m.Called(conn)
 }
 
-func (m *MockWebSocketService) SaveMessage(msg models.Message) error {
+func (m *MockWebSocketService) BroadcastMessage(ctx context.Context, msg models.Message) {
+	m.Called(msg)
+}
+
+func (m *MockWebSocketService) Send(conn *websocket.Conn, v any) error {
+	args := m.Called(conn, v)
+	return args.Error(0)
+}
+
+func (m *MockWebSocketService) Publish(ctx context.Context, msg models.Message) error {
 	args := m.Called(msg)
 	return args.Error(0)
 }
 
-func (m *MockWebSocketService) BroadcastMessage(msg models.Message) {
-	m.Called(msg)
+func (m *MockWebSocketService) PublishEvent(ctx context.Context, event services.HubEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockWebSocketService) BroadcastEvent(ctx context.Context, event services.HubEvent) {
+	m.Called(event)
+}
+
+func (m *MockWebSocketService) Subscribe(spaceID int) (<-chan models.Message, func()) {
+	args := m.Called(spaceID)
+	return args.Get(0).(<-chan models.Message), args.Get(1).(func())
 }
 
 func (m *MockWebSocketService) GetClients() map[*websocket.Conn]bool {
@@ -40,11 +79,277 @@ func (m *MockWebSocketService) HandleMessages() {
 	m.Called()
 }
 
+func (m *MockWebSocketService) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockWebSocketService) Shutdown(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 // NewWebSocketController のユニットテスト
 func TestNewWebSocketController(t *testing.T) {
 	mockService := new(MockWebSocketService)
-	controller := NewWebSocketController(mockService)
+	controller := NewWebSocketController(mockService, nil, nil, nil, config.Default().WebSocket, config.Default().RateLimit, origins.MustNew(config.Default().CORS.AllowOrigins), logging.Discard())
 
 	assert.NotNil(t, controller, "WebSocketController の生成に失敗")
 	assert.NotNil(t, controller.Service, "Service が nil")
 }
+
+func TestWebSocketController_CheckOrigin(t *testing.T) {
+	controller := NewWebSocketController(new(MockWebSocketService), nil, nil, nil, config.Default().WebSocket, config.Default().RateLimit,
+		origins.MustNew([]string{"http://localhost:3000", "https://*.echo-talk.com"}), logging.Discard())
+
+	cases := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:3000", true},
+		{"https://www.echo-talk.com", true},
+		{"https://evil.example.com", false},
+		{"null", false},
+		// ブラウザ以外のクライアント
+		{"", true},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/api/ws", nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		assert.Equal(t, c.want, controller.Upgrader.CheckOrigin(req), c.origin)
+	}
+}
+
+// 登録済みのスペースだけを返す SpaceService
+type stubSpaceService struct {
+	services.SpaceService
+	spaces map[int]models.Space
+}
+
+func (s *stubSpaceService) GetSpace(ctx context.Context, id int) (models.Space, error) {
+	space, ok := s.spaces[id]
+	if !ok {
+		return models.Space{}, services.ErrSpaceNotFound
+	}
+	return space, nil
+}
+
+// 不正な spaceId・存在しないスペースはアップグレードせずにエラーを返す（メトリクスのラベルを増やさない）
+func TestWebSocketController_HandleConnections_SpaceID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := new(MockWebSocketService)
+	spaces := &stubSpaceService{spaces: map[int]models.Space{1: {ID: 1, Name: "general"}}}
+	controller := NewWebSocketController(service, spaces, nil, nil, config.Default().WebSocket, config.Default().RateLimit, origins.MustNew(config.Default().CORS.AllowOrigins), logging.Discard())
+
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.GET("/ws", controller.HandleConnections)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	cases := []struct {
+		query string
+		want  int
+	}{
+		{"?spaceId=99", http.StatusNotFound},
+		{"?spaceId=abc", http.StatusBadRequest},
+		{"?spaceId=-1", http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+c.query, nil)
+		require.Error(t, err, c.query)
+		assert.Equal(t, c.want, resp.StatusCode, c.query)
+	}
+	service.AssertNotCalled(t, "AddClient", mock.Anything, mock.Anything)
+
+	// 存在するスペースと、スペースを指定しない接続はアップグレードする
+	added := make(chan services.Client, 2)
+	service.On("AddClient", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		added <- args.Get(1).(services.Client)
+	})
+	service.On("RemoveClient", mock.Anything).Maybe()
+	for _, c := range []struct {
+		query   string
+		spaceID int
+	}{{"?spaceId=1", 1}, {"", 0}} {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+c.query, nil)
+		require.NoError(t, err, c.query)
+		select {
+		case client := <-added:
+			assert.Equal(t, c.spaceID, client.SpaceID, c.query)
+		case <-time.After(time.Second):
+			t.Fatal("クライアントが登録されませんでした")
+		}
+		conn.Close()
+	}
+}
+
+// 保存したメッセージと受け取ったコンテキストのスパンを記録する MessageService
+type stubMessageService struct {
+	services.MessageService
+	err   error
+	saved []models.Message
+	span  trace.SpanContext
+}
+
+func (s *stubMessageService) CreateMessage(ctx context.Context, msg models.Message) (models.Message, error) {
+	s.span = trace.SpanContextFromContext(ctx)
+	if s.err != nil {
+		return models.Message{}, s.err
+	}
+	s.saved = append(s.saved, msg)
+	msg.ID = len(s.saved)
+	return msg, nil
+}
+
+func TestWebSocketController_HandleMessageTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	messages := &stubMessageService{}
+	controller := NewWebSocketController(new(MockWebSocketService), nil, messages, nil, config.Default().WebSocket, config.Default().RateLimit, origins.MustNew(config.Default().CORS.AllowOrigins), logging.Discard())
+
+	// 接続のリクエストにスパンがあっても、メッセージは別のトレースにする
+	connCtx, connSpan := otel.Tracer("test").Start(context.Background(), "connect")
+	controller.handleMessage(connCtx, nil, services.Client{ConnID: "conn-1", Username: "alice"}, models.Message{SpaceID: 1, Text: "hi"})
+	connSpan.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	receive := spans[0]
+	assert.Equal(t, "WebSocket.receive", receive.Name())
+	assert.NotEqual(t, connSpan.SpanContext().TraceID(), receive.SpanContext().TraceID())
+
+	// 保存（とハブへの配信）には受信スパンのコンテキストが渡る
+	assert.Equal(t, receive.SpanContext(), messages.span)
+}
+
+// メッセージは接続時に認証したユーザーとして保存し、配信は MessageService に任せる
+func TestWebSocketController_HandleMessage(t *testing.T) {
+	service := new(MockWebSocketService)
+	messages := &stubMessageService{}
+	controller := NewWebSocketController(service, nil, messages, nil, config.Default().WebSocket, config.Default().RateLimit, origins.MustNew(config.Default().CORS.AllowOrigins), logging.Discard())
+
+	client := services.Client{ConnID: "conn-1", SpaceID: 1, Username: "alice", Language: "en"}
+	controller.handleMessage(context.Background(), nil, client, models.Message{SpaceID: 1, Username: "mallory", Text: "hi"})
+	assert.Equal(t, []models.Message{{SpaceID: 1, Username: "alice", Text: "hi"}}, messages.saved)
+
+	// 保存できなければ配信せず、送信者にだけエラーを返す
+	messages.err = services.ErrSpaceNotFound
+	service.On("Send", (*websocket.Conn)(nil), gin.H{"type": "error", "code": "space_not_found", "error": "Space not found"}).Return(nil).Once()
+	controller.handleMessage(context.Background(), nil, client, models.Message{SpaceID: 2, Text: "hi"})
+
+	// 未ログインの接続からは投稿できない
+	service.On("Send", (*websocket.Conn)(nil), gin.H{"type": "error", "code": "auth_required", "error": "Login required"}).Return(nil).Once()
+	controller.handleMessage(context.Background(), nil, services.Client{ConnID: "conn-2", Language: "en"}, models.Message{SpaceID: 1, Username: "alice", Text: "hi"})
+
+	assert.Len(t, messages.saved, 1)
+	service.AssertExpectations(t)
+	service.AssertNotCalled(t, "BroadcastMessage", mock.Anything)
+}
+
+// コマンドの応答を返すだけの CommandService
+type stubCommandService struct {
+	services.CommandService
+	res services.CommandResponse
+	err error
+	got services.CommandCall
+}
+
+func (s *stubCommandService) Execute(ctx context.Context, call services.CommandCall) (services.CommandResponse, error) {
+	s.got = call
+	return s.res, s.err
+}
+
+// "/" で始まるメッセージは保存・配信せず、自分だけの応答をこの接続にだけ送る
+func TestWebSocketController_HandleCommand(t *testing.T) {
+	service := new(MockWebSocketService)
+	commands := &stubCommandService{res: services.CommandResponse{Command: "help", ResponseType: services.ResponseEphemeral, Text: "/help"}}
+	messages := &stubMessageService{}
+	controller := NewWebSocketController(service, nil, messages, commands, config.Default().WebSocket, config.Default().RateLimit, origins.MustNew(config.Default().CORS.AllowOrigins), logging.Discard())
+
+	service.On("Send", (*websocket.Conn)(nil), commands.res).Return(nil).Once()
+	client := services.Client{ConnID: "conn-1", Username: "alice", Language: "en"}
+	controller.handleMessage(context.Background(), nil, client, models.Message{SpaceID: 1, Username: "alice", Text: "/help"})
+
+	// 自分だけに見える応答は接続の言語で返す
+	assert.Equal(t, services.CommandCall{Name: "help", SpaceID: 1, Actor: "alice", Username: "alice", Language: "en"}, commands.got)
+	service.AssertExpectations(t)
+	assert.Empty(t, messages.saved)
+	service.AssertNotCalled(t, "BroadcastMessage", mock.Anything)
+
+	// 公開の応答はハブから配信されるため、この接続には送らない
+	commands.res = services.CommandResponse{Command: "me", ResponseType: services.ResponsePublic}
+	controller.handleMessage(context.Background(), nil, client, models.Message{SpaceID: 1, Username: "alice", Text: "/me waves"})
+	service.AssertNumberOfCalls(t, "Send", 1)
+
+	// エラーは応答の言語で送る
+	commands.err = services.ErrForbidden
+	service.On("Send", (*websocket.Conn)(nil), services.CommandResponse{Command: "topic", ResponseType: services.ResponseEphemeral, Text: "You are not allowed to perform this action"}).Return(nil).Once()
+	controller.handleMessage(context.Background(), nil, client, models.Message{SpaceID: 1, Username: "alice", Text: "/topic hi"})
+	service.AssertExpectations(t)
+}
+
+// "token-<name>" を <name> として認証する TokenVerifier
+type stubTokenVerifier struct{}
+
+func (stubTokenVerifier) VerifyToken(token string) (string, error) {
+	if name, ok := strings.CutPrefix(token, "token-"); ok {
+		return name, nil
+	}
+	return "", services.ErrInvalidToken
+}
+
+// ブラウザはヘッダーを付けられないため、トークンを Sec-WebSocket-Protocol で送る
+func TestWebSocketController_HandleConnections_SubprotocolToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := new(MockWebSocketService)
+	controller := NewWebSocketController(service, nil, nil, nil, config.Default().WebSocket, config.Default().RateLimit, origins.MustNew(config.Default().CORS.AllowOrigins), logging.Discard())
+
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.Use(middlewares.Authenticate(stubTokenVerifier{}))
+	router.GET("/ws", controller.HandleConnections)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	added := make(chan services.Client, 3)
+	service.On("AddClient", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		added <- args.Get(1).(services.Client)
+	})
+	service.On("RemoveClient", mock.Anything).Maybe()
+
+	cases := []struct {
+		name      string
+		protocols []string
+		header    http.Header
+		want      string
+	}{
+		{"サブプロトコル", []string{"bearer", "token-alice"}, nil, "alice"},
+		{"Authorization ヘッダー", nil, http.Header{"Authorization": {"Bearer token-bob"}}, "bob"},
+		// 不正なトークンは未認証の接続（閲覧のみ）になる
+		{"不正なトークン", []string{"bearer", "expired"}, nil, ""},
+	}
+	for _, c := range cases {
+		dialer := websocket.Dialer{Subprotocols: c.protocols}
+		conn, resp, err := dialer.Dial(wsURL, c.header)
+		require.NoError(t, err, c.name)
+		// トークンを応答ヘッダーに返さない
+		if c.protocols != nil {
+			assert.Equal(t, "bearer", resp.Header.Get("Sec-WebSocket-Protocol"), c.name)
+		}
+		select {
+		case client := <-added:
+			assert.Equal(t, c.want, client.Username, c.name)
+		case <-time.After(time.Second):
+			t.Fatal("クライアントが登録されませんでした")
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,152 @@
+package dto
+
+import (
+	"chat/models"
+	"time"
+)
+
+// ユーザー登録リクエスト
+type RegisterUserRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=32,username"`
+	Password string `json:"password" binding:"required,min=8,max=72,password"`
+}
+
+func (r RegisterUserRequest) ToModel() models.User {
+	return models.User{Username: r.Username, Password: r.Password}
+}
+
+// ログインリクエスト（既存ユーザーのためパスワードポリシーは適用しない）
+type LoginRequest struct {
+	Username string `json:"username" binding:"required,max=32"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+func (r LoginRequest) ToModel() models.User {
+	return models.User{Username: r.Username, Password: r.Password}
+}
+
+// メッセージ作成リクエスト
+type CreateMessageRequest struct {
+	SpaceID  int    `json:"space_id" binding:"required,min=1"`
+	Username string `json:"username" binding:"required,max=32"`
+	Text     string `json:"text" binding:"required,max=2000,notblank"`
+}
+
+func (r CreateMessageRequest) ToModel() models.Message {
+	return models.Message{SpaceID: r.SpaceID, Username: r.Username, Text: r.Text}
+}
+
+// スペース作成リクエスト
+type CreateSpaceRequest struct {
+	Name string `json:"name" binding:"required,max=100,notblank,nocontrol"`
+}
+
+// メッセージ一覧取得のクエリ
+type GetMessagesQuery struct {
+	SpaceID int `form:"spaceId" binding:"required,min=1"`
+}
+
+// WebSocket 接続のクエリ（表示中のスペース。省略可）
+type WebSocketQuery struct {
+	SpaceID int `form:"spaceId" binding:"omitempty,min=1"`
+}
+
+// メッセージ削除のクエリ
+type DeleteMessageQuery struct {
+	ID      int `form:"id" binding:"required,min=1"`
+	SpaceID int `form:"spaceId" binding:"required,min=1"`
+}
+
+// v2 のスペースのパス（/spaces/:spaceId）
+type SpacePath struct {
+	SpaceID int `uri:"spaceId" binding:"required,min=1"`
+}
+
+// v2 のメッセージのパス（/spaces/:spaceId/messages/:messageId）
+type MessagePath struct {
+	SpaceID   int `uri:"spaceId" binding:"required,min=1"`
+	MessageID int `uri:"messageId" binding:"required,min=1"`
+}
+
+// v2 のメッセージ投稿リクエスト（スペースはパスで指定する）
+type PostMessageRequest struct {
+	Username string `json:"username" binding:"required,max=32"`
+	Text     string `json:"text" binding:"required,max=2000,notblank"`
+}
+
+func (r PostMessageRequest) ToModel(spaceID int) models.Message {
+	return models.Message{SpaceID: spaceID, Username: r.Username, Text: r.Text}
+}
+
+// v2 の Webhook のパス（/spaces/:spaceId/webhooks/:webhookId）
+type WebhookPath struct {
+	SpaceID   int `uri:"spaceId" binding:"required,min=1"`
+	WebhookID int `uri:"webhookId" binding:"required,min=1"`
+}
+
+// Webhook 登録リクエスト（署名の鍵はサーバーで生成する）
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=2048,httpurl"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=message.created message.deleted"`
+}
+
+// v2 の受信用 Webhook のパス（/spaces/:spaceId/incoming-webhooks/:hookId）
+type IncomingWebhookPath struct {
+	SpaceID int `uri:"spaceId" binding:"required,min=1"`
+	HookID  int `uri:"hookId" binding:"required,min=1"`
+}
+
+// 受信用 Webhook 作成リクエスト（name は投稿するメッセージの表示名）
+type CreateIncomingWebhookRequest struct {
+	Name string `json:"name" binding:"required,max=32,notblank,nocontrol"`
+}
+
+// 受信用 Webhook への投稿のパス（/hooks/:hookId）
+type HookPath struct {
+	HookID int `uri:"hookId" binding:"required,min=1"`
+}
+
+// 受信用 Webhook への投稿（username を省略すると Webhook の名前で投稿する）
+type HookMessageRequest struct {
+	Username string `json:"username" binding:"max=32,nocontrol"`
+	Text     string `json:"text" binding:"required,max=2000,notblank"`
+}
+
+// ボットのパス（/bots/:botId）
+type BotPath struct {
+	BotID int `uri:"botId" binding:"required,min=1"`
+}
+
+// ボット登録リクエスト（command は "/" を除いた名前。署名の鍵はサーバーで生成する）
+type CreateBotRequest struct {
+	Name        string `json:"name" binding:"required,max=32,notblank,nocontrol"`
+	Command     string `json:"command" binding:"required,max=32,command"`
+	CallbackURL string `json:"callback_url" binding:"required,max=2048,httpurl"`
+}
+
+func (r CreateBotRequest) ToModel() models.Bot {
+	return models.Bot{Name: r.Name, Command: r.Command, CallbackURL: r.CallbackURL}
+}
+
+// v2 の予約のパス（/spaces/:spaceId/scheduled-messages/:scheduledId）
+type ScheduledMessagePath struct {
+	SpaceID     int `uri:"spaceId" binding:"required,min=1"`
+	ScheduledID int `uri:"scheduledId" binding:"required,min=1"`
+}
+
+// 予約投稿リクエスト（kind を省略すると通常のメッセージとして自分の名前で投稿する）
+type CreateScheduledMessageRequest struct {
+	Text   string    `json:"text" binding:"required,max=2000,notblank"`
+	SendAt time.Time `json:"send_at" binding:"required"`
+	Kind   string    `json:"kind" binding:"omitempty,oneof=message reminder"`
+}
+
+func (r CreateScheduledMessageRequest) ToModel(spaceID int) models.ScheduledMessage {
+	return models.ScheduledMessage{SpaceID: spaceID, Text: r.Text, SendAt: r.SendAt, Kind: r.Kind}
+}
+
+// 保存したメッセージ一覧のページング（before は前のページの next_before。省略すると最新から）
+type ListBookmarksQuery struct {
+	Before int `form:"before" binding:"omitempty,min=1"`
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"chat/apperrors"
+	"errors"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	commandPattern  = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+)
+
+var registerOnce sync.Once
+
+// gin のバリデーターに独自ルールを登録する（複数回呼んでもよい）
+//
+//	username  英数字と _ . - のみ
+//	password  英字と数字をそれぞれ1文字以上含む
+//	notblank  空白だけの文字列は不可
+//	nocontrol 制御文字を含まない
+//	httpurl   http・https の絶対 URL
+//	command   小文字の英字で始まる英小文字・数字と _ -（スラッシュコマンドの名前）
+func RegisterValidators() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		// エラーの項目名を JSON のキー（クエリはフォーム名、パスはパラメーター名）にする
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+				if name != "" && name != "-" {
+					return name
+				}
+			}
+			return f.Name
+		})
+
+		v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+			return usernamePattern.MatchString(fl.Field().String())
+		})
+		v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+			s := fl.Field().String()
+			return strings.IndexFunc(s, unicode.IsLetter) >= 0 && strings.IndexFunc(s, unicode.IsDigit) >= 0
+		})
+		v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+			return strings.TrimSpace(fl.Field().String()) != ""
+		})
+		v.RegisterValidation("nocontrol", func(fl validator.FieldLevel) bool {
+			return strings.IndexFunc(fl.Field().String(), unicode.IsControl) < 0
+		})
+		v.RegisterValidation("httpurl", func(fl validator.FieldLevel) bool {
+			u, err := url.Parse(fl.Field().String())
+			return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+		})
+		v.RegisterValidation("command", func(fl validator.FieldLevel) bool {
+			return commandPattern.MatchString(fl.Field().String())
+		})
+	})
+}
+
+// validator のエラーを項目ごとのエラーに変換する。バリデーションエラーでなければ ok=false
+func FieldErrors(err error) (fields []apperrors.FieldError, ok bool) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil, false
+	}
+
+	for _, fe := range verrs {
+		fields = append(fields, apperrors.FieldError{
+			Field: fe.Field(),
+			Code:  fieldCode(fe),
+			Param: fe.Param(),
+		})
+	}
+	return fields, true
+}
+
+// ルール名を API が返す理由コードに変換する
+func fieldCode(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "min":
+		if isString {
+			return "too_short"
+		}
+		return "too_small"
+	case "max":
+		if isString {
+			return "too_long"
+		}
+		return "too_large"
+	default:
+		return fe.Tag()
+	}
+}
@@ -0,0 +1,47 @@
+package health
+
+import (
+	"chat/migrations"
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DB のコネクションプールから接続を取り出して ping する
+func Database(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// 未適用のマイグレーションがないか確認する（古いスキーマのまま新しいコードを動かさない）
+func Migrations(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		migrator, err := migrations.New(sqlDB)
+		if err != nil {
+			return err
+		}
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			names := make([]string, len(pending))
+			for i, m := range pending {
+				names[i] = fmt.Sprintf("%04d_%s", m.Version, m.Name)
+			}
+			return fmt.Errorf("未適用のマイグレーションがあります: %s", strings.Join(names, ", "))
+		}
+		return nil
+	}
+}
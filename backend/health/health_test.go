@@ -0,0 +1,35 @@
+package health_test
+
+import (
+	"chat/health"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Run(t *testing.T) {
+	checker := health.NewChecker(50 * time.Millisecond)
+	checker.Register("database", func(ctx context.Context) error { return nil })
+
+	report := checker.Run(context.Background())
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+
+	checker.Register("migrations", func(ctx context.Context) error { return errors.New("2 pending") })
+	// 制限時間を超えた確認は失敗扱い
+	checker.Register("websocket_hub", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report = checker.Run(context.Background())
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, health.Result{Status: health.StatusUnavailable, Error: "2 pending"},
+		health.Result{Status: report.Checks["migrations"].Status, Error: report.Checks["migrations"].Error})
+	assert.Equal(t, "context deadline exceeded", report.Checks["websocket_hub"].Error)
+	assert.GreaterOrEqual(t, report.Checks["websocket_hub"].DurationMs, 50.0)
+}
@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// 対応言語
+const (
+	Japanese = "ja"
+	English  = "en"
+)
+
+// 言語が決まらない場合に使う言語
+const DefaultLanguage = Japanese
+
+// gin.Context に言語を保存するキー
+const contextKey = "i18n.lang"
+
+// 先頭がデフォルト（一致しない場合は Matcher が先頭を返す）
+var supported = []language.Tag{language.Japanese, language.English}
+
+var matcher = language.NewMatcher(supported)
+
+// 対応している言語コードか
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Accept-Language ヘッダーから最も適切な対応言語を選ぶ
+func Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLanguage
+	}
+	base, _ := supported[index].Base()
+	return base.String()
+}
+
+// key に対応する文言を返す。見つからない場合はデフォルト言語、それもなければ key を返す
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// key が辞書に登録されているか
+func Has(key string) bool {
+	_, ok := catalogs[DefaultLanguage][key]
+	return ok
+}
+
+// リクエストに言語を設定する
+func SetLanguage(ctx *gin.Context, lang string) {
+	ctx.Set(contextKey, strings.ToLower(lang))
+}
+
+// リクエストの言語（未設定ならデフォルト言語）
+func Language(ctx *gin.Context) string {
+	if lang := ctx.GetString(contextKey); lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// リクエストの言語で key を翻訳する
+func Translate(ctx *gin.Context, key string, args ...any) string {
+	return T(Language(ctx), key, args...)
+}
@@ -0,0 +1,36 @@
+package i18n_test
+
+import (
+	"chat/i18n"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"", i18n.Japanese},
+		{"en", i18n.English},
+		{"en-US,en;q=0.9", i18n.English},
+		{"ja-JP", i18n.Japanese},
+		{"fr-FR,en;q=0.8,ja;q=0.5", i18n.English},
+		{"ja;q=0.3,en;q=0.9", i18n.English},
+		{"de", i18n.Japanese},
+		{"not a valid header;;;", i18n.Japanese},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, i18n.Match(c.header), "Accept-Language: %q", c.header)
+	}
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Space not found", i18n.T(i18n.English, "space_not_found"))
+	assert.Equal(t, "スペースが見つかりません", i18n.T(i18n.Japanese, "space_not_found"))
+
+	// 未対応の言語はデフォルト言語、未登録のキーはキーそのもの
+	assert.Equal(t, "スペースが見つかりません", i18n.T("de", "space_not_found"))
+	assert.Equal(t, "unknown_key", i18n.T(i18n.English, "unknown_key"))
+}
@@ -0,0 +1,198 @@
+package i18n
+
+// 言語ごとのメッセージ辞書。キーは API が返すコード（error の "code"）と共通。
+var catalogs = map[string]map[string]string{
+	Japanese: {
+		// 成功メッセージ
+		"user_registered":  "ユーザー登録成功",
+		"space_created":    "スペースが作成されました",
+		"message_deleted":  "メッセージ削除成功",
+		"account_unlocked": "アカウントのロックを解除しました",
+
+		// リクエストの形式
+		"invalid_request":    "リクエストのパースに失敗しました",
+		"validation_failed":  "入力内容に誤りがあります",
+		"message_invalid":    "メッセージまたはユーザー名が空です",
+		"message_id_invalid": "メッセージIDまたはスペースIDが無効です",
+
+		// ドメインエラー
+		"message_not_found":   "メッセージが見つかりませんでした",
+		"space_not_found":     "スペースが見つかりません",
+		"user_not_found":      "ユーザーが見つかりません",
+		"username_taken":      "ユーザー名が既に使用されています",
+		"invalid_credentials": "認証失敗",
+		"invalid_token":       "トークンが無効です",
+		"auth_required":       "ログインが必要です",
+		"rate_limited":        "リクエストが多すぎます。しばらくしてから再試行してください",
+		"account_locked":      "ログイン失敗が続いたため一時的にロックされています",
+		"forbidden":           "この操作を行う権限がありません",
+		"webhook_not_found":   "Webhook が見つかりません",
+		"webhook_invalid":     "URL または通知するイベントが無効です",
+		"hook_token_invalid":  "Webhook のトークンが無効です",
+		"topic_invalid":       "トピックが長すぎます",
+		"bot_not_found":       "ボットが見つかりません",
+		"bot_invalid":         "コマンド名またはコールバック URL が無効です",
+		"bot_command_taken":   "コマンド名が既に使用されています",
+		"schedule_invalid":    "送信日時・種類・本文のいずれかが無効です",
+		"schedule_not_found":  "予約が見つかりません",
+		"bookmark_not_found":  "保存したメッセージが見つかりません",
+
+		// 入力項目ごとのエラー（%s はルールの引数）
+		"validation.required":  "必須項目です",
+		"validation.too_short": "%s文字以上で入力してください",
+		"validation.too_long":  "%s文字以内で入力してください",
+		"validation.too_small": "%s以上の値を指定してください",
+		"validation.too_large": "%s以下の値を指定してください",
+		"validation.username":  "英数字と _ . - のみ使用できます",
+		"validation.password":  "英字と数字をそれぞれ1文字以上含めてください",
+		"validation.notblank":  "空白以外の文字を入力してください",
+		"validation.nocontrol": "使用できない文字が含まれています",
+		"validation.httpurl":   "http または https の URL を入力してください",
+		"validation.oneof":     "%s のいずれかを指定してください",
+		"validation.command":   "英小文字で始まり、英小文字・数字と _ - のみ使用できます",
+		"validation.invalid":   "入力内容が正しくありません",
+
+		// スラッシュコマンドの応答（実行したユーザーにだけ返す）
+		"command.usage.help":       "/help — 使えるコマンドの一覧",
+		"command.usage.me":         "/me <動作> — 自分の動作として投稿する",
+		"command.usage.topic":      "/topic [トピック] — スペースのトピックを表示・変更する（所有者・管理者のみ）",
+		"command.usage.invite":     "/invite @<ユーザー> — ユーザーをスペースに招待する",
+		"command.usage.remind":     "/remind <時間> <内容> — 指定した時間の後にリマインドする（例: /remind 30m 会議、/remind 1d 請求書）",
+		"command.usage.bot":        "/%s — %s（ボット）",
+		"command.unknown":          "/%s というコマンドはありません（/help で一覧を表示します）",
+		"command.topic":            "トピック: %s",
+		"command.no_topic":         "トピックは設定されていません",
+		"command.user_not_found":   "ユーザー @%s が見つかりません",
+		"command.bot_no_response":  "%s から応答がありませんでした",
+		"command.remind_login":     "リマインダーを使うにはログインしてください",
+		"command.login_required":   "/%s を使うにはログインしてください",
+		"command.remind_scheduled": "%s後にリマインドします: %s",
+
+		// スラッシュコマンドがスペースに投稿するメッセージ（スペースの全員が読む）
+		"command.topic_changed": "トピックを「%s」に変更しました",
+		"command.invited":       "@%s をこのスペースに招待しました",
+
+		// 内部エラー
+		"internal_error":         "サーバー内部エラーが発生しました",
+		"message_fetch_failed":   "メッセージ取得失敗",
+		"message_save_failed":    "メッセージの保存に失敗しました",
+		"message_delete_failed":  "メッセージの削除に失敗しました",
+		"space_create_failed":    "スペースの作成に失敗しました",
+		"space_list_failed":      "スペース一覧の取得に失敗しました",
+		"space_fetch_failed":     "スペースの取得に失敗しました",
+		"register_failed":        "ユーザー登録に失敗しました",
+		"login_failed":           "ログイン処理に失敗しました",
+		"unlock_failed":          "ロックの解除に失敗しました",
+		"request_timeout":        "処理がタイムアウトしました。しばらくしてから再度お試しください",
+		"request_canceled":       "リクエストが中断されました",
+		"webhook_create_failed":  "Webhook の登録に失敗しました",
+		"webhook_list_failed":    "Webhook の取得に失敗しました",
+		"webhook_delete_failed":  "Webhook の削除に失敗しました",
+		"command_failed":         "コマンドの実行に失敗しました",
+		"bot_create_failed":      "ボットの登録に失敗しました",
+		"bot_list_failed":        "ボットの取得に失敗しました",
+		"bot_delete_failed":      "ボットの削除に失敗しました",
+		"schedule_failed":        "メッセージの予約に失敗しました",
+		"schedule_list_failed":   "予約の取得に失敗しました",
+		"schedule_cancel_failed": "予約の取り消しに失敗しました",
+		"pin_failed":             "ピン留めに失敗しました",
+		"unpin_failed":           "ピン留めの解除に失敗しました",
+		"bookmark_failed":        "メッセージの保存に失敗しました",
+		"bookmark_list_failed":   "保存したメッセージの取得に失敗しました",
+		"bookmark_delete_failed": "保存の取り消しに失敗しました",
+		"preview_failed":         "リンクのプレビューの取得に失敗しました",
+	},
+	English: {
+		"user_registered":  "User registered successfully",
+		"space_created":    "Space created",
+		"message_deleted":  "Message deleted",
+		"account_unlocked": "Account unlocked",
+
+		"invalid_request":    "Failed to parse the request",
+		"validation_failed":  "Some fields are invalid",
+		"message_invalid":    "Message text or username is empty",
+		"message_id_invalid": "Invalid message ID or space ID",
+
+		"message_not_found":   "Message not found",
+		"space_not_found":     "Space not found",
+		"user_not_found":      "User not found",
+		"username_taken":      "Username is already taken",
+		"invalid_credentials": "Authentication failed",
+		"invalid_token":       "Invalid token",
+		"auth_required":       "Login required",
+		"rate_limited":        "Too many requests. Please try again later",
+		"account_locked":      "Too many failed login attempts. Please try again later",
+		"forbidden":           "You are not allowed to perform this action",
+		"webhook_not_found":   "Webhook not found",
+		"webhook_invalid":     "Invalid URL or events",
+		"hook_token_invalid":  "Invalid webhook token",
+		"topic_invalid":       "The topic is too long",
+		"bot_not_found":       "Bot not found",
+		"bot_invalid":         "Invalid command name or callback URL",
+		"bot_command_taken":   "The command name is already in use",
+		"schedule_invalid":    "Invalid send time, kind or text",
+		"schedule_not_found":  "Scheduled message not found",
+		"bookmark_not_found":  "Saved message not found",
+
+		"validation.required":  "This field is required",
+		"validation.too_short": "Must be at least %s characters",
+		"validation.too_long":  "Must be at most %s characters",
+		"validation.too_small": "Must be %s or greater",
+		"validation.too_large": "Must be %s or less",
+		"validation.username":  "Only letters, digits, _ . - are allowed",
+		"validation.password":  "Must contain at least one letter and one digit",
+		"validation.notblank":  "Must not be blank",
+		"validation.nocontrol": "Contains characters that are not allowed",
+		"validation.httpurl":   "Must be an http or https URL",
+		"validation.oneof":     "Must be one of: %s",
+		"validation.command":   "Must start with a lowercase letter and contain only lowercase letters, digits, _ and -",
+		"validation.invalid":   "Invalid value",
+
+		"command.usage.help":       "/help — List available commands",
+		"command.usage.me":         "/me <action> — Post as your own action",
+		"command.usage.topic":      "/topic [topic] — Show or change the space topic (owner and admins only)",
+		"command.usage.invite":     "/invite @<user> — Invite a user to the space",
+		"command.usage.remind":     "/remind <time> <text> — Remind you after the given time (e.g. /remind 30m meeting, /remind 1d invoice)",
+		"command.usage.bot":        "/%s — %s (bot)",
+		"command.unknown":          "There is no command /%s (type /help to list commands)",
+		"command.topic":            "Topic: %s",
+		"command.no_topic":         "No topic is set",
+		"command.user_not_found":   "User @%s not found",
+		"command.bot_no_response":  "No response from %s",
+		"command.remind_login":     "Log in to use reminders",
+		"command.login_required":   "Log in to use /%s",
+		"command.remind_scheduled": "I will remind you in %s: %s",
+
+		"command.topic_changed": "Changed the topic to \"%s\"",
+		"command.invited":       "Invited @%s to this space",
+
+		"internal_error":         "An internal server error occurred",
+		"message_fetch_failed":   "Failed to fetch messages",
+		"message_save_failed":    "Failed to save the message",
+		"message_delete_failed":  "Failed to delete the message",
+		"space_create_failed":    "Failed to create the space",
+		"space_list_failed":      "Failed to fetch the space list",
+		"space_fetch_failed":     "Failed to fetch the space",
+		"register_failed":        "Failed to register the user",
+		"login_failed":           "Failed to process the login",
+		"unlock_failed":          "Failed to unlock the account",
+		"request_timeout":        "The request timed out. Please try again later",
+		"request_canceled":       "The request was canceled",
+		"webhook_create_failed":  "Failed to register the webhook",
+		"webhook_list_failed":    "Failed to fetch webhooks",
+		"webhook_delete_failed":  "Failed to delete the webhook",
+		"command_failed":         "Failed to run the command",
+		"bot_create_failed":      "Failed to register the bot",
+		"bot_list_failed":        "Failed to fetch bots",
+		"bot_delete_failed":      "Failed to delete the bot",
+		"schedule_failed":        "Failed to schedule the message",
+		"schedule_list_failed":   "Failed to fetch scheduled messages",
+		"schedule_cancel_failed": "Failed to cancel the scheduled message",
+		"pin_failed":             "Failed to pin the message",
+		"unpin_failed":           "Failed to unpin the message",
+		"bookmark_failed":        "Failed to save the message",
+		"bookmark_list_failed":   "Failed to fetch saved messages",
+		"bookmark_delete_failed": "Failed to remove the saved message",
+		"preview_failed":         "Failed to fetch link previews",
+	},
+}
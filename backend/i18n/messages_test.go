@@ -0,0 +1,18 @@
+package i18n
+
+import "testing"
+
+// すべての言語で同じキーが揃っていること
+func TestCatalogsComplete(t *testing.T) {
+	base := catalogs[DefaultLanguage]
+	for lang, catalog := range catalogs {
+		if len(catalog) != len(base) {
+			t.Errorf("%s: キー数が %s と一致しません (%d != %d)", lang, DefaultLanguage, len(catalog), len(base))
+		}
+		for key := range base {
+			if catalog[key] == "" {
+				t.Errorf("%s: %q の翻訳がありません", lang, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package linkpreview
+
+import "time"
+
+// テストから時計を差し替える
+func (f *Fetcher) SetClock(now func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
@@ -0,0 +1,299 @@
+// Package linkpreview はメッセージ中のリンク先のページから Open Graph のタイトル・説明・画像を取得する
+package linkpreview
+
+import (
+	"chat/config"
+	"chat/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// 読み込むレスポンスの上限（<head> にあればよいため先頭だけ読む）
+	maxBodySize = 512 << 10
+	// 従うリダイレクトの数
+	maxRedirects = 3
+	// 保存する文字数の上限
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+
+	userAgent = "EchoTalkBot/1.0 (+link preview)"
+)
+
+var (
+	ErrUnsupportedURL = errors.New("プレビューを取得できない URL です")
+	ErrNoPreview      = errors.New("ページにプレビューの情報がありません")
+)
+
+// リンクのプレビュー
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// URL ごとにプレビューを取得してキャッシュする
+//
+// 取得に失敗した結果もキャッシュし、同じ URL に繰り返しアクセスしない。同じ URL の取得が
+// 同時に要求された場合は1回だけ取得し、結果を共有する。取得先は webhook と同じく
+// AllowPrivateNetworks でなければ内部のアドレスを拒否する。
+type Fetcher struct {
+	cfg    config.PreviewConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+type entry struct {
+	// 取得が終わると閉じる
+	done    chan struct{}
+	preview Preview
+	err     error
+	expires time.Time
+}
+
+func New(cfg config.PreviewConfig) *Fetcher {
+	client := webhook.NewClient(cfg.Timeout, cfg.AllowPrivateNetworks)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("リダイレクトが %d 回を超えました", maxRedirects)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return ErrUnsupportedURL
+		}
+		return nil
+	}
+
+	return &Fetcher{
+		cfg:     cfg,
+		client:  client,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// URL のプレビューを取得する（キャッシュがあればそれを返す）
+// 取得は呼び出し元のキャンセルでは中断せず、Timeout で打ち切る。ほかの呼び出し元が結果を待っているため。
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Preview{}, ErrUnsupportedURL
+	}
+	u.Fragment = ""
+	key := u.String()
+
+	f.mu.Lock()
+	e, ok := f.entries[key]
+	if !ok || (isDone(e) && !f.now().Before(e.expires)) {
+		f.sweep()
+		e = &entry{done: make(chan struct{})}
+		f.entries[key] = e
+		go f.load(context.WithoutCancel(ctx), key, e)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.preview, e.err
+	case <-ctx.Done():
+		return Preview{}, ctx.Err()
+	}
+}
+
+func (f *Fetcher) load(ctx context.Context, key string, e *entry) {
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.Timeout)
+	defer cancel()
+
+	preview, err := f.fetch(ctx, key)
+
+	f.mu.Lock()
+	e.preview, e.err = preview, err
+	e.expires = f.now().Add(f.cfg.CacheTTL)
+	f.mu.Unlock()
+	close(e.done)
+}
+
+// 期限切れのキャッシュを消し、それでも上限に達していれば期限の近いものから消す
+// 取得中のものは消さない。f.mu を保持して呼ぶ。
+func (f *Fetcher) sweep() {
+	if len(f.entries) < f.cfg.CacheSize {
+		return
+	}
+	now := f.now()
+	for key, e := range f.entries {
+		if isDone(e) && !now.Before(e.expires) {
+			delete(f.entries, key)
+		}
+	}
+	for len(f.entries) >= f.cfg.CacheSize {
+		var oldest string
+		for key, e := range f.entries {
+			if isDone(e) && (oldest == "" || e.expires.Before(f.entries[oldest].expires)) {
+				oldest = key
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		delete(f.entries, oldest)
+	}
+}
+
+func isDone(e *entry) bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *Fetcher) fetch(ctx context.Context, rawURL string) (Preview, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("ステータス %d が返されました", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("%w: %s", ErrNoPreview, contentType)
+	}
+
+	// Shift_JIS などのページも UTF-8 に変換して読む
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBodySize), contentType)
+	if err != nil {
+		return Preview{}, err
+	}
+	preview := parse(body, resp.Request.URL)
+	preview.URL = rawURL
+	if preview.Title == "" && preview.Description == "" {
+		return Preview{}, ErrNoPreview
+	}
+	return preview, nil
+}
+
+// <head> の Open Graph のメタタグを読む。og:title がなければ <title>、og:description がなければ description を使う
+func parse(r io.Reader, base *url.URL) Preview {
+	var preview Preview
+	var title, description string
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return finish(preview, title, description, base)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				return finish(preview, title, description, base)
+			case "title":
+				if z.Next() == html.TextToken && title == "" {
+					title = string(z.Text())
+				}
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				key, content := metaAttrs(z)
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:image", "og:image:url":
+					if preview.Image == "" {
+						preview.Image = content
+					}
+				case "og:site_name":
+					preview.SiteName = content
+				case "description":
+					description = content
+				}
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return finish(preview, title, description, base)
+			}
+		}
+	}
+}
+
+// <meta property="..." content="..."> または <meta name="..." content="...">
+func metaAttrs(z *html.Tokenizer) (key, content string) {
+	for {
+		name, value, more := z.TagAttr()
+		switch string(name) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(string(value)))
+			}
+		case "content":
+			content = string(value)
+		}
+		if !more {
+			return key, content
+		}
+	}
+}
+
+func finish(preview Preview, title, description string, base *url.URL) Preview {
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+	preview.Title = truncate(preview.Title, maxTitleLength)
+	preview.Description = truncate(preview.Description, maxDescriptionLength)
+	preview.SiteName = truncate(preview.SiteName, maxTitleLength)
+
+	// 画像は相対 URL を解決し、http・https のみ残す（data: や javascript: を除く）
+	if preview.Image != "" {
+		image, err := base.Parse(strings.TrimSpace(preview.Image))
+		if err != nil || (image.Scheme != "http" && image.Scheme != "https") {
+			preview.Image = ""
+		} else {
+			preview.Image = image.String()
+		}
+	}
+	return preview
+}
+
+// 空白をまとめ、limit 文字を超える分を切り詰める
+func truncate(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,213 @@
+package linkpreview_test
+
+import (
+	"chat/config"
+	"chat/linkpreview"
+	"chat/webhook"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+)
+
+const ogPage = `<!DOCTYPE html>
+<html><head>
+<title>タイトル要素</title>
+<meta property="og:title" content="Go &amp; チャット">
+<meta property="og:description" content="  説明
+  文 ">
+<meta property="og:image" content="/images/cover.png">
+<meta property="og:site_name" content="Example">
+</head><body><meta property="og:title" content="本文中は読まない"></body></html>`
+
+func testConfig() config.PreviewConfig {
+	cfg := config.Default().Preview
+	cfg.Timeout = time.Second
+	cfg.AllowPrivateNetworks = true
+	return cfg
+}
+
+// パスごとのハンドラーとアクセス数
+func newServer(t *testing.T, handlers map[string]http.HandlerFunc) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	hits := new(sync.Map)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter, _ := hits.LoadOrStore(r.URL.Path, new(atomic.Int32))
+		counter.(*atomic.Int32).Add(1)
+		if h, ok := handlers[r.URL.Path]; ok {
+			h(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, hits
+}
+
+func hitCount(hits *sync.Map, path string) int32 {
+	counter, ok := hits.Load(path)
+	if !ok {
+		return 0
+	}
+	return counter.(*atomic.Int32).Load()
+}
+
+func html(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestFetch_OpenGraph(t *testing.T) {
+	server, _ := newServer(t, map[string]http.HandlerFunc{"/article": html(ogPage)})
+	fetcher := linkpreview.New(testConfig())
+
+	preview, err := fetcher.Fetch(context.Background(), server.URL+"/article")
+	require.NoError(t, err)
+	assert.Equal(t, linkpreview.Preview{
+		URL:         server.URL + "/article",
+		Title:       "Go & チャット",
+		Description: "説明 文",
+		Image:       server.URL + "/images/cover.png",
+		SiteName:    "Example",
+	}, preview)
+}
+
+// Open Graph がなければ <title> と description を使う。Shift_JIS のページも読める
+func TestFetch_Fallback(t *testing.T) {
+	page, err := japanese.ShiftJIS.NewEncoder().String(`<html><head><title>日本語のページ</title>` +
+		`<meta name="description" content="説明です"><meta property="og:image" content="javascript:alert(1)"></head></html>`)
+	require.NoError(t, err)
+	server, _ := newServer(t, map[string]http.HandlerFunc{
+		"/sjis": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+			_, _ = w.Write([]byte(page))
+		},
+	})
+
+	preview, err := linkpreview.New(testConfig()).Fetch(context.Background(), server.URL+"/sjis")
+	require.NoError(t, err)
+	assert.Equal(t, "日本語のページ", preview.Title)
+	assert.Equal(t, "説明です", preview.Description)
+	assert.Empty(t, preview.Image)
+}
+
+func TestFetch_Failures(t *testing.T) {
+	server, _ := newServer(t, map[string]http.HandlerFunc{
+		"/image": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("\x89PNG"))
+		},
+		"/empty": html(`<html><head></head><body>本文</body></html>`),
+	})
+	fetcher := linkpreview.New(testConfig())
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/image")
+	assert.ErrorIs(t, err, linkpreview.ErrNoPreview)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/empty")
+	assert.ErrorIs(t, err, linkpreview.ErrNoPreview)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+
+	_, err = fetcher.Fetch(context.Background(), "ftp://example.com/file")
+	assert.ErrorIs(t, err, linkpreview.ErrUnsupportedURL)
+}
+
+// 応答が遅いページは Timeout で打ち切る
+func TestFetch_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server, _ := newServer(t, map[string]http.HandlerFunc{
+		"/slow": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		},
+	})
+	defer close(release)
+
+	cfg := testConfig()
+	cfg.Timeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err := linkpreview.New(cfg).Fetch(context.Background(), server.URL+"/slow")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// 内部のアドレスは AllowPrivateNetworks でなければ取得しない
+func TestFetch_PrivateNetwork(t *testing.T) {
+	server, hits := newServer(t, map[string]http.HandlerFunc{"/article": html(ogPage)})
+
+	cfg := testConfig()
+	cfg.AllowPrivateNetworks = false
+	_, err := linkpreview.New(cfg).Fetch(context.Background(), server.URL+"/article")
+	assert.ErrorIs(t, err, webhook.ErrForbiddenAddress)
+	assert.Zero(t, hitCount(hits, "/article"))
+}
+
+// 成功・失敗とも CacheTTL の間はキャッシュを返し、同時の要求は1回だけ取得する
+func TestFetch_Cache(t *testing.T) {
+	server, hits := newServer(t, map[string]http.HandlerFunc{
+		"/article": func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			html(ogPage)(w, r)
+		},
+	})
+	fetcher := linkpreview.New(testConfig())
+	now := time.Now()
+	fetcher.SetClock(func() time.Time { return now })
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			preview, err := fetcher.Fetch(context.Background(), server.URL+"/article#section")
+			assert.NoError(t, err)
+			assert.Equal(t, "Go & チャット", preview.Title)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), hitCount(hits, "/article"))
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), hitCount(hits, "/missing"))
+
+	// 期限が切れたら取得し直す
+	fetcher.SetClock(func() time.Time { return now.Add(testConfig().CacheTTL) })
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/article")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), hitCount(hits, "/article"))
+}
+
+// 呼び出し元がキャンセルしてもすぐに戻る
+func TestFetch_CallerCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server, _ := newServer(t, map[string]http.HandlerFunc{
+		"/slow": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		},
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := linkpreview.New(testConfig()).Fetch(ctx, server.URL+"/slow")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GORM のログを slog に流す
+//
+// SQL はプレースホルダーのまま記録し、パスワードなどのパラメーターは出力しない。
+type gormLogger struct {
+	logger    *slog.Logger
+	slowQuery time.Duration
+}
+
+func NewGormLogger(logger *slog.Logger, slowQuery time.Duration) gormlogger.Interface {
+	return &gormLogger{logger: logger, slowQuery: slowQuery}
+}
+
+// レベルは slog 側で制御する
+func (l *gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// クエリごとに呼ばれる。エラーと遅いクエリは warn、それ以外は debug で記録する
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "クエリエラー", "sql", sql, "rows", rows, "elapsed", elapsed, "error", err)
+	case elapsed > l.slowQuery:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "スロークエリ", "sql", sql, "rows", rows, "elapsed", elapsed)
+	case l.logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		l.logger.DebugContext(ctx, "クエリ", "sql", sql, "rows", rows, "elapsed", elapsed)
+	}
+}
+
+// パラメーターを埋め込まない（gorm.ParamsFilter）
+func (l *gormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,46 @@
+package logging_test
+
+import (
+	"bytes"
+	"chat/config"
+	"chat/logging"
+	"chat/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormLogger_HidesParams(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(config.LogConfig{Level: "debug", Format: "text"}, &buf)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{
+		Logger: logging.NewGormLogger(logger, time.Second),
+	})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	require.NoError(t, db.Create(&models.User{Username: "alice", Password: "hunter22"}).Error)
+
+	assert.Contains(t, buf.String(), "level=DEBUG")
+	assert.Contains(t, buf.String(), "$2")
+	assert.NotContains(t, buf.String(), "hunter22")
+
+	// エラーは warn で記録される
+	buf.Reset()
+	mock.ExpectQuery(`SELECT`).WillReturnError(errors.New("connection reset"))
+	db.First(&models.User{}, 1)
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "connection reset")
+}
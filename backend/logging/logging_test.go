@@ -0,0 +1,68 @@
+package logging_test
+
+import (
+	"bytes"
+	"chat/config"
+	"chat/logging"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew_JSONWithContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(config.LogConfig{Level: "info", Format: "json"}, &buf)
+
+	ctx := logging.WithAttrs(context.Background(), slog.String("request_id", "req-1"))
+	ctx = logging.WithAttrs(ctx, slog.String("conn_id", "conn-1"))
+	logger.InfoContext(ctx, "ログイン", "username", "alice", "password", "secret1", "token", "abc.def.ghi")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "ログイン", line["msg"])
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "conn-1", line["conn_id"])
+	assert.Equal(t, "alice", line["username"])
+	assert.Equal(t, "[REDACTED]", line["password"])
+	assert.Equal(t, "[REDACTED]", line["token"])
+	assert.NotContains(t, buf.String(), "secret1")
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(config.LogConfig{Level: "warn", Format: "text"}, &buf)
+
+	logger.Info("出力されない")
+	assert.Empty(t, buf.String())
+
+	logger.With("authorization", "Bearer xyz").Warn("出力される")
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "authorization=[REDACTED]")
+	assert.NotContains(t, buf.String(), "xyz")
+}
+
+func TestNewID(t *testing.T) {
+	a, b := logging.NewID(), logging.NewID()
+	assert.Len(t, a, 16)
+	assert.NotEqual(t, a, b)
+}
+
+func TestNew_TraceID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(config.LogConfig{Level: "info", Format: "json"}, &buf)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	logger.InfoContext(ctx, "メッセージ保存")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", line["span_id"])
+}
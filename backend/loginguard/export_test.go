@@ -0,0 +1,8 @@
+package loginguard
+
+import "time"
+
+// テストから時計を差し替える
+func (g *Guard) SetClock(now func() time.Time) {
+	g.now = now
+}
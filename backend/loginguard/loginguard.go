@@ -0,0 +1,119 @@
+package loginguard
+
+import (
+	"chat/config"
+	"sync"
+	"time"
+)
+
+// キー（ユーザー名や IP）ごとにログイン失敗を数え、再試行までの待ち時間を決める
+//
+// 失敗が MaxFailures 回未満の間は BaseDelay を起点に指数的に待たせ（バックオフ）、
+// 到達したら LockoutDuration だけロックする。ロック後も失敗が続くとロック時間は倍々に延び、
+// MaxLockout で頭打ちになる。
+type Guard struct {
+	cfg config.LoginGuardConfig
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	blockedTill time.Time
+}
+
+func New(cfg config.LoginGuardConfig) *Guard {
+	return &Guard{
+		cfg:     cfg,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// キーのいずれかが待機中・ロック中であれば、最も長い残り時間を返す（0 ならログインを試行してよい）
+func (g *Guard) Check(keys ...string) time.Duration {
+	now := g.now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var wait time.Duration
+	for _, key := range keys {
+		if e, ok := g.entries[key]; ok {
+			wait = max(wait, e.blockedTill.Sub(now))
+		}
+	}
+	return wait
+}
+
+// 失敗を1回記録する。この失敗でロック状態になった場合はロック時間を返す
+func (g *Guard) Fail(key string, maxFailures int) time.Duration {
+	now := g.now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	e, ok := g.entries[key]
+	if !ok {
+		e = &entry{}
+		g.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	wait, locked := g.delay(e.failures, maxFailures)
+	e.blockedTill = now.Add(wait)
+	if locked {
+		return wait
+	}
+	return 0
+}
+
+// 成功時や管理者によるロック解除で失敗回数を消す
+func (g *Guard) Reset(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, key)
+}
+
+// failures 回目の失敗後に待たせる時間と、それがロックかどうか
+func (g *Guard) delay(failures, maxFailures int) (time.Duration, bool) {
+	if failures < maxFailures {
+		return min(doubled(g.cfg.BaseDelay, failures-1), g.cfg.LockoutDuration), false
+	}
+	return min(doubled(g.cfg.LockoutDuration, failures-maxFailures), g.cfg.MaxLockout), true
+}
+
+// d * 2^n（オーバーフローしないよう途中で打ち切る）
+func doubled(d time.Duration, n int) time.Duration {
+	for ; n > 0 && d < time.Duration(1)<<62; n-- {
+		d *= 2
+	}
+	return d
+}
+
+// 最後の失敗から MaxLockout 以上経ったキーは忘れる
+func (g *Guard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < g.cfg.MaxLockout {
+		return
+	}
+	for key, e := range g.entries {
+		if now.Sub(e.lastFailure) >= g.cfg.MaxLockout {
+			delete(g.entries, key)
+		}
+	}
+	g.lastSweep = now
+}
+
+// 保持しているキーの数（テスト・監視用）
+func (g *Guard) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.entries)
+}
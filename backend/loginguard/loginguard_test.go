@@ -0,0 +1,71 @@
+package loginguard_test
+
+import (
+	"chat/config"
+	"chat/loginguard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = config.LoginGuardConfig{
+	Enabled:         true,
+	MaxFailures:     3,
+	IPMaxFailures:   10,
+	BaseDelay:       time.Second,
+	LockoutDuration: time.Minute,
+	MaxLockout:      5 * time.Minute,
+}
+
+func TestGuard_BackoffAndLockout(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := loginguard.New(testConfig)
+	guard.SetClock(func() time.Time { return now })
+
+	assert.Zero(t, guard.Check("user:alice"))
+
+	// ロック前は 1s, 2s と倍々に待たせる
+	assert.Zero(t, guard.Fail("user:alice", 3))
+	assert.Equal(t, time.Second, guard.Check("user:alice"))
+	now = now.Add(time.Second)
+	assert.Zero(t, guard.Check("user:alice"))
+
+	assert.Zero(t, guard.Fail("user:alice", 3))
+	assert.Equal(t, 2*time.Second, guard.Check("user:alice"))
+	now = now.Add(2 * time.Second)
+
+	// 3回目でロック
+	assert.Equal(t, time.Minute, guard.Fail("user:alice", 3))
+	assert.Equal(t, time.Minute, guard.Check("user:alice"))
+
+	// ロック後の失敗でロック時間が延び、上限で止まる
+	now = now.Add(time.Minute)
+	assert.Equal(t, 2*time.Minute, guard.Fail("user:alice", 3))
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 4*time.Minute, guard.Fail("user:alice", 3))
+	now = now.Add(4 * time.Minute)
+	assert.Equal(t, 5*time.Minute, guard.Fail("user:alice", 3))
+
+	// 他のキーは影響を受けない
+	assert.Zero(t, guard.Check("user:bob"))
+	// 複数キーのうち最も長い待ち時間を返す
+	assert.Equal(t, 5*time.Minute, guard.Check("user:bob", "user:alice"))
+
+	guard.Reset("user:alice")
+	assert.Zero(t, guard.Check("user:alice"))
+}
+
+func TestGuard_SweepsOldEntries(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := loginguard.New(testConfig)
+	guard.SetClock(func() time.Time { return now })
+
+	guard.Fail("ip:10.0.0.1", 10)
+	guard.Fail("ip:10.0.0.2", 10)
+	assert.Equal(t, 2, guard.Len())
+
+	now = now.Add(10 * time.Minute)
+	guard.Fail("ip:10.0.0.3", 10)
+	assert.Equal(t, 1, guard.Len())
+}
@@ -2,37 +2,139 @@ package main
 
 import (
 	"chat/api"
-	"fmt"
-	"log"
+	"chat/config"
+	"chat/logging"
+	"chat/metrics"
+	"chat/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var (
-	host     = os.Getenv("DATABASE_HOST")
-	user     = os.Getenv("DATABASE_USER")
-	password = os.Getenv("DATABASE_PASSWORD")
-	dbName   = os.Getenv("DATABASE_NAME")
-	port     = "5432"
-	dsn      = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, user, password, dbName, port)
-)
-
 func main() {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	cfg, err := config.Load()
+	if err != nil {
+		fatal(slog.Default(), "設定エラー", err)
+	}
+
+	// 以降のログはすべて構造化ログ（標準の log パッケージの出力も含む）
+	logger := logging.New(cfg.Log, os.Stdout)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, os.Stdout)
 	if err != nil {
-		log.Fatalf("DB接続エラー: %v", err)
+		fatal(logger, "トレースの初期化エラー", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.Database.DSN()), &gorm.Config{
+		Logger: logging.NewGormLogger(logger, cfg.Log.SlowQuery),
+	})
+	if err != nil {
+		fatal(logger, "DB接続エラー", err)
+	}
+
+	// クエリ時間などのメトリクス
+	m := metrics.New()
+	if err := db.Use(m.GormPlugin()); err != nil {
+		fatal(logger, "メトリクスの初期化エラー", err)
+	}
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		fatal(logger, "トレースの初期化エラー", err)
+	}
+
+	// `./main migrate ...` はマイグレーションだけ実行して終了
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db, os.Args[2:], logger); err != nil {
+			fatal(logger, "マイグレーションエラー", err)
+		}
+		return
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := migrateOnStart(context.Background(), db, logger); err != nil {
+			fatal(logger, "マイグレーションエラー", err)
+		}
 	}
 
 	// ルートの登録
-	r, webSocketService := api.RegisterRoutes(db)
+	r, workers := api.RegisterRoutes(db, cfg, logger, m)
 
 	// **WebSocketのメッセージ処理をゴルーチンで実行**
-	go webSocketService.HandleMessages()
+	go workers.WebSocket.HandleMessages()
+
+	// Webhook の送信（再送を含む）
+	go workers.Webhooks.Run()
 
-	r.Run(":8080")
+	// 予約投稿・リマインダーの送信（複数のインスタンスで動かしても同じ予約は1回だけ送る）
+	go workers.Scheduler.Run()
+
+	srv := &http.Server{
+		Addr:     cfg.Server.Addr,
+		Handler:  r,
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.Info("サーバーを起動中", "addr", cfg.Server.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "サーバー起動エラー", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	logger.Info("シャットダウンを開始します")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// 新規接続の受付を停止し、処理中のリクエストを待つ
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTPサーバー停止エラー", "error", err)
+	}
+
+	// 予約の投稿はハブから配信するため、ハブより先に止める（送れなかった予約は次の起動後に送る）
+	if err := workers.Scheduler.Shutdown(shutdownCtx); err != nil {
+		logger.Error("スケジューラー停止エラー", "error", err)
+	}
+
+	// WebSocket クライアントへ close フレームを送り、Broadcast を排出する
+	if err := workers.WebSocket.Shutdown(shutdownCtx); err != nil {
+		logger.Error("WebSocket停止エラー", "error", err)
+	}
+
+	// 送信中の Webhook を待つ（送れなかった配信は次の起動後に再送する）
+	if err := workers.Webhooks.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Webhook停止エラー", "error", err)
+	}
+
+	// DB コネクションプールを閉じる
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("DB切断エラー", "error", err)
+		}
+	}
+
+	// 未送信のスパンを送り出す
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("トレース送信エラー", "error", err)
+	}
+
+	logger.Info("サーバーを停止しました")
+}
 
-	log.Println("サーバーを起動中: ポート:8080")
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
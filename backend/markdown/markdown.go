@@ -0,0 +1,263 @@
+// Package markdown はメッセージ本文の Markdown（チャット向けの一部の記法）を HTML に変換する
+//
+// 対応する記法:
+//   - 段落（空行で区切る。段落内の改行は <br>）
+//   - コードブロック（``` で囲む）・引用（> で始まる行）・箇条書き（- または * で始まる行）
+//   - **太字**・*斜体*・_斜体_・~~取り消し線~~・`コード`
+//   - [テキスト](https://…) のリンクと、本文中の http・https の URL
+//
+// 生成する HTML は上の記法に対応するタグとエスケープしたテキストだけで組み立てる。本文の HTML は
+// そのまま出力しないため、クライアントは結果をサニタイズせずに表示できる。リンクは http と https のみ。
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 変換結果
+type Result struct {
+	// サニタイズ済みの HTML
+	HTML string
+	// 本文中のリンク先（出現順・重複なし。コード内の URL は含まない）
+	URLs []string
+}
+
+// 本文を HTML に変換し、リンク先の URL を取り出す
+func Parse(src string) Result {
+	p := &parser{seen: make(map[string]bool)}
+	p.blocks(strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"))
+	return Result{HTML: p.out.String(), URLs: p.urls}
+}
+
+// 本文中のリンク先の URL（リンクのプレビュー用）
+func ExtractURLs(src string) []string {
+	return Parse(src).URLs
+}
+
+type parser struct {
+	out  strings.Builder
+	urls []string
+	seen map[string]bool
+}
+
+func (p *parser) blocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case strings.HasPrefix(strings.TrimSpace(line), "```"):
+			// 閉じる ``` がなければ最後の行までをコードとする
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "```") {
+				end++
+			}
+			p.out.WriteString("<pre><code>")
+			p.out.WriteString(html.EscapeString(strings.Join(lines[i+1:min(end, len(lines))], "\n")))
+			p.out.WriteString("</code></pre>")
+			i = end + 1
+
+		case isQuote(line):
+			var quoted []string
+			for ; i < len(lines) && isQuote(lines[i]); i++ {
+				text := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+				quoted = append(quoted, strings.TrimPrefix(text, " "))
+			}
+			p.out.WriteString("<blockquote>")
+			p.inlineLines(quoted)
+			p.out.WriteString("</blockquote>")
+
+		case isListItem(line):
+			p.out.WriteString("<ul>")
+			for ; i < len(lines) && isListItem(lines[i]); i++ {
+				p.out.WriteString("<li>")
+				p.inline(strings.TrimSpace(lines[i])[2:], true)
+				p.out.WriteString("</li>")
+			}
+			p.out.WriteString("</ul>")
+
+		default:
+			var para []string
+			for ; i < len(lines) && !startsBlock(lines[i]); i++ {
+				para = append(para, lines[i])
+			}
+			p.out.WriteString("<p>")
+			p.inlineLines(para)
+			p.out.WriteString("</p>")
+		}
+	}
+}
+
+func isQuote(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, " "), ">")
+}
+
+func isListItem(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) > 2 && (line[0] == '-' || line[0] == '*') && line[1] == ' '
+}
+
+// 段落を終える行（空行・他のブロックの始まり）
+func startsBlock(line string) bool {
+	return strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "```") || isQuote(line) || isListItem(line)
+}
+
+func (p *parser) inlineLines(lines []string) {
+	for i, line := range lines {
+		if i > 0 {
+			p.out.WriteString("<br>")
+		}
+		p.inline(line, true)
+	}
+}
+
+// 強調・コード・リンクを変換する。links が false ならリンクにしない（リンクのテキスト内）
+func (p *parser) inline(s string, links bool) {
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				p.out.WriteString("<code>" + html.EscapeString(rest[1:1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "~~"):
+			tag := map[byte]string{'*': "strong", '~': "del"}[rest[0]]
+			if n, ok := p.emphasis(s, i, rest[:2], tag, links); ok {
+				i += n
+				continue
+			}
+
+		case rest[0] == '*' || rest[0] == '_':
+			// snake_case などの単語中の _ は強調にしない
+			if rest[0] == '*' || !wordBefore(s, i) {
+				if n, ok := p.emphasis(s, i, rest[:1], "em", links); ok {
+					i += n
+					continue
+				}
+			}
+
+		case rest[0] == '[' && links:
+			if n, ok := p.link(rest); ok {
+				i += n
+				continue
+			}
+
+		case links && (strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://")) && !wordBefore(s, i):
+			if n, ok := p.autolink(rest); ok {
+				i += n
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		p.out.WriteString(html.EscapeString(rest[:size]))
+		i += size
+	}
+}
+
+// s[i:] が delim で始まる強調。閉じる delim があり、中身が空白で始まらず空でなければ変換する
+func (p *parser) emphasis(s string, i int, delim, tag string, links bool) (int, bool) {
+	body := s[i+len(delim):]
+	end := strings.Index(body, delim)
+	if end <= 0 || strings.TrimSpace(body[:end]) != body[:end] {
+		return 0, false
+	}
+	// 閉じる _ の直後が単語の途中なら強調にしない
+	if delim == "_" && wordAt(body, end+1) {
+		return 0, false
+	}
+	p.out.WriteString("<" + tag + ">")
+	p.inline(body[:end], links)
+	p.out.WriteString("</" + tag + ">")
+	return len(delim)*2 + end, true
+}
+
+// [テキスト](URL)
+func (p *parser) link(rest string) (int, bool) {
+	closeText := strings.Index(rest, "](")
+	if closeText <= 1 {
+		return 0, false
+	}
+	closeURL := strings.IndexByte(rest[closeText+2:], ')')
+	if closeURL < 0 {
+		return 0, false
+	}
+	href, ok := safeURL(strings.TrimSpace(rest[closeText+2 : closeText+2+closeURL]))
+	if !ok {
+		return 0, false
+	}
+
+	p.openLink(href)
+	p.inline(rest[1:closeText], false)
+	p.out.WriteString("</a>")
+	return closeText + 2 + closeURL + 1, true
+}
+
+// 本文中の URL。末尾の句読点や対応しない閉じ括弧は URL に含めない
+func (p *parser) autolink(rest string) (int, bool) {
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '<' || r == '>' || r == '"' || r == '`' || r > unicode.MaxASCII
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	candidate := rest[:end]
+	for len(candidate) > 0 {
+		last := candidate[len(candidate)-1]
+		if strings.IndexByte(".,;:!?'*_~(", last) >= 0 ||
+			(last == ')' && strings.Count(candidate, ")") > strings.Count(candidate, "(")) {
+			candidate = candidate[:len(candidate)-1]
+			continue
+		}
+		break
+	}
+
+	href, ok := safeURL(candidate)
+	if !ok {
+		return 0, false
+	}
+	p.openLink(href)
+	p.out.WriteString(html.EscapeString(candidate))
+	p.out.WriteString("</a>")
+	return len(candidate), true
+}
+
+func (p *parser) openLink(href string) {
+	if !p.seen[href] {
+		p.seen[href] = true
+		p.urls = append(p.urls, href)
+	}
+	p.out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">`)
+}
+
+// http・https でホストのある URL のみリンクにする（javascript: などを除く）
+func safeURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// s[i] が英数字か
+func wordAt(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// s[i] の直前が英数字か
+func wordBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
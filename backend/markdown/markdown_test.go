@@ -0,0 +1,73 @@
+package markdown_test
+
+import (
+	"chat/markdown"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Inline(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"平文", "こんにちは", "<p>こんにちは</p>"},
+		{"太字と斜体", "**重要** と *注意* と _補足_", "<p><strong>重要</strong> と <em>注意</em> と <em>補足</em></p>"},
+		{"取り消し線", "~~中止~~", "<p><del>中止</del></p>"},
+		{"コード内は変換しない", "`**a** <b>`", "<p><code>**a** &lt;b&gt;</code></p>"},
+		{"単語中の _ はそのまま", "snake_case_name", "<p>snake_case_name</p>"},
+		{"閉じていない記号はそのまま", "2 * 3 = 6", "<p>2 * 3 = 6</p>"},
+		{"段落内の改行", "1行目\n2行目", "<p>1行目<br>2行目</p>"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, markdown.Parse(tc.src).HTML)
+		})
+	}
+}
+
+func TestParse_Blocks(t *testing.T) {
+	src := "手順:\n- 準備\n- **実行**\n\n> 引用\n> 2行目\n\n```\nfmt.Println(\"<hi>\")\n```\n最後"
+	assert.Equal(t,
+		"<p>手順:</p><ul><li>準備</li><li><strong>実行</strong></li></ul>"+
+			"<blockquote>引用<br>2行目</blockquote>"+
+			"<pre><code>fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre><p>最後</p>",
+		markdown.Parse(src).HTML)
+}
+
+// 本文の HTML はすべてエスケープし、http・https 以外のリンクは作らない
+func TestParse_Sanitize(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"タグ", `<script>alert("x")</script>`, "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>"},
+		{"javascript スキーム", "[押す](javascript:alert(1))", "<p>[押す](javascript:alert(1))</p>"},
+		{"属性の閉じ忘れ", `[a](https://example.com/"onmouseover="x)`, `<p><a href="https://example.com/%22onmouseover=%22x" rel="nofollow noopener noreferrer" target="_blank">a</a></p>`},
+		{"強調内のタグ", "**<img src=x onerror=y>**", "<p><strong>&lt;img src=x onerror=y&gt;</strong></p>"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, markdown.Parse(tc.src).HTML)
+		})
+	}
+}
+
+func TestParse_Links(t *testing.T) {
+	result := markdown.Parse("資料は [こちら](https://example.com/doc) と https://example.com/wiki/Go_(lang) を参照。https://example.com/doc も。`https://code.example.com`")
+
+	assert.Equal(t, `<p>資料は <a href="https://example.com/doc" rel="nofollow noopener noreferrer" target="_blank">こちら</a> と `+
+		`<a href="https://example.com/wiki/Go_(lang)" rel="nofollow noopener noreferrer" target="_blank">https://example.com/wiki/Go_(lang)</a> を参照。`+
+		`<a href="https://example.com/doc" rel="nofollow noopener noreferrer" target="_blank">https://example.com/doc</a> も。`+
+		`<code>https://code.example.com</code></p>`, result.HTML)
+	// 重複とコード内の URL は含めない
+	assert.Equal(t, []string{"https://example.com/doc", "https://example.com/wiki/Go_(lang)"}, result.URLs)
+}
+
+func TestExtractURLs_TrailingPunctuation(t *testing.T) {
+	urls := markdown.ExtractURLs("見て（https://example.com/a）、(https://example.com/b). https://example.com/c?q=1!")
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b", "https://example.com/c?q=1"}, urls)
+}
@@ -0,0 +1,42 @@
+package metrics_test
+
+import (
+	"chat/metrics"
+	"chat/models"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormPlugin(t *testing.T) {
+	m := metrics.New()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(m.GormPlugin()))
+
+	mock.ExpectQuery(`SELECT \* FROM "spaces"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "general"))
+	var spaces []models.Space
+	require.NoError(t, db.Find(&spaces).Error)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "messages"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, db.Delete(&models.Message{}, 1).Error)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.DBQueryDuration))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `chat_db_query_duration_seconds_count{operation="query",table="spaces"} 1`)
+	assert.Contains(t, w.Body.String(), `chat_db_query_duration_seconds_count{operation="delete",table="messages"} 1`)
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chat"
+
+// アプリケーションのメトリクス
+//
+// グローバルなレジストリは使わず、インスタンスごとに登録する（テストで何度生成してもよい）。
+type Metrics struct {
+	registry *prometheus.Registry
+
+	HTTPRequests      *prometheus.CounterVec
+	HTTPDuration      *prometheus.HistogramVec
+	WSConnections     *prometheus.GaugeVec
+	BroadcastDuration prometheus.Histogram
+	DroppedClients    *prometheus.CounterVec
+	SSEConnections    *prometheus.GaugeVec
+	MessagesCreated   *prometheus.CounterVec
+	DBQueryDuration   *prometheus.HistogramVec
+	WebhookDeliveries *prometheus.CounterVec
+	ScheduledMessages *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "ルート・ステータスごとの HTTP リクエスト数",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "ルートごとの HTTP リクエスト処理時間",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		WSConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "websocket_connections",
+			Help:      "スペースごとの接続中の WebSocket クライアント数",
+		}, []string{"space_id"}),
+		BroadcastDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "websocket_broadcast_duration_seconds",
+			Help:      "1メッセージを全クライアントへ送り終えるまでの時間",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}),
+		DroppedClients: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "websocket_dropped_clients_total",
+			Help:      "サーバー側から切断したクライアント数",
+		}, []string{"reason"}),
+		SSEConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sse_connections",
+			Help:      "スペースごとの接続中の SSE クライアント数",
+		}, []string{"space_id"}),
+		MessagesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_created_total",
+			Help:      "作成されたメッセージ数（rate() で毎秒の投稿数）",
+		}, []string{"source"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "操作・テーブルごとの DB クエリ時間",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		WebhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Webhook の送信結果ごとの送信数（succeeded・retry・failed）",
+		}, []string{"result"}),
+		ScheduledMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scheduled_messages_total",
+			Help:      "予約投稿・リマインダーの送信結果ごとの送信数（sent・retry・failed）",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		m.HTTPRequests,
+		m.HTTPDuration,
+		m.WSConnections,
+		m.BroadcastDuration,
+		m.DroppedClients,
+		m.SSEConnections,
+		m.MessagesCreated,
+		m.DBQueryDuration,
+		m.WebhookDeliveries,
+		m.ScheduledMessages,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// /metrics のハンドラー
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// スペース ID のラベル値（スペースを指定しない接続は "none"）
+func SpaceLabel(spaceID int) string {
+	if spaceID <= 0 {
+		return "none"
+	}
+	return strconv.Itoa(spaceID)
+}
@@ -1,20 +1,20 @@
 package middlewares
 
 import (
+	"chat/origins"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORSミドルウェアの設定
-func CORSConfig() gin.HandlerFunc {
+// CORSミドルウェアの設定（許可するオリジンは WebSocket と共通）
+// レート制限・リクエスト ID・非推奨 API・作成したリソースの案内のヘッダーはフロントエンドの JS から読めるよう公開する
+func CORSConfig(allowOrigins *origins.Matcher) gin.HandlerFunc {
 	return cors.New(cors.Config{
-		AllowOrigins: []string{
-			"http://localhost:3000",
-			"https://www.echo-talk.com",
-		},
+		AllowOriginFunc:  allowOrigins.Allowed,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-Request-ID", "Deprecation", "Link", "Location"},
 		AllowCredentials: true,
 	})
 }
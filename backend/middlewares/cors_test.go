@@ -0,0 +1,52 @@
+package middlewares_test
+
+import (
+	"chat/middlewares"
+	"chat/origins"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouterCORS() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.CORSConfig(origins.MustNew([]string{"http://localhost:3000"})))
+	router.GET("/ping", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return router
+}
+
+// 許可したオリジンには、フロントエンドが読むレスポンスヘッダーを公開する
+func TestCORSConfig_ExposeHeaders(t *testing.T) {
+	router := setupRouterCORS()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ping", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+	exposed := strings.Split(w.Header().Get("Access-Control-Expose-Headers"), ",")
+	for _, header := range []string{"Content-Length", "Retry-After", "X-Request-Id", "Deprecation", "Link", "Location"} {
+		assert.Contains(t, exposed, header)
+	}
+}
+
+func TestCORSConfig_DisallowedOrigin(t *testing.T) {
+	router := setupRouterCORS()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ping", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 廃止予定のルートであることをレスポンスヘッダーで知らせる（RFC 9745）
+//
+//	Deprecation: @<廃止予定とした日時の UNIX 秒>
+//	Link: <移行先の説明>; rel="deprecation"
+func Deprecated(since time.Time, docs string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	link := "<" + docs + `>; rel="deprecation"; type="text/html"`
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		ctx.Writer.Header().Add("Link", link)
+		ctx.Next()
+	}
+}
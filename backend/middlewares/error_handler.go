@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"chat/apperrors"
+	"chat/i18n"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// クライアントが応答を待たずに切断した（nginx の慣例に合わせる）
+const statusClientClosedRequest = 499
+
+// エラーの種類と HTTP ステータスの対応
+var errorStatuses = []struct {
+	kind   error
+	status int
+}{
+	{apperrors.ErrBadRequest, http.StatusBadRequest},
+	{apperrors.ErrValidation, http.StatusUnprocessableEntity},
+	{apperrors.ErrNotFound, http.StatusNotFound},
+	{apperrors.ErrConflict, http.StatusConflict},
+	{apperrors.ErrUnauthorized, http.StatusUnauthorized},
+	{apperrors.ErrForbidden, http.StatusForbidden},
+	{apperrors.ErrRateLimited, http.StatusTooManyRequests},
+	{apperrors.ErrInternal, http.StatusInternalServerError},
+	{apperrors.ErrTimeout, http.StatusGatewayTimeout},
+	{apperrors.ErrCanceled, statusClientClosedRequest},
+}
+
+// ハンドラーが ctx.Error で登録したエラーを共通形式の JSON に変換する
+// メッセージは Language ミドルウェアで決めた言語に翻訳される。
+//
+//	{"code": "space_not_found", "error": "スペースが見つかりません"}
+//
+// バリデーションエラーは項目ごとの理由を "fields" に含める。
+//
+//	{"code": "validation_failed", "error": "...", "fields": {"username": {"code": "too_short", "message": "..."}}}
+func ErrorHandler(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+		}
+		status, body := errorResponse(err, i18n.Language(ctx))
+		if status == http.StatusInternalServerError {
+			logger.ErrorContext(ctx.Request.Context(), "内部エラー",
+				"method", ctx.Request.Method, "route", ctx.FullPath(), "error", err)
+		}
+		ctx.JSON(status, body)
+	}
+}
+
+func errorResponse(err error, lang string) (int, gin.H) {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		for _, s := range errorStatuses {
+			if errors.Is(appErr.Kind, s.kind) {
+				body := gin.H{"code": appErr.Code, "error": translateError(appErr, lang)}
+				if len(appErr.Fields) > 0 {
+					body["fields"] = fieldErrors(appErr.Fields, lang)
+				}
+				return s.status, body
+			}
+		}
+	}
+	return http.StatusInternalServerError, gin.H{"code": "internal_error", "error": i18n.T(lang, "internal_error")}
+}
+
+// 辞書にないコードはエラー自身のメッセージを使う
+func translateError(appErr *apperrors.Error, lang string) string {
+	if i18n.Has(appErr.Code) {
+		return i18n.T(lang, appErr.Code)
+	}
+	return appErr.Message
+}
+
+// 項目名をキーにしたエラーの map（フロントエンドで入力欄の横に表示する）
+func fieldErrors(fields []apperrors.FieldError, lang string) gin.H {
+	result := gin.H{}
+	for _, f := range fields {
+		key := "validation." + f.Code
+		if !i18n.Has(key) {
+			key = "validation.invalid"
+		}
+		var message string
+		if f.Param != "" {
+			message = i18n.T(lang, key, f.Param)
+		} else {
+			message = i18n.T(lang, key)
+		}
+		result[f.Field] = gin.H{"code": f.Code, "message": message}
+	}
+	return result
+}
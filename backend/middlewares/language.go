@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"chat/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// レスポンスの言語を決める
+//
+// 優先順位: クエリ ?lang= → Cookie "lang"（ユーザーの設定）→ Accept-Language → デフォルト（ja）
+func Language() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		lang := ""
+		if q := ctx.Query("lang"); i18n.IsSupported(q) {
+			lang = q
+		} else if c, err := ctx.Cookie("lang"); err == nil && i18n.IsSupported(c) {
+			lang = c
+		} else {
+			lang = i18n.Match(ctx.GetHeader("Accept-Language"))
+		}
+
+		i18n.SetLanguage(ctx, lang)
+		ctx.Header("Content-Language", lang)
+		// 言語は Accept-Language だけでなく Cookie でも変わるため、共有キャッシュが別の言語の応答を返さないようにする
+		// （?lang= はクエリなので URL でキャッシュが分かれる）
+		ctx.Writer.Header().Add("Vary", "Accept-Language")
+		ctx.Writer.Header().Add("Vary", "Cookie")
+		ctx.Next()
+	}
+}
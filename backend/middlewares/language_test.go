@@ -0,0 +1,84 @@
+package middlewares_test
+
+import (
+	"chat/apperrors"
+	"chat/i18n"
+	"chat/logging"
+	"chat/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var errSpaceNotFound = apperrors.New(apperrors.ErrNotFound, "space_not_found", "スペースが見つかりません")
+
+func setupRouterLanguage() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.Language())
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.GET("/lang", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, i18n.Language(ctx))
+	})
+	router.GET("/error", func(ctx *gin.Context) {
+		ctx.Error(errSpaceNotFound)
+	})
+	return router
+}
+
+func TestLanguage_Precedence(t *testing.T) {
+	router := setupRouterLanguage()
+
+	cases := []struct {
+		name   string
+		url    string
+		cookie string
+		accept string
+		want   string
+	}{
+		{"デフォルト", "/lang", "", "", "ja"},
+		{"Accept-Language", "/lang", "", "en-US,en;q=0.9", "en"},
+		{"Cookie が Accept-Language より優先", "/lang", "ja", "en", "ja"},
+		{"クエリが最優先", "/lang?lang=en", "ja", "ja", "en"},
+		{"未対応のクエリは無視", "/lang?lang=xx", "", "en", "en"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", c.url, nil)
+			if c.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: c.cookie})
+			}
+			if c.accept != "" {
+				req.Header.Set("Accept-Language", c.accept)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, c.want, w.Body.String())
+			assert.Equal(t, c.want, w.Header().Get("Content-Language"))
+			assert.Equal(t, []string{"Accept-Language", "Cookie"}, w.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestErrorHandler_Translated(t *testing.T) {
+	router := setupRouterLanguage()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/error", nil)
+	req.Header.Set("Accept-Language", "en")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"space_not_found","error":"Space not found"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/error", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"space_not_found","error":"スペースが見つかりません"}`, w.Body.String())
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"chat/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ルートごとのリクエスト数と処理時間を記録する
+// ラベルにはパスではなくルートのパターンを使う（存在しないパスは "unmatched" にまとめる）。
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := ctx.Request.Method
+		m.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		m.HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"chat/apperrors"
+	"chat/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errRateLimited = apperrors.New(apperrors.ErrRateLimited, "rate_limited", "リクエストが多すぎます。しばらくしてから再試行してください")
+
+// トークンバケットによるレート制限。認証済みならユーザー単位、未認証ならクライアント IP 単位。
+// 超過した場合は 429 と Retry-After（秒）を返す。
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		allowed, retryAfter := limiter.Allow(rateLimitKey(ctx))
+		if !allowed {
+			ctx.Error(errRateLimited.WithRetryAfter(retryAfter))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func rateLimitKey(ctx *gin.Context) string {
+	if username := Username(ctx); username != "" {
+		return "user:" + username
+	}
+	return "ip:" + ctx.ClientIP()
+}
@@ -0,0 +1,112 @@
+package middlewares_test
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/logging"
+	"chat/middlewares"
+	"chat/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// "token-<name>" を <name> として認証するテスト用 TokenVerifier
+type stubVerifier struct{}
+
+var errInvalidToken = apperrors.New(apperrors.ErrUnauthorized, "invalid_token", "トークンが無効です")
+
+func (stubVerifier) VerifyToken(token string) (string, error) {
+	if len(token) > 6 && token[:6] == "token-" {
+		return token[6:], nil
+	}
+	return "", errInvalidToken
+}
+
+func setupRouterRateLimit() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.Use(middlewares.Authenticate(stubVerifier{}))
+	limiter := ratelimit.New(config.RatePolicy{Requests: 2, Per: time.Minute})
+	router.GET("/limited", middlewares.RateLimit(limiter), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	router.GET("/private", middlewares.RequireAuth(), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, middlewares.Username(ctx))
+	})
+	return router
+}
+
+func doRequest(router *gin.Engine, remoteAddr, token string) *httptest.ResponseRecorder {
+	return doRequestPath(router, "/limited", remoteAddr, token)
+}
+
+func doRequestPath(router *gin.Engine, path, remoteAddr, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.RemoteAddr = remoteAddr
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_PerIP(t *testing.T) {
+	router := setupRouterRateLimit()
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1235", "").Code)
+
+	w := doRequest(router, "10.0.0.1:1236", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+
+	// 別の IP は制限されない
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.2:1234", "").Code)
+}
+
+func TestRateLimit_PerUser(t *testing.T) {
+	router := setupRouterRateLimit()
+
+	// 同じ IP でもユーザーごとに数える
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1234", "token-alice").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1234", "token-alice").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(router, "10.0.0.1:1234", "token-alice").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1234", "token-bob").Code)
+}
+
+// 不正・期限切れのトークンは未認証として通し、ログインが必要なルートだけがその理由で 401 を返す
+func TestAuthenticate_InvalidToken(t *testing.T) {
+	router := setupRouterRateLimit()
+
+	w := doRequest(router, "10.0.0.1:1234", "invalid")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequestPath(router, "/private", "10.0.0.1:1234", "invalid")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_token"`)
+
+	w = doRequestPath(router, "/private", "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"auth_required"`)
+
+	w = doRequestPath(router, "/private", "10.0.0.1:1234", "token-alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+}
+
+// 不正なトークンのリクエストはユーザーではなく IP ごとに数える
+func TestRateLimit_InvalidTokenPerIP(t *testing.T) {
+	router := setupRouterRateLimit()
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1234", "invalid").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(router, "10.0.0.1:1234", "expired").Code)
+}
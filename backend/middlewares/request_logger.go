@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"chat/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// クライアントから受け取る X-Request-ID として受け付ける形式
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// リクエスト ID を決めてコンテキストとレスポンスヘッダーに付け、完了時にアクセスログを出力する
+// 上流（ロードバランサーなど）が X-Request-ID を付けていればそれを引き継ぐ。
+// クエリ文字列にはトークンが含まれうるため、パスのみ記録する。
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		requestID := ctx.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = logging.NewID()
+		}
+		ctx.Header("X-Request-ID", requestID)
+		ctx.Request = ctx.Request.WithContext(
+			logging.WithAttrs(ctx.Request.Context(), slog.String("request_id", requestID)))
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(ctx.Request.Context(), level, "リクエスト",
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.String("user", Username(ctx)),
+		)
+	}
+}
+
+// panic を 500 にし、スタックトレースを含めて記録する
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, err any) {
+		logger.ErrorContext(ctx.Request.Context(), "panic が発生しました",
+			"method", ctx.Request.Method, "route", ctx.FullPath(), "error", err, "stack", string(debug.Stack()))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,101 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"chat/config"
+	"chat/logging"
+	"chat/middlewares"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouterRequestLogger(buf *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logging.New(config.LogConfig{Level: "info", Format: "json"}, buf)
+
+	router := gin.New()
+	router.Use(middlewares.RequestLogger(logger))
+	router.Use(middlewares.Recovery(logger))
+	router.GET("/hello", func(ctx *gin.Context) {
+		logger.InfoContext(ctx.Request.Context(), "ハンドラー")
+		ctx.String(http.StatusOK, "ok")
+	})
+	router.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+	return router
+}
+
+// JSON ログを1行ずつ読む
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal([]byte(raw), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestLogger_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupRouterRequestLogger(&buf)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hello?token=secret-token", nil)
+	router.ServeHTTP(w, req)
+
+	requestID := w.Header().Get("X-Request-ID")
+	assert.NotEmpty(t, requestID)
+
+	lines := logLines(t, &buf)
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, requestID, line["request_id"])
+	}
+	assert.Equal(t, "/hello", lines[1]["path"])
+	assert.EqualValues(t, http.StatusOK, lines[1]["status"])
+	assert.NotContains(t, buf.String(), "secret-token")
+}
+
+func TestRequestLogger_PropagatesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupRouterRequestLogger(&buf)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	req.Header.Set("X-Request-ID", "upstream-123")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "upstream-123", w.Header().Get("X-Request-ID"))
+
+	// 不正な形式の ID は引き継がない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/hello", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	router.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\n", w.Header().Get("X-Request-ID"))
+}
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupRouterRequestLogger(&buf)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	lines := logLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "panic が発生しました", lines[0]["msg"])
+	assert.Equal(t, lines[0]["request_id"], lines[1]["request_id"])
+	assert.Equal(t, "ERROR", lines[1]["level"])
+}
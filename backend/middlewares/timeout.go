@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストのコンテキストに処理時間の上限を設定する
+// サービス・リポジトリはこのコンテキストでクエリを実行するため、上限を超えると DB の処理も打ち切られる。
+// WebSocket と streamRoutes のルート（SSE）は接続が長時間続くため対象外。
+// ルートで判定するため、通常の API に Accept: text/event-stream を付けても上限は外れない。
+func Timeout(d time.Duration, streamRoutes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.IsWebsocket() || slices.Contains(streamRoutes, ctx.FullPath()) {
+			ctx.Next()
+			return
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), d)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+		ctx.Next()
+	}
+}
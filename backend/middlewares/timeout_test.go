@@ -0,0 +1,60 @@
+package middlewares_test
+
+import (
+	"chat/middlewares"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.Timeout(20*time.Millisecond, "/events/:id"))
+	// DB の処理の代わりにコンテキストの終了を待つ
+	router.GET("/slow", func(ctx *gin.Context) {
+		select {
+		case <-ctx.Request.Context().Done():
+			ctx.String(http.StatusGatewayTimeout, ctx.Request.Context().Err().Error())
+		case <-time.After(time.Second):
+			ctx.String(http.StatusOK, "done")
+		}
+	})
+	noDeadline := func(ctx *gin.Context) {
+		_, hasDeadline := ctx.Request.Context().Deadline()
+		assert.False(t, hasDeadline, "WebSocket・SSE には上限を設けない")
+		ctx.Status(http.StatusOK)
+	}
+	router.GET("/ws", noDeadline)
+	router.GET("/events/:id", noDeadline)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), w.Body.String())
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/events/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Accept ヘッダーでは上限を外せない
+	req = httptest.NewRequest("GET", "/slow", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// リクエストごとのスパンを記録する（W3C traceparent ヘッダーがあればその子スパンにする）
+//
+// skip に前方一致するパスと、skip にルート（"/api/v2/spaces/:spaceId/events" など）が一致する
+// リクエストは記録しない。SSE のストリームはルートで除外する。WebSocket は接続が長時間続くため、
+// 接続ではなくメッセージごとにコントローラーでスパンを作る。
+func Tracing(serviceName string, skip ...string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName,
+		otelgin.WithFilter(func(r *http.Request) bool {
+			for _, prefix := range skip {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					return false
+				}
+			}
+			return true
+		}),
+		otelgin.WithGinFilter(func(ctx *gin.Context) bool {
+			route := ctx.FullPath()
+			for _, s := range skip {
+				if route == s {
+					return false
+				}
+			}
+			return true
+		}),
+	)
+}
@@ -0,0 +1,43 @@
+package middlewares_test
+
+import (
+	"chat/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// 前方一致するパスと一致するルートは記録しない。Accept ヘッダーでは除外されない
+func TestTracing_Skip(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.Tracing("test", "/health", "/spaces/:spaceId/events"))
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router.GET("/health/live", ok)
+	router.GET("/spaces/:spaceId/events", ok)
+	router.GET("/spaces/:spaceId", ok)
+
+	for _, path := range []string{"/health/live", "/spaces/1/events"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	assert.Empty(t, recorder.Ended())
+
+	req := httptest.NewRequest("GET", "/spaces/1", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "/spaces/:spaceId", spans[0].Name())
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"chat/migrations"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// `migrate` サブコマンド
+//
+//	./main migrate up        未適用のマイグレーションをすべて適用
+//	./main migrate down [n]  直近 n 件（省略時 1 件）をロールバック
+//	./main migrate status    適用状況を表示
+func runMigrate(ctx context.Context, db *gorm.DB, args []string, logger *slog.Logger) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			logger.Info("マイグレーションを適用しました", "version", m.Version, "name", m.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("ロールバック件数が不正です: %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			logger.Info("マイグレーションをロールバックしました", "version", m.Version, "name", m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "未適用"
+			if s.Applied {
+				state = "適用済み " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("不明な migrate コマンドです: %q (up | down [n] | status)", cmd)
+	}
+}
+
+// 起動時の自動マイグレーション
+func migrateOnStart(ctx context.Context, db *gorm.DB, logger *slog.Logger) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		logger.Info("マイグレーションを適用しました", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+func newMigrator(db *gorm.DB) (*migrations.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB)
+}
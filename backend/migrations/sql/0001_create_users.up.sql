@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+    username TEXT NOT NULL,
+    password TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (username);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS spaces (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
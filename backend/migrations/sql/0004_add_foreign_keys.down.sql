@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_messages_user_id;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS fk_messages_user;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS fk_messages_space;
+
+-- up で移したメッセージを戻す
+INSERT INTO messages SELECT * FROM orphaned_messages ON CONFLICT (id) DO NOTHING;
+DROP TABLE IF EXISTS orphaned_messages;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS user_id;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_pkey;
+ALTER TABLE users DROP COLUMN IF EXISTS id;
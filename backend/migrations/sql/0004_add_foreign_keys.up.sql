@@ -0,0 +1,30 @@
+-- ユーザーに数値の主キーを追加（既存行には連番が振られる）
+ALTER TABLE users ADD COLUMN IF NOT EXISTS id SERIAL;
+ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+-- メッセージの投稿者（匿名・ボット投稿は NULL）
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS user_id INTEGER;
+UPDATE messages m SET user_id = u.id FROM users u WHERE m.username = u.username AND m.user_id IS NULL;
+
+-- 存在しないスペースを指すメッセージは制約を付けられないため、削除せずに orphaned_messages へ移す
+-- （内容を確認してから手動で削除・復元する。down で messages に戻す）
+CREATE TABLE IF NOT EXISTS orphaned_messages (LIKE messages INCLUDING DEFAULTS);
+INSERT INTO orphaned_messages SELECT * FROM messages WHERE space_id NOT IN (SELECT id FROM spaces);
+DELETE FROM messages WHERE id IN (SELECT id FROM orphaned_messages);
+
+DO $$
+DECLARE
+    moved BIGINT;
+BEGIN
+    SELECT count(*) INTO moved FROM orphaned_messages;
+    IF moved > 0 THEN
+        RAISE WARNING '存在しないスペースのメッセージ % 件を orphaned_messages に移しました', moved;
+    END IF;
+END $$;
+
+ALTER TABLE messages
+    ADD CONSTRAINT fk_messages_space FOREIGN KEY (space_id) REFERENCES spaces (id) ON DELETE CASCADE;
+ALTER TABLE messages
+    ADD CONSTRAINT fk_messages_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages (user_id);
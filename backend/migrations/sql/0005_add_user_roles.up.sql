@@ -0,0 +1,2 @@
+-- 管理者は UPDATE users SET role = 'admin' WHERE username = '...' で設定する
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member';
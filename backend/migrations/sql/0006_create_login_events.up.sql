@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS login_events (
+    id         SERIAL PRIMARY KEY,
+    event      TEXT NOT NULL,
+    username   TEXT NOT NULL,
+    ip         TEXT NOT NULL,
+    actor      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_username_created_at ON login_events (username, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_ip_created_at ON login_events (ip, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE spaces DROP CONSTRAINT IF EXISTS fk_spaces_owner;
+ALTER TABLE spaces DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,4 @@
+-- スペースの作成者（Webhook などの管理権限を持つ）。未ログインで作成された既存のスペースは NULL
+ALTER TABLE spaces ADD COLUMN IF NOT EXISTS owner_id INTEGER;
+ALTER TABLE spaces
+    ADD CONSTRAINT fk_spaces_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,14 @@
+-- 外部のシステム（CI・監視）がユーザーなしで投稿したメッセージ
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS bot BOOLEAN NOT NULL DEFAULT false;
+
+-- スペースにメッセージを投稿するための受信用 Webhook（トークンはハッシュのみ保存する）
+CREATE TABLE IF NOT EXISTS incoming_webhooks (
+    id         SERIAL PRIMARY KEY,
+    space_id   INTEGER NOT NULL REFERENCES spaces (id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    created_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_webhooks_space_id ON incoming_webhooks (space_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bots;
+ALTER TABLE spaces DROP COLUMN IF EXISTS topic;
@@ -0,0 +1,13 @@
+-- スペースのトピック（/topic で変更する）
+ALTER TABLE spaces ADD COLUMN IF NOT EXISTS topic TEXT NOT NULL DEFAULT '';
+
+-- スラッシュコマンドに HTTP コールバックで応答するボット（コマンド名は全スペースで共通）
+CREATE TABLE IF NOT EXISTS bots (
+    id           SERIAL PRIMARY KEY,
+    name         TEXT NOT NULL,
+    command      TEXT NOT NULL UNIQUE,
+    callback_url TEXT NOT NULL,
+    secret       TEXT NOT NULL,
+    created_by   INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
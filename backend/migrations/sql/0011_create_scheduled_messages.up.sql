@@ -0,0 +1,21 @@
+-- 指定した日時に投稿するメッセージ・リマインダー（送信待ちのキューを兼ねる）
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id              SERIAL PRIMARY KEY,
+    space_id        INTEGER NOT NULL REFERENCES spaces (id) ON DELETE CASCADE,
+    user_id         INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    username        TEXT NOT NULL,
+    kind            TEXT NOT NULL DEFAULT 'message',
+    text            TEXT NOT NULL,
+    send_at         TIMESTAMPTZ NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    message_id      INTEGER REFERENCES messages (id) ON DELETE SET NULL,
+    error           TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at         TIMESTAMPTZ
+);
+
+-- 送信時刻になった予約の取り出し用
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_space_user ON scheduled_messages (space_id, user_id);
@@ -0,0 +1,10 @@
+-- ユーザーが後で見返すために保存したメッセージ（メッセージを削除すると保存も削除される）
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    message_id INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_message_id ON bookmarks (message_id);
@@ -0,0 +1,2 @@
+-- 本文の Markdown を変換したサニタイズ済みの HTML（この列の追加前のメッセージは空）
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS html TEXT NOT NULL DEFAULT '';
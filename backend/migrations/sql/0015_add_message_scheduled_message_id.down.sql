@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_scheduled_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS scheduled_message_id;
@@ -0,0 +1,9 @@
+-- 予約から投稿したメッセージの予約 ID
+-- 一意にし、送信済みの記録の前に lease が切れて再送されても同じ予約から二重に投稿しないようにする
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS scheduled_message_id INTEGER REFERENCES scheduled_messages (id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_scheduled_message_id ON messages (scheduled_message_id);
+
+-- 送信済みの予約から投稿したメッセージにも設定する
+UPDATE messages SET scheduled_message_id = s.id
+FROM scheduled_messages s
+WHERE s.message_id = messages.id;
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ユーザーが保存したメッセージ（メッセージの削除で外部キーにより削除される）
+type Bookmark struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	MessageID int       `json:"message_id"`
+	Message   *Message  `json:"message,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// スラッシュコマンドに応答するボット
+// /<Command> が投稿されると CallbackURL に署名付きで POST し、応答を Name の名前で返す。
+type Bot struct {
+	ID int `json:"id"`
+	// 応答するメッセージの表示名
+	Name string `json:"name"`
+	// "/" を除いたコマンド名（全スペースで共通）
+	Command     string `json:"command"`
+	CallbackURL string `json:"callback_url"`
+	// 署名の鍵（登録時のレスポンスでのみ返す）
+	Secret    string    `json:"-"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
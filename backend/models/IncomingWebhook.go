@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// 外部のシステムがスペースにメッセージを投稿するための受信用 Webhook
+type IncomingWebhook struct {
+	ID      int    `json:"id"`
+	SpaceID int    `json:"space_id"`
+	Space   *Space `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	// 投稿するメッセージの表示名
+	Name string `json:"name"`
+	// トークンの SHA-256（トークンは作成時のレスポンスでのみ返す）
+	TokenHash string    `json:"-"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ログインに関する監査イベントの種類
+const (
+	LoginSucceeded  = "login_succeeded"
+	LoginFailed     = "login_failed"
+	AccountLocked   = "account_locked"
+	IPLocked        = "ip_locked"
+	AccountUnlocked = "account_unlocked"
+)
+
+// ログイン試行・ロック・ロック解除の監査ログ
+type LoginEvent struct {
+	ID       int    `json:"id"`
+	Event    string `json:"event"`
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+	// 操作した管理者（ロック解除のみ）
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
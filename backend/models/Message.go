@@ -2,10 +2,29 @@ package models
 
 import "time"
 
+// ハブから配信するピン留めの変更
+const (
+	EventMessagePinned   = "message.pinned"
+	EventMessageUnpinned = "message.unpinned"
+)
+
 type Message struct {
-	ID        int       `json:"id"`
-	SpaceID   int       `json:"space_id"`
-	Username  string    `json:"username"`
-	Text      string    `json:"text"`
-	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	ID       int    `json:"id"`
+	SpaceID  int    `json:"space_id"`
+	Space    *Space `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID   *int   `json:"user_id,omitempty"`
+	User     *User  `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+	Username string `json:"username"`
+	// 受信用 Webhook などユーザー以外からの投稿（Username は表示名）
+	Bot  bool   `json:"bot,omitempty"`
+	Text string `json:"text"`
+	// Text の Markdown を変換したサニタイズ済みの HTML（空なら Text を平文として表示する）
+	HTML string `json:"html"`
+	// 予約から投稿したメッセージの予約 ID（一意。再送しても同じ予約から二重に投稿しない）
+	ScheduledMessageID *int      `json:"-"`
+	CreatedAt          time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	// スペースにピン留めされているか（投稿時には設定しない）
+	Pinned   bool       `json:"pinned" gorm:"<-:update"`
+	PinnedBy *int       `json:"pinned_by,omitempty" gorm:"<-:update"`
+	PinnedAt *time.Time `json:"pinned_at,omitempty" gorm:"<-:update"`
 }
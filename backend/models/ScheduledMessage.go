@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// 予約の種類
+const (
+	// 予約したユーザーとして投稿する
+	ScheduledKindMessage = "message"
+	// 予約したユーザーへのメンションとしてリマインダーが投稿する
+	ScheduledKindReminder = "reminder"
+)
+
+// 予約の状態
+const (
+	ScheduledPending  = "pending"
+	ScheduledSent     = "sent"
+	ScheduledFailed   = "failed"
+	ScheduledCanceled = "canceled"
+)
+
+// 指定した日時に投稿するメッセージ（送信待ちのキューを兼ねる）
+type ScheduledMessage struct {
+	ID       int       `json:"id"`
+	SpaceID  int       `json:"space_id"`
+	Space    *Space    `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	Kind     string    `json:"kind"`
+	Text     string    `json:"text"`
+	SendAt   time.Time `json:"send_at"`
+	Status   string    `json:"status" gorm:"default:pending"`
+	Attempts int       `json:"attempts"`
+	// 次に送信する時刻（取り出し中・失敗後の再送では SendAt より後になる）
+	NextAttemptAt time.Time `json:"-"`
+	// 投稿したメッセージ（送信済みの場合）
+	MessageID *int `json:"message_id,omitempty"`
+	// 最後に失敗した理由
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
@@ -3,7 +3,12 @@ package models
 import "time"
 
 type Space struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// 作成したユーザー（未ログインで作成したスペースは nil）
+	OwnerID *int  `json:"owner_id,omitempty"`
+	Owner   *User `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+	// /topic で設定する説明（作成時は空。変更は UpdateSpaceTopic で行う）
+	Topic     string    `json:"topic,omitempty" gorm:"<-:update"`
 	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
 }
@@ -1,6 +1,14 @@
 package models
 
+// ユーザーの権限
+const (
+	RoleMember = "member"
+	RoleAdmin  = "admin"
+)
+
 type User struct {
-	Username string `json:"username"`
+	ID       int    `json:"id"`
+	Username string `json:"username" gorm:"uniqueIndex"`
 	Password string `json:"password"`
+	Role     string `json:"role" gorm:"default:member"`
 }
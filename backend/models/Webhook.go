@@ -0,0 +1,79 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook で通知するスペースのイベント
+const (
+	EventMessageCreated = "message.created"
+	EventMessageDeleted = "message.deleted"
+)
+
+// 配信の状態
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// スペースのイベントを外部の URL に通知する設定
+type Webhook struct {
+	ID      int    `json:"id"`
+	SpaceID int    `json:"space_id"`
+	Space   *Space `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	URL     string `json:"url"`
+	// 署名の鍵（作成時のレスポンスでのみ返す）
+	Secret string `json:"-"`
+	// 通知するイベント（カンマ区切りで保存する）
+	Events    string    `json:"-"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// 通知するイベントの一覧
+func (w Webhook) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// event を通知するか
+func (w Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// 1つのイベントの配信（送信待ちのキューを兼ねる）
+type WebhookDelivery struct {
+	ID        int      `json:"id"`
+	WebhookID int      `json:"webhook_id"`
+	Webhook   *Webhook `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Event     string   `json:"event"`
+	// 送信する JSON（再送でも同じ内容を送る）
+	Payload       string           `json:"-"`
+	Status        string           `json:"status" gorm:"default:pending"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	AttemptLog    []WebhookAttempt `json:"attempt_log,omitempty" gorm:"foreignKey:DeliveryID"`
+}
+
+// 送信の試行1回分の結果
+type WebhookAttempt struct {
+	ID         int `json:"id"`
+	DeliveryID int `json:"-"`
+	Attempt    int `json:"attempt"`
+	// 応答がなかった場合は nil
+	StatusCode *int      `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
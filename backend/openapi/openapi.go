@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// API 仕様（ルートを追加・変更したらこのファイルも更新する。api パッケージのテストで照合している）
+//
+//go:embed openapi.yaml
+var Spec []byte
+
+// Redoc で仕様を表示するページ（仕様は /docs/openapi.yaml から読む）
+const uiHTML = `<!DOCTYPE html>
+<html lang="ja">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Echo Talk Chat API</title>
+</head>
+<body>
+  <redoc spec-url="/docs/openapi.yaml"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// 仕様の YAML を返す
+func SpecHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		_, _ = w.Write(Spec)
+	})
+}
+
+// API ドキュメントの HTML を返す
+func UIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(uiHTML))
+	})
+}
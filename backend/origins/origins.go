@@ -0,0 +1,112 @@
+package origins
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrMissing    = errors.New("Origin ヘッダーがありません")
+	ErrMalformed  = errors.New("Origin の形式が不正です")
+	ErrNotAllowed = errors.New("許可されていないオリジンです")
+)
+
+// CORS と WebSocket で共有する、許可するオリジンの一覧
+//
+// "https://app.example.com" のような完全一致と、"https://*.example.com" のような
+// サブドメインのワイルドカードを指定できる（ワイルドカードは example.com 自体には一致しない）。
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	scheme string
+	// ワイルドカードの場合は ".example.com" のようにドットから始まる
+	host     string
+	port     string
+	wildcard bool
+}
+
+func New(allowOrigins []string) (*Matcher, error) {
+	var errs []error
+	m := &Matcher{}
+	for _, origin := range allowOrigins {
+		p, err := parsePattern(origin)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// 検証済みの設定から生成する（不正な値が含まれていれば panic）
+func MustNew(allowOrigins []string) *Matcher {
+	m, err := New(allowOrigins)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// オリジンが許可されていれば nil、そうでなければ拒否の理由を返す
+func (m *Matcher) Check(origin string) error {
+	if origin == "" {
+		return ErrMissing
+	}
+	scheme, host, port, err := split(origin)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrMalformed, origin)
+	}
+	for _, p := range m.patterns {
+		if p.matches(scheme, host, port) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrNotAllowed, origin)
+}
+
+// オリジンが許可されているか（gin-contrib/cors の AllowOriginFunc 用）
+func (m *Matcher) Allowed(origin string) bool {
+	return m.Check(origin) == nil
+}
+
+func (p pattern) matches(scheme, host, port string) bool {
+	if scheme != p.scheme || port != p.port {
+		return false
+	}
+	if p.wildcard {
+		return strings.HasSuffix(host, p.host) && len(host) > len(p.host)
+	}
+	return host == p.host
+}
+
+func parsePattern(origin string) (pattern, error) {
+	wildcard := strings.Contains(origin, "://*.")
+	scheme, host, port, err := split(strings.Replace(origin, "://*.", "://", 1))
+	if err != nil || strings.Contains(host, "*") {
+		return pattern{}, fmt.Errorf("CORS_ALLOW_ORIGINS に不正なオリジンがあります: %q", origin)
+	}
+	if wildcard {
+		host = "." + host
+	}
+	return pattern{scheme: scheme, host: host, port: port, wildcard: wildcard}, nil
+}
+
+// "scheme://host[:port]" を分解する（パスやクエリを含むものは不正）
+func split(origin string) (scheme, host, port string, err error) {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme == "" || u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", "", "", ErrMalformed
+	}
+	return strings.ToLower(u.Scheme), strings.ToLower(u.Hostname()), u.Port(), nil
+}
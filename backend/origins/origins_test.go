@@ -0,0 +1,60 @@
+package origins_test
+
+import (
+	"chat/origins"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Check(t *testing.T) {
+	m, err := origins.New([]string{"http://localhost:3000", "https://*.echo-talk.com"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		origin string
+		want   error
+	}{
+		{"http://localhost:3000", nil},
+		{"HTTP://LOCALHOST:3000", nil},
+		{"https://www.echo-talk.com", nil},
+		{"https://a.b.echo-talk.com", nil},
+		{"http://localhost:3001", origins.ErrNotAllowed},
+		{"https://localhost:3000", origins.ErrNotAllowed},
+		// ワイルドカードはドメイン自体には一致しない
+		{"https://echo-talk.com", origins.ErrNotAllowed},
+		{"https://evil-echo-talk.com", origins.ErrNotAllowed},
+		{"https://www.echo-talk.com.evil.com", origins.ErrNotAllowed},
+		{"http://www.echo-talk.com", origins.ErrNotAllowed},
+		{"https://www.echo-talk.com:8443", origins.ErrNotAllowed},
+		{"", origins.ErrMissing},
+		{"null", origins.ErrMalformed},
+		{"https://www.echo-talk.com/path", origins.ErrMalformed},
+	}
+	for _, c := range cases {
+		t.Run(c.origin, func(t *testing.T) {
+			err := m.Check(c.origin)
+			if c.want == nil {
+				assert.NoError(t, err)
+				assert.True(t, m.Allowed(c.origin))
+			} else {
+				assert.ErrorIs(t, err, c.want)
+				assert.False(t, m.Allowed(c.origin))
+			}
+		})
+	}
+}
+
+func TestNew_InvalidPatterns(t *testing.T) {
+	_, err := origins.New([]string{"localhost:3000"})
+	assert.Error(t, err)
+
+	_, err = origins.New([]string{"*"})
+	assert.Error(t, err)
+
+	_, err = origins.New([]string{"https://app.*.example.com"})
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { origins.MustNew([]string{"https://*"}) })
+}
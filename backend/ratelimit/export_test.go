@@ -0,0 +1,8 @@
+package ratelimit
+
+import "time"
+
+// テストから時計を差し替える
+func (l *Limiter) SetClock(now func() time.Time) {
+	l.now = now
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"chat/config"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// キー（ユーザーや IP）ごとにトークンバケットを持つレート制限
+type Limiter struct {
+	limit rate.Limit
+	burst int
+	// この時間使われなかったバケットは満杯に戻っているので破棄してよい
+	idleTTL time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func New(policy config.RatePolicy) *Limiter {
+	return &Limiter{
+		limit:   Limit(policy),
+		burst:   policy.Requests,
+		idleTTL: policy.Per,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// ポリシーを rate.Limit（1秒あたりの回数）に変換する
+func Limit(policy config.RatePolicy) rate.Limit {
+	return rate.Limit(float64(policy.Requests) / policy.Per.Seconds())
+}
+
+// 1つの接続など、キーを持たない用途のトークンバケット
+func NewBucket(policy config.RatePolicy) *rate.Limiter {
+	return rate.NewLimiter(Limit(policy), policy.Requests)
+}
+
+// key のリクエストを1回分消費する。許可されない場合は再試行までの待ち時間を返す
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, l.idleTTL
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// 使われていないバケットを定期的に破棄する
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// 保持しているバケット数（テスト・監視用）
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,57 @@
+package ratelimit_test
+
+import (
+	"chat/config"
+	"chat/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.New(config.RatePolicy{Requests: 3, Per: 3 * time.Second})
+	limiter.SetClock(func() time.Time { return now })
+
+	// バースト分は即座に許可される
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow("ip:1.2.3.4")
+		assert.True(t, ok, "request %d", i)
+	}
+
+	// 超過すると再試行までの時間が返る
+	ok, retryAfter := limiter.Allow("ip:1.2.3.4")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// 別のキーは影響を受けない
+	ok, _ = limiter.Allow("user:alice")
+	assert.True(t, ok)
+
+	// 時間が経つとトークンが補充される
+	now = now.Add(time.Second)
+	ok, _ = limiter.Allow("ip:1.2.3.4")
+	assert.True(t, ok)
+}
+
+func TestLimiter_SweepsIdleBuckets(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.New(config.RatePolicy{Requests: 1, Per: time.Minute})
+	limiter.SetClock(func() time.Time { return now })
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+	assert.Equal(t, 2, limiter.Len())
+
+	now = now.Add(2 * time.Minute)
+	limiter.Allow("c")
+	assert.Equal(t, 1, limiter.Len())
+}
+
+func TestNewBucket(t *testing.T) {
+	bucket := ratelimit.NewBucket(config.RatePolicy{Requests: 2, Per: time.Minute})
+	assert.True(t, bucket.Allow())
+	assert.True(t, bucket.Allow())
+	assert.False(t, bucket.Allow())
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type bookmarkRepository struct {
+	DB *gorm.DB
+}
+
+func NewBookmarkRepository(db *gorm.DB) BookmarkRepository {
+	return &bookmarkRepository{DB: db}
+}
+
+// メッセージを保存する（保存済みなら何もしない）
+func (repo *bookmarkRepository) CreateBookmark(ctx context.Context, userID, messageID int) error {
+	bookmark := models.Bookmark{UserID: userID, MessageID: messageID}
+	err := repo.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "message_id"}},
+		DoNothing: true,
+	}).Create(&bookmark).Error
+	return translateError(ctx, err)
+}
+
+// 保存を取り消す
+func (repo *bookmarkRepository) DeleteBookmark(ctx context.Context, userID, messageID int) error {
+	result := repo.DB.WithContext(ctx).Delete(&models.Bookmark{}, "user_id = ? AND message_id = ?", userID, messageID)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: 保存したメッセージが見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// ユーザーが保存したメッセージを新しく保存した順に最大 limit 件取得（beforeID が 0 でなければその ID より前）
+func (repo *bookmarkRepository) ListBookmarks(ctx context.Context, userID, beforeID, limit int) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
+	query := repo.DB.WithContext(ctx).Preload("Message").Where("user_id = ?", userID)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&bookmarks).Error
+	return bookmarks, translateError(ctx, err)
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+)
+
+type BookmarkRepository interface {
+	CreateBookmark(ctx context.Context, userID, messageID int) error
+	DeleteBookmark(ctx context.Context, userID, messageID int) error
+	ListBookmarks(ctx context.Context, userID, beforeID, limit int) ([]models.Bookmark, error)
+}
@@ -0,0 +1,88 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat/apperrors"
+	"chat/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockBookmarkDB(t *testing.T) (repositories.BookmarkRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: mockDB,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm DB with sqlmock: %v", err)
+	}
+
+	return repositories.NewBookmarkRepository(gormDB), mock
+}
+
+// 保存済みのメッセージをもう一度保存してもエラーにしない
+func TestCreateBookmark(t *testing.T) {
+	repo, mock := setupMockBookmarkDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "bookmarks" \("user_id","message_id"\) VALUES \(\$1,\$2\) ON CONFLICT \("user_id","message_id"\) DO NOTHING RETURNING "created_at","id"`).
+		WithArgs(7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.CreateBookmark(context.Background(), 7, 10))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteBookmark_NotFound(t *testing.T) {
+	repo, mock := setupMockBookmarkDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "bookmarks" WHERE user_id = \$1 AND message_id = \$2`).
+		WithArgs(7, 10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteBookmark(context.Background(), 7, 10)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 保存したメッセージは全スペース分をまとめて、カーソル（ID）より前を取得する
+func TestListBookmarks(t *testing.T) {
+	repo, mock := setupMockBookmarkDB(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT \* FROM "bookmarks" WHERE user_id = \$1 AND id < \$2 ORDER BY id DESC LIMIT \$3`).
+		WithArgs(7, 30, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "message_id", "created_at"}).
+			AddRow(21, 7, 10, now).
+			AddRow(20, 7, 11, now))
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE "messages"\."id" IN \(\$1,\$2\)`).
+		WithArgs(10, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "username", "text"}).
+			AddRow(10, 1, "alice", "議事録").
+			AddRow(11, 2, "bob", "手順書"))
+
+	bookmarks, err := repo.ListBookmarks(context.Background(), 7, 30, 2)
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+	assert.Equal(t, "議事録", bookmarks[0].Message.Text)
+	assert.Equal(t, 2, bookmarks[1].Message.SpaceID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
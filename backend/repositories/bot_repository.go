@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type botRepository struct {
+	DB *gorm.DB
+}
+
+func NewBotRepository(db *gorm.DB) BotRepository {
+	return &botRepository{DB: db}
+}
+
+// ボットを登録し、ID と作成日時を設定して返す（コマンド名の重複は ErrConflict）
+func (repo *botRepository) CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error) {
+	err := repo.DB.WithContext(ctx).Create(&bot).Error
+	return bot, translateError(ctx, err)
+}
+
+// ボット一覧を取得
+func (repo *botRepository) ListBots(ctx context.Context) ([]models.Bot, error) {
+	var bots []models.Bot
+	err := repo.DB.WithContext(ctx).Order("command ASC").Find(&bots).Error
+	return bots, translateError(ctx, err)
+}
+
+// コマンド名でボットを取得（コマンドの実行時）
+func (repo *botRepository) GetBotByCommand(ctx context.Context, command string) (models.Bot, error) {
+	var bot models.Bot
+	err := repo.DB.WithContext(ctx).Where("command = ?", command).First(&bot).Error
+	return bot, translateError(ctx, err)
+}
+
+// ボットを削除する
+func (repo *botRepository) DeleteBot(ctx context.Context, id int) error {
+	result := repo.DB.WithContext(ctx).Delete(&models.Bot{}, id)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: ボットが見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
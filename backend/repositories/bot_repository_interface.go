@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+)
+
+type BotRepository interface {
+	CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error)
+	ListBots(ctx context.Context) ([]models.Bot, error)
+	GetBotByCommand(ctx context.Context, command string) (models.Bot, error)
+	DeleteBot(ctx context.Context, id int) error
+}
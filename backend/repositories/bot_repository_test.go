@@ -0,0 +1,79 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockBotDB(t *testing.T) (repositories.BotRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: mockDB,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm DB with sqlmock: %v", err)
+	}
+
+	return repositories.NewBotRepository(gormDB), mock
+}
+
+func TestCreateBot(t *testing.T) {
+	repo, mock := setupMockBotDB(t)
+
+	createdBy := 1
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "bots" \("name","command","callback_url","secret","created_by"\) VALUES \(\$1,\$2,\$3,\$4,\$5\) RETURNING "created_at","id"`).
+		WithArgs("Deploy", "deploy", "https://ci.example.com/hook", "secret", createdBy).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).AddRow(time.Now(), 3))
+	mock.ExpectCommit()
+
+	bot, err := repo.CreateBot(context.Background(), models.Bot{Name: "Deploy", Command: "deploy", CallbackURL: "https://ci.example.com/hook", Secret: "secret", CreatedBy: &createdBy})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, bot.ID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBotByCommand_NotFound(t *testing.T) {
+	repo, mock := setupMockBotDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "bots" WHERE command = \$1 ORDER BY "bots"."id" LIMIT \$2`).
+		WithArgs("deploy", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.GetBotByCommand(context.Background(), "deploy")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteBot_NotFound(t *testing.T) {
+	repo, mock := setupMockBotDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "bots" WHERE "bots"."id" = \$1`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteBot(context.Background(), 3)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
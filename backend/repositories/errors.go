@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"chat/apperrors"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// PostgreSQL のエラーコード
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// DB のエラーを apperrors の種類に変換する（元のエラーも errors.Is で辿れる）
+//
+// ctx が終了していれば、ドライバーのエラーの形にかかわらずタイムアウト・切断によるものとして扱う。
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctxErr := ctx.Err(); {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w: %w", apperrors.ErrTimeout, ctxErr, err)
+	case errors.Is(ctxErr, context.Canceled):
+		return fmt.Errorf("%w: %w: %w", apperrors.ErrCanceled, ctxErr, err)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %w", apperrors.ErrNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: %w", apperrors.ErrConflict, err)
+		case pgForeignKeyViolation:
+			// 参照先（スペースやユーザー）が存在しない
+			return fmt.Errorf("%w: %w", apperrors.ErrNotFound, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type incomingWebhookRepository struct {
+	DB *gorm.DB
+}
+
+func NewIncomingWebhookRepository(db *gorm.DB) IncomingWebhookRepository {
+	return &incomingWebhookRepository{DB: db}
+}
+
+// 受信用 Webhook を登録し、ID と作成日時を設定して返す
+func (repo *incomingWebhookRepository) CreateIncomingWebhook(ctx context.Context, hook models.IncomingWebhook) (models.IncomingWebhook, error) {
+	err := repo.DB.WithContext(ctx).Create(&hook).Error
+	return hook, translateError(ctx, err)
+}
+
+// スペースの受信用 Webhook 一覧を取得
+func (repo *incomingWebhookRepository) ListIncomingWebhooks(ctx context.Context, spaceID int) ([]models.IncomingWebhook, error) {
+	var hooks []models.IncomingWebhook
+	err := repo.DB.WithContext(ctx).Where("space_id = ?", spaceID).Order("id ASC").Find(&hooks).Error
+	return hooks, translateError(ctx, err)
+}
+
+// ID で受信用 Webhook を取得（投稿の受け付け時）
+func (repo *incomingWebhookRepository) GetIncomingWebhook(ctx context.Context, id int) (models.IncomingWebhook, error) {
+	var hook models.IncomingWebhook
+	err := repo.DB.WithContext(ctx).First(&hook, id).Error
+	return hook, translateError(ctx, err)
+}
+
+// 受信用 Webhook を削除する（以後そのトークンでは投稿できない）
+func (repo *incomingWebhookRepository) DeleteIncomingWebhook(ctx context.Context, id, spaceID int) error {
+	result := repo.DB.WithContext(ctx).Delete(&models.IncomingWebhook{}, "id = ? AND space_id = ?", id, spaceID)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: 受信用 Webhook が見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+)
+
+type IncomingWebhookRepository interface {
+	CreateIncomingWebhook(ctx context.Context, hook models.IncomingWebhook) (models.IncomingWebhook, error)
+	ListIncomingWebhooks(ctx context.Context, spaceID int) ([]models.IncomingWebhook, error)
+	GetIncomingWebhook(ctx context.Context, id int) (models.IncomingWebhook, error)
+	DeleteIncomingWebhook(ctx context.Context, id, spaceID int) error
+}
@@ -0,0 +1,78 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockIncomingWebhookDB(t *testing.T) (repositories.IncomingWebhookRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: mockDB,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm DB with sqlmock: %v", err)
+	}
+
+	return repositories.NewIncomingWebhookRepository(gormDB), mock
+}
+
+func TestCreateIncomingWebhook(t *testing.T) {
+	repo, mock := setupMockIncomingWebhookDB(t)
+
+	createdBy := 7
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "incoming_webhooks" \("space_id","name","token_hash","created_by"\) VALUES \(\$1,\$2,\$3,\$4\) RETURNING "created_at","id"`).
+		WithArgs(1, "CI", "hash", createdBy).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).AddRow(time.Now(), 2))
+	mock.ExpectCommit()
+
+	hook, err := repo.CreateIncomingWebhook(context.Background(), models.IncomingWebhook{SpaceID: 1, Name: "CI", TokenHash: "hash", CreatedBy: &createdBy})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, hook.ID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIncomingWebhook_NotFound(t *testing.T) {
+	repo, mock := setupMockIncomingWebhookDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "incoming_webhooks" WHERE "incoming_webhooks"."id" = \$1 ORDER BY "incoming_webhooks"."id" LIMIT \$2`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.GetIncomingWebhook(context.Background(), 2)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteIncomingWebhook(t *testing.T) {
+	repo, mock := setupMockIncomingWebhookDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "incoming_webhooks" WHERE id = \$1 AND space_id = \$2`).
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.DeleteIncomingWebhook(context.Background(), 2, 1))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
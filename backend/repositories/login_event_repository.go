@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type loginEventRepository struct {
+	DB *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) LoginEventRepository {
+	return &loginEventRepository{DB: db}
+}
+
+// 監査イベントを記録
+func (repo *loginEventRepository) CreateLoginEvent(ctx context.Context, event models.LoginEvent) error {
+	return translateError(ctx, repo.DB.WithContext(ctx).Create(&event).Error)
+}
@@ -0,0 +1,10 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+)
+
+type LoginEventRepository interface {
+	CreateLoginEvent(ctx context.Context, event models.LoginEvent) error
+}
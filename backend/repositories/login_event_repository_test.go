@@ -0,0 +1,32 @@
+package repositories_test
+
+import (
+	"chat/models"
+	"chat/repositories"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestCreateLoginEvent(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{})
+	assert.NoError(t, err)
+	repo := repositories.NewLoginEventRepository(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "login_events" ("event","username","ip","actor") VALUES ($1,$2,$3,$4) RETURNING "created_at","id"`)).
+		WithArgs(models.LoginFailed, "alice", "10.0.0.1", "").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).AddRow(nil, 1))
+	mock.ExpectCommit()
+
+	err = repo.CreateLoginEvent(context.Background(), models.LoginEvent{Event: models.LoginFailed, Username: "alice", IP: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,9 +1,11 @@
 package repositories
 
 import (
+	"chat/apperrors"
 	"chat/models"
-	"errors"
+	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -17,31 +19,73 @@ func NewMessageRepository(db *gorm.DB) MessageRepository {
 }
 
 // メッセージを作成し、新しいIDを返す
-func (repo *messageRepository) CreateMessage(msg models.Message) (int, error) {
-	result := repo.db.Create(&msg)
-	if result.Error != nil {
-		fmt.Println("DBエラー:", result.Error)
-		return 0, result.Error
+func (repo *messageRepository) CreateMessage(ctx context.Context, msg models.Message) (int, error) {
+	if err := repo.db.WithContext(ctx).Create(&msg).Error; err != nil {
+		return 0, translateError(ctx, err)
 	}
 	return msg.ID, nil
 }
 
 // 指定された spaceId のメッセージ一覧を取得
-func (repo *messageRepository) GetMessages(spaceId int) ([]models.Message, error) {
+func (repo *messageRepository) GetMessages(ctx context.Context, spaceId int) ([]models.Message, error) {
+	var messages []models.Message
+	err := repo.db.WithContext(ctx).Where("space_id = ?", spaceId).Order("created_at ASC").Find(&messages).Error
+	return messages, translateError(ctx, err)
+}
+
+// 指定した ID より後のメッセージを ID 順に最大 limit 件取得（SSE の再送用）
+func (repo *messageRepository) GetMessagesAfter(ctx context.Context, spaceID, afterID, limit int) ([]models.Message, error) {
 	var messages []models.Message
-	err := repo.db.Where("space_id = ?", spaceId).Order("created_at ASC").Find(&messages).Error
-	return messages, err
+	err := repo.db.WithContext(ctx).Where("space_id = ? AND id > ?", spaceID, afterID).Order("id ASC").Limit(limit).Find(&messages).Error
+	return messages, translateError(ctx, err)
+}
+
+// スペース内のメッセージを ID で取得
+func (repo *messageRepository) GetMessage(ctx context.Context, messageID, spaceID int) (models.Message, error) {
+	var msg models.Message
+	err := repo.db.WithContext(ctx).Where("id = ? AND space_id = ?", messageID, spaceID).First(&msg).Error
+	return msg, translateError(ctx, err)
+}
+
+func (repo *messageRepository) DeleteMessage(ctx context.Context, messageID, spaceID int) error {
+	result := repo.db.WithContext(ctx).Delete(&models.Message{}, "id = ? AND space_id = ?", messageID, spaceID)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: メッセージが見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// メッセージをピン留めする（ピン留め済みなら日時とユーザーを更新する）
+func (repo *messageRepository) PinMessage(ctx context.Context, messageID, spaceID, userID int, pinnedAt time.Time) error {
+	return repo.updatePin(ctx, messageID, spaceID, map[string]any{"pinned": true, "pinned_by": userID, "pinned_at": pinnedAt})
 }
 
-func (repo *messageRepository) DeleteMessage(messageID, spaceID int) error {
-	result := repo.db.Delete(&models.Message{}, "id = ? AND space_id = ?", messageID, spaceID)
+// ピン留めを外す（ピン留めされていなくてもメッセージがあれば成功とする）
+func (repo *messageRepository) UnpinMessage(ctx context.Context, messageID, spaceID int) error {
+	return repo.updatePin(ctx, messageID, spaceID, map[string]any{"pinned": false, "pinned_by": nil, "pinned_at": nil})
+}
+
+func (repo *messageRepository) updatePin(ctx context.Context, messageID, spaceID int, values map[string]any) error {
+	result := repo.db.WithContext(ctx).Model(&models.Message{}).Where("id = ? AND space_id = ?", messageID, spaceID).Updates(values)
 	if result.Error != nil {
-		return result.Error
+		return translateError(ctx, result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("メッセージが見つかりませんでした")
+		return fmt.Errorf("%w: メッセージが見つかりませんでした", apperrors.ErrNotFound)
 	}
 
 	return nil
 }
+
+// スペースのピン留めされたメッセージを新しくピン留めした順に取得
+func (repo *messageRepository) GetPinnedMessages(ctx context.Context, spaceID int) ([]models.Message, error) {
+	var messages []models.Message
+	err := repo.db.WithContext(ctx).Where("space_id = ? AND pinned", spaceID).Order("pinned_at DESC, id DESC").Find(&messages).Error
+	return messages, translateError(ctx, err)
+}
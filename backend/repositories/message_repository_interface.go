@@ -1,9 +1,18 @@
 package repositories
 
-import "chat/models"
+import (
+	"chat/models"
+	"context"
+	"time"
+)
 
 type MessageRepository interface {
-	CreateMessage(msg models.Message) (int, error)
-	GetMessages(spaceId int) ([]models.Message, error)
-	DeleteMessage(messageID, spaceID int) error
+	CreateMessage(ctx context.Context, msg models.Message) (int, error)
+	GetMessages(ctx context.Context, spaceId int) ([]models.Message, error)
+	GetMessage(ctx context.Context, messageID, spaceID int) (models.Message, error)
+	GetMessagesAfter(ctx context.Context, spaceID, afterID, limit int) ([]models.Message, error)
+	DeleteMessage(ctx context.Context, messageID, spaceID int) error
+	PinMessage(ctx context.Context, messageID, spaceID, userID int, pinnedAt time.Time) error
+	UnpinMessage(ctx context.Context, messageID, spaceID int) error
+	GetPinnedMessages(ctx context.Context, spaceID int) ([]models.Message, error)
 }
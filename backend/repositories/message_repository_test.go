@@ -1,10 +1,12 @@
 package repositories_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
+	"chat/apperrors"
 	"chat/models"
 	"chat/repositories"
 
@@ -46,16 +48,17 @@ func TestCreateMessage(t *testing.T) {
 		SpaceID:   1,
 		Username:  "alice",
 		Text:      "Hello, World!",
+		HTML:      "<p>Hello, World!</p>",
 		CreatedAt: now,
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "messages" \("space_id","username","text","created_at"\) VALUES \(\$1,\$2,\$3,\$4\) RETURNING "created_at","id"`).
-		WithArgs(msg.SpaceID, msg.Username, msg.Text, msg.CreatedAt).
+	mock.ExpectQuery(`INSERT INTO "messages" \("space_id","user_id","username","bot","text","html","scheduled_message_id","created_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "created_at","id"`).
+		WithArgs(msg.SpaceID, msg.UserID, msg.Username, msg.Bot, msg.Text, msg.HTML, msg.ScheduledMessageID, msg.CreatedAt).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, now))
 	mock.ExpectCommit()
 
-	id, err := repo.CreateMessage(msg)
+	id, err := repo.CreateMessage(context.Background(), msg)
 	assert.NoError(t, err)
 	assert.Equal(t, 10, id, "返却される ID が正しいこと")
 
@@ -71,17 +74,18 @@ func TestCreateMessage_DBError(t *testing.T) {
 		SpaceID:   1,
 		Username:  "alice",
 		Text:      "Hello, World!",
+		HTML:      "<p>Hello, World!</p>",
 		CreatedAt: now,
 	}
 
 	// DBがエラーを返すケースをモック
 	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "messages" \("space_id","username","text","created_at"\) VALUES \(\$1,\$2,\$3,\$4\) RETURNING "created_at","id"`).
-		WithArgs(msg.SpaceID, msg.Username, msg.Text, msg.CreatedAt).
+	mock.ExpectQuery(`INSERT INTO "messages" \("space_id","user_id","username","bot","text","html","scheduled_message_id","created_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "created_at","id"`).
+		WithArgs(msg.SpaceID, msg.UserID, msg.Username, msg.Bot, msg.Text, msg.HTML, msg.ScheduledMessageID, msg.CreatedAt).
 		WillReturnError(errors.New("mock db error"))
 	mock.ExpectRollback()
 
-	id, err := repo.CreateMessage(msg)
+	id, err := repo.CreateMessage(context.Background(), msg)
 	// ここでエラーが返るはず
 	assert.Error(t, err)
 	assert.Equal(t, 0, id, "IDは0が返る")
@@ -107,7 +111,7 @@ func TestGetMessages(t *testing.T) {
 			AddRow(2, 1, "charlie", "Second message", time.Now()),
 		)
 
-	messages, err := repo.GetMessages(spaceID)
+	messages, err := repo.GetMessages(context.Background(), spaceID)
 	assert.NoError(t, err)
 	assert.Len(t, messages, 2)
 
@@ -136,7 +140,7 @@ func TestDeleteMessage(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	err := repo.DeleteMessage(messageID, spaceID)
+	err := repo.DeleteMessage(context.Background(), messageID, spaceID)
 	assert.NoError(t, err)
 
 	err = mock.ExpectationsWereMet()
@@ -156,8 +160,9 @@ func TestDeleteMessage_NotFound(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 0)) // RowsAffected=0
 	mock.ExpectCommit()
 
-	err := repo.DeleteMessage(messageID, spaceID)
+	err := repo.DeleteMessage(context.Background(), messageID, spaceID)
 	assert.Error(t, err, "該当メッセージが存在しない場合はエラー")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
 
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
@@ -176,7 +181,7 @@ func TestDeleteMessage_DBError(t *testing.T) {
 		WillReturnError(errors.New("mock delete error"))
 	mock.ExpectRollback()
 
-	err := repo.DeleteMessage(messageID, spaceID)
+	err := repo.DeleteMessage(context.Background(), messageID, spaceID)
 	// ここでエラーが返ることを期待
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "mock delete error")
@@ -184,3 +189,147 @@ func TestDeleteMessage_DBError(t *testing.T) {
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
 }
+
+// タイムアウトで実行中のクエリが打ち切られること
+func TestGetMessages_Timeout(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE space_id = \$1`).
+		WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	messages, err := repo.GetMessages(ctx, 1)
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "クエリの完了を待たずに戻ること")
+	assert.ErrorIs(t, err, apperrors.ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, messages)
+}
+
+// クライアントの切断（コンテキストのキャンセル）で保存が打ち切られること
+func TestCreateMessage_Canceled(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "messages"`).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	id, err := repo.CreateMessage(ctx, models.Message{SpaceID: 1, Username: "alice", Text: "Hello"})
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "クエリの完了を待たずに戻ること")
+	assert.ErrorIs(t, err, apperrors.ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, id)
+}
+
+// GetMessage: スペースとIDの両方で絞り込むこと
+func TestGetMessage(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE id = \$1 AND space_id = \$2 ORDER BY "messages"."id" LIMIT \$3`).
+		WithArgs(10, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "username", "text"}).
+			AddRow(10, 1, "alice", "Hello"))
+
+	msg, err := repo.GetMessage(context.Background(), 10, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, msg.ID)
+	assert.Equal(t, "alice", msg.Username)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetMessage_NotFound(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE id = \$1 AND space_id = \$2`).
+		WithArgs(10, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.GetMessage(context.Background(), 10, 2)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+// GetMessagesAfter: 指定 ID より後を ID 順に件数を絞って取得すること
+func TestGetMessagesAfter(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE space_id = \$1 AND id > \$2 ORDER BY id ASC LIMIT \$3`).
+		WithArgs(1, 5, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "username", "text"}).
+			AddRow(6, 1, "alice", "Hello").
+			AddRow(7, 1, "bob", "Hi"))
+
+	messages, err := repo.GetMessagesAfter(context.Background(), 1, 5, 100)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, 6, messages[0].ID)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+// ピン留めは日時とユーザーを記録する
+func TestPinMessage(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+	pinnedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "messages" SET "pinned"=\$1,"pinned_at"=\$2,"pinned_by"=\$3 WHERE id = \$4 AND space_id = \$5`).
+		WithArgs(true, pinnedAt, 7, 10, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.PinMessage(context.Background(), 10, 1, 7, pinnedAt))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+// 別のスペースのメッセージはピン留めできない
+func TestUnpinMessage_NotFound(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "messages" SET "pinned"=\$1,"pinned_at"=\$2,"pinned_by"=\$3 WHERE id = \$4 AND space_id = \$5`).
+		WithArgs(false, nil, nil, 10, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.UnpinMessage(context.Background(), 10, 2)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetPinnedMessages(t *testing.T) {
+	repo, mock := setupMockMessageDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE space_id = \$1 AND pinned ORDER BY pinned_at DESC, id DESC`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "username", "text", "pinned", "pinned_by", "pinned_at"}).
+			AddRow(10, 1, "alice", "お知らせ", true, 7, time.Now()))
+
+	messages, err := repo.GetPinnedMessages(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.True(t, messages[0].Pinned)
+	assert.Equal(t, 7, *messages[0].PinnedBy)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
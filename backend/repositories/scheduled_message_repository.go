@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type scheduledMessageRepository struct {
+	DB *gorm.DB
+}
+
+func NewScheduledMessageRepository(db *gorm.DB) ScheduledMessageRepository {
+	return &scheduledMessageRepository{DB: db}
+}
+
+// 予約を登録し、ID と作成日時を設定して返す
+func (repo *scheduledMessageRepository) CreateScheduledMessage(ctx context.Context, sm models.ScheduledMessage) (models.ScheduledMessage, error) {
+	err := repo.DB.WithContext(ctx).Create(&sm).Error
+	return sm, translateError(ctx, err)
+}
+
+// スペースにあるユーザーの送信待ちの予約を送信日時の順に取得
+func (repo *scheduledMessageRepository) ListScheduledMessages(ctx context.Context, spaceID, userID int) ([]models.ScheduledMessage, error) {
+	var list []models.ScheduledMessage
+	err := repo.DB.WithContext(ctx).
+		Where("space_id = ? AND user_id = ? AND status = ?", spaceID, userID, models.ScheduledPending).
+		Order("send_at ASC, id ASC").Find(&list).Error
+	return list, translateError(ctx, err)
+}
+
+// 送信待ちの予約を取り消す（送信済み・取り消し済みの予約は見つからない扱い）
+func (repo *scheduledMessageRepository) CancelScheduledMessage(ctx context.Context, scheduledID, spaceID, userID int) error {
+	result := repo.DB.WithContext(ctx).Model(&models.ScheduledMessage{}).
+		Where("id = ? AND space_id = ? AND user_id = ? AND status = ?", scheduledID, spaceID, userID, models.ScheduledPending).
+		Update("status", models.ScheduledCanceled)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: 予約が見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// 送信時刻になった予約を送信日時の順に最大 limit 件取り出す
+//
+// 取り出した予約は次の送信時刻を lease だけ先に延ばし、送信中に他のインスタンスが
+// 同じ予約を取り出さないようにする。送信中にプロセスが落ちた場合は lease の後に再送される。
+// 返す予約の NextAttemptAt は延ばした時刻で、結果の記録はこの値が変わっていない場合だけ行う。
+func (repo *scheduledMessageRepository) ClaimDueScheduledMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ScheduledMessage, error) {
+	var list []models.ScheduledMessage
+	// DB の精度（マイクロ秒）に合わせ、記録時に同じ値で比較できるようにする
+	leaseUntil := now.Add(lease).Truncate(time.Microsecond)
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.ScheduledPending, now).
+			Order("send_at ASC, id ASC").Limit(limit).Find(&list).Error
+		if err != nil || len(list) == 0 {
+			return err
+		}
+
+		ids := make([]int, len(list))
+		for i := range list {
+			ids[i] = list[i].ID
+			list[i].NextAttemptAt = leaseUntil
+		}
+		return tx.Model(&models.ScheduledMessage{}).Where("id IN ?", ids).Update("next_attempt_at", leaseUntil).Error
+	})
+	if err != nil || len(list) == 0 {
+		return nil, translateError(ctx, err)
+	}
+	return list, nil
+}
+
+// 取り出した予約を送信済みにし、投稿したメッセージを記録する
+func (repo *scheduledMessageRepository) MarkScheduledMessageSent(ctx context.Context, sm models.ScheduledMessage, messageID int, sentAt time.Time) error {
+	return repo.updateClaimed(ctx, sm.ID, sm.NextAttemptAt, map[string]any{
+		"status":     models.ScheduledSent,
+		"message_id": messageID,
+		"sent_at":    sentAt,
+		"error":      "",
+	})
+}
+
+// 取り出した予約の送信の失敗を記録する（再送するかは Status、再送の時刻は NextAttemptAt で決まる）
+// leaseUntil は取り出した時の next_attempt_at で、取り出した時のまま送信待ちかの確認に使う。
+func (repo *scheduledMessageRepository) RecordScheduledMessageFailure(ctx context.Context, sm models.ScheduledMessage, leaseUntil time.Time) error {
+	return repo.updateClaimed(ctx, sm.ID, leaseUntil, map[string]any{
+		"status":          sm.Status,
+		"attempts":        sm.Attempts,
+		"error":           sm.Error,
+		"next_attempt_at": sm.NextAttemptAt,
+	})
+}
+
+// 取り出した時のまま送信待ちの予約だけを更新する
+// 送信中に取り消された予約・lease が切れて他のインスタンスが取り出した予約は見つからない扱いにし、上書きしない。
+func (repo *scheduledMessageRepository) updateClaimed(ctx context.Context, id int, leaseUntil time.Time, values map[string]any) error {
+	result := repo.DB.WithContext(ctx).Model(&models.ScheduledMessage{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", id, models.ScheduledPending, leaseUntil).
+		Updates(values)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: 予約は取り消されたか、他のインスタンスが取り出しました", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// 予約から投稿済みのメッセージの ID を取得する（送信済みを記録する前に再送された場合の確認用）
+func (repo *scheduledMessageRepository) GetScheduledMessagePost(ctx context.Context, scheduledID int) (int, error) {
+	var msg models.Message
+	err := repo.DB.WithContext(ctx).Select("id").Where("scheduled_message_id = ?", scheduledID).First(&msg).Error
+	return msg.ID, translateError(ctx, err)
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+	"time"
+)
+
+type ScheduledMessageRepository interface {
+	CreateScheduledMessage(ctx context.Context, sm models.ScheduledMessage) (models.ScheduledMessage, error)
+	ListScheduledMessages(ctx context.Context, spaceID, userID int) ([]models.ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, scheduledID, spaceID, userID int) error
+	ClaimDueScheduledMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ScheduledMessage, error)
+	MarkScheduledMessageSent(ctx context.Context, sm models.ScheduledMessage, messageID int, sentAt time.Time) error
+	RecordScheduledMessageFailure(ctx context.Context, sm models.ScheduledMessage, leaseUntil time.Time) error
+	GetScheduledMessagePost(ctx context.Context, scheduledID int) (int, error)
+}
@@ -0,0 +1,215 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockScheduledMessageDB(t *testing.T) (repositories.ScheduledMessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: mockDB,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm DB with sqlmock: %v", err)
+	}
+
+	return repositories.NewScheduledMessageRepository(gormDB), mock
+}
+
+func TestCreateScheduledMessage(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+	sendAt := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "scheduled_messages" \("space_id","user_id","username","kind","text","send_at","status","attempts","next_attempt_at","message_id","error","sent_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11,\$12\) RETURNING "created_at","id"`).
+		WithArgs(1, 7, "alice", models.ScheduledKindMessage, "おはようございます", sendAt, models.ScheduledPending, 0, sendAt, nil, "", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).AddRow(time.Now(), 5))
+	mock.ExpectCommit()
+
+	sm, err := repo.CreateScheduledMessage(context.Background(), models.ScheduledMessage{
+		SpaceID: 1, UserID: 7, Username: "alice", Kind: models.ScheduledKindMessage, Text: "おはようございます",
+		SendAt: sendAt, Status: models.ScheduledPending, NextAttemptAt: sendAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, sm.ID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListScheduledMessages(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "scheduled_messages" WHERE space_id = \$1 AND user_id = \$2 AND status = \$3 ORDER BY send_at ASC, id ASC`).
+		WithArgs(1, 7, models.ScheduledPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "user_id", "text"}).AddRow(5, 1, 7, "a").AddRow(6, 1, 7, "b"))
+
+	list, err := repo.ListScheduledMessages(context.Background(), 1, 7)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 他のユーザーの予約・送信済みの予約は取り消せない
+func TestCancelScheduledMessage_NotFound(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "scheduled_messages" SET "status"=\$1 WHERE id = \$2 AND space_id = \$3 AND user_id = \$4 AND status = \$5`).
+		WithArgs(models.ScheduledCanceled, 5, 1, 7, models.ScheduledPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.CancelScheduledMessage(context.Background(), 5, 1, 7)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 送信時刻になった予約を行ロックして取り出し、次の送信時刻を延ばす
+func TestClaimDueScheduledMessages(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 123456789, time.UTC)
+	// DB の精度に合わせてマイクロ秒に切り捨てる
+	leaseUntil := time.Date(2026, 10, 19, 12, 1, 0, 123456000, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "scheduled_messages" WHERE status = \$1 AND next_attempt_at <= \$2 ORDER BY send_at ASC, id ASC LIMIT \$3 FOR UPDATE SKIP LOCKED`).
+		WithArgs(models.ScheduledPending, now, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "user_id", "username", "kind", "text", "status", "attempts"}).
+			AddRow(5, 1, 7, "alice", "message", "a", "pending", 0).
+			AddRow(6, 2, 7, "alice", "reminder", "b", "pending", 1))
+	mock.ExpectExec(`UPDATE "scheduled_messages" SET "next_attempt_at"=\$1 WHERE id IN \(\$2,\$3\)`).
+		WithArgs(leaseUntil, 5, 6).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	list, err := repo.ClaimDueScheduledMessages(context.Background(), now, time.Minute, 50)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, models.ScheduledKindReminder, list[1].Kind)
+	// 結果の記録で照合できるよう、延ばした時刻を返す
+	assert.Equal(t, leaseUntil, list[0].NextAttemptAt)
+	assert.Equal(t, leaseUntil, list[1].NextAttemptAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDueScheduledMessages_Empty(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "scheduled_messages"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	list, err := repo.ClaimDueScheduledMessages(context.Background(), time.Now(), time.Minute, 50)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkScheduledMessageSent(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+	sentAt := time.Date(2026, 10, 19, 12, 0, 1, 0, time.UTC)
+	claimed := models.ScheduledMessage{ID: 5, Status: models.ScheduledPending, NextAttemptAt: time.Date(2026, 10, 19, 12, 1, 0, 0, time.UTC)}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "scheduled_messages" SET "error"=\$1,"message_id"=\$2,"sent_at"=\$3,"status"=\$4 WHERE id = \$5 AND status = \$6 AND next_attempt_at = \$7`).
+		WithArgs("", 42, sentAt, models.ScheduledSent, 5, models.ScheduledPending, claimed.NextAttemptAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.MarkScheduledMessageSent(context.Background(), claimed, 42, sentAt))
+
+	// 送信中に取り消された・lease が切れて他のインスタンスが取り出した予約は更新しない
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "scheduled_messages"`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.MarkScheduledMessageSent(context.Background(), claimed, 42, sentAt)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetScheduledMessagePost(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+
+	mock.ExpectQuery(`SELECT "id" FROM "messages" WHERE scheduled_message_id = \$1 ORDER BY "messages"."id" LIMIT \$2`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+	id, err := repo.GetScheduledMessagePost(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, id)
+
+	// まだ投稿していない
+	mock.ExpectQuery(`SELECT "id" FROM "messages"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.GetScheduledMessagePost(context.Background(), 6)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordScheduledMessageFailure(t *testing.T) {
+	repo, mock := setupMockScheduledMessageDB(t)
+
+	leaseUntil := time.Date(2026, 10, 19, 12, 1, 0, 0, time.UTC)
+	retryAt := time.Date(2026, 10, 19, 12, 2, 0, 0, time.UTC)
+
+	// 再送の時刻を延ばす。取り出した時の next_attempt_at のまま送信待ちの予約だけを更新する
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "scheduled_messages" SET "attempts"=\$1,"error"=\$2,"next_attempt_at"=\$3,"status"=\$4 WHERE id = \$5 AND status = \$6 AND next_attempt_at = \$7`).
+		WithArgs(1, "db down", retryAt, models.ScheduledPending, 5, models.ScheduledPending, leaseUntil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.RecordScheduledMessageFailure(context.Background(),
+		models.ScheduledMessage{ID: 5, Status: models.ScheduledPending, Attempts: 1, Error: "db down", NextAttemptAt: retryAt}, leaseUntil)
+	assert.NoError(t, err)
+
+	// 諦める場合も、取り出した時に送信待ちだった予約だけを更新する
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "scheduled_messages" SET "attempts"=\$1,"error"=\$2,"next_attempt_at"=\$3,"status"=\$4 WHERE id = \$5 AND status = \$6 AND next_attempt_at = \$7`).
+		WithArgs(2, "db down", leaseUntil, models.ScheduledFailed, 5, models.ScheduledPending, leaseUntil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.RecordScheduledMessageFailure(context.Background(),
+		models.ScheduledMessage{ID: 5, Status: models.ScheduledFailed, Attempts: 2, Error: "db down", NextAttemptAt: leaseUntil}, leaseUntil)
+	assert.NoError(t, err)
+
+	// lease が切れて他のインスタンスが取り出した予約は上書きしない
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "scheduled_messages"`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = repo.RecordScheduledMessageFailure(context.Background(),
+		models.ScheduledMessage{ID: 5, Status: models.ScheduledPending, Attempts: 1, Error: "db down", NextAttemptAt: retryAt}, leaseUntil)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,7 +1,10 @@
 package repositories
 
 import (
+	"chat/apperrors"
 	"chat/models"
+	"context"
+	"fmt"
 
 	"gorm.io/gorm"
 )
@@ -14,15 +17,37 @@ func NewSpaceRepository(db *gorm.DB) SpaceRepository {
 	return &spaceRepository{DB: db}
 }
 
-// スペースを作成
-func (repo *spaceRepository) CreateSpace(name string) error {
-	space := models.Space{Name: name}
-	return repo.DB.Create(&space).Error
+// スペースを作成し、ID と作成日時を設定したスペースを返す（ownerID は未ログインなら nil）
+func (repo *spaceRepository) CreateSpace(ctx context.Context, name string, ownerID *int) (models.Space, error) {
+	space := models.Space{Name: name, OwnerID: ownerID}
+	err := repo.DB.WithContext(ctx).Create(&space).Error
+	return space, translateError(ctx, err)
 }
 
 // スペース一覧を取得
-func (repo *spaceRepository) GetSpaces() ([]models.Space, error) {
+func (repo *spaceRepository) GetSpaces(ctx context.Context) ([]models.Space, error) {
 	var spaces []models.Space
-	err := repo.DB.Order("created_at ASC").Find(&spaces).Error
-	return spaces, err
+	err := repo.DB.WithContext(ctx).Order("created_at ASC").Find(&spaces).Error
+	return spaces, translateError(ctx, err)
+}
+
+// ID でスペースを取得
+func (repo *spaceRepository) GetSpaceByID(ctx context.Context, id int) (models.Space, error) {
+	var space models.Space
+	err := repo.DB.WithContext(ctx).First(&space, id).Error
+	return space, translateError(ctx, err)
+}
+
+// スペースのトピックを変更する
+func (repo *spaceRepository) UpdateSpaceTopic(ctx context.Context, id int, topic string) error {
+	result := repo.DB.WithContext(ctx).Model(&models.Space{}).Where("id = ?", id).Update("topic", topic)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: スペースが見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
 }
@@ -1,8 +1,13 @@
 package repositories
 
-import "chat/models"
+import (
+	"chat/models"
+	"context"
+)
 
 type SpaceRepository interface {
-	CreateSpace(name string) error
-	GetSpaces() ([]models.Space, error)
+	CreateSpace(ctx context.Context, name string, ownerID *int) (models.Space, error)
+	GetSpaces(ctx context.Context) ([]models.Space, error)
+	GetSpaceByID(ctx context.Context, id int) (models.Space, error)
+	UpdateSpaceTopic(ctx context.Context, id int, topic string) error
 }
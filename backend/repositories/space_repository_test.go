@@ -1,7 +1,9 @@
 package repositories_test
 
 import (
+	"chat/apperrors"
 	"chat/repositories"
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -39,14 +41,16 @@ func TestCreateSpace(t *testing.T) {
 	repo, mock := setupMockSpaceDB(t)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "spaces" \("name"\) VALUES \(\$1\) RETURNING "created_at","id"`).
-		WithArgs("Test Space").
+	mock.ExpectQuery(`INSERT INTO "spaces" \("name","owner_id"\) VALUES \(\$1,\$2\) RETURNING "created_at","id"`).
+		WithArgs("Test Space", nil).
 		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).
 			AddRow(time.Now(), 1))
 	mock.ExpectCommit()
 
-	err := repo.CreateSpace("Test Space")
+	space, err := repo.CreateSpace(context.Background(), "Test Space", nil)
 	assert.NoError(t, err)
+	assert.Equal(t, 1, space.ID)
+	assert.Equal(t, "Test Space", space.Name)
 
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
@@ -57,12 +61,12 @@ func TestCreateSpace_DBError(t *testing.T) {
 	repo, mock := setupMockSpaceDB(t)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "spaces" \("name"\) VALUES \(\$1\) RETURNING "created_at","id"`).
-		WithArgs("Test Space").
+	mock.ExpectQuery(`INSERT INTO "spaces" \("name","owner_id"\) VALUES \(\$1,\$2\) RETURNING "created_at","id"`).
+		WithArgs("Test Space", nil).
 		WillReturnError(errors.New("mock db error"))
 	mock.ExpectRollback()
 
-	err := repo.CreateSpace("Test Space")
+	_, err := repo.CreateSpace(context.Background(), "Test Space", nil)
 	assert.Error(t, err)
 
 	err = mock.ExpectationsWereMet()
@@ -82,7 +86,7 @@ func TestGetSpaces(t *testing.T) {
 			AddRow(1, "Space A", now).
 			AddRow(2, "Space B", now))
 
-	spaces, err := repo.GetSpaces()
+	spaces, err := repo.GetSpaces(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, spaces, 2)
 
@@ -102,7 +106,7 @@ func TestGetSpaces_DBError(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "spaces" ORDER BY created_at ASC`).
 		WillReturnError(errors.New("mock db error"))
 
-	spaces, err := repo.GetSpaces()
+	spaces, err := repo.GetSpaces(context.Background())
 	assert.Error(t, err)
 	assert.Nil(t, spaces)
 	assert.Contains(t, err.Error(), "mock db error")
@@ -110,3 +114,72 @@ func TestGetSpaces_DBError(t *testing.T) {
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
 }
+
+// ---------------
+// GetSpaceByID のテスト
+// ---------------
+func TestGetSpaceByID(t *testing.T) {
+	repo, mock := setupMockSpaceDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "spaces" WHERE "spaces"\."id" = \$1 ORDER BY "spaces"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).
+			AddRow(1, "Space A", time.Now()))
+
+	space, err := repo.GetSpaceByID(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Space A", space.Name)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+// 存在しないスペース
+func TestGetSpaceByID_NotFound(t *testing.T) {
+	repo, mock := setupMockSpaceDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "spaces" WHERE "spaces"\."id" = \$1 ORDER BY "spaces"\."id" LIMIT \$2`).
+		WithArgs(99, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}))
+
+	_, err := repo.GetSpaceByID(context.Background(), 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+// ---------------
+// UpdateSpaceTopic のテスト
+// ---------------
+func TestUpdateSpaceTopic(t *testing.T) {
+	repo, mock := setupMockSpaceDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "spaces" SET "topic"=\$1 WHERE id = \$2`).
+		WithArgs("リリース準備", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UpdateSpaceTopic(context.Background(), 1, "リリース準備"))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+// 存在しないスペース
+func TestUpdateSpaceTopic_NotFound(t *testing.T) {
+	repo, mock := setupMockSpaceDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "spaces" SET "topic"=\$1 WHERE id = \$2`).
+		WithArgs("x", 99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.UpdateSpaceTopic(context.Background(), 99, "x")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
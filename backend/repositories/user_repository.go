@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"chat/models"
+	"context"
 
 	"gorm.io/gorm"
 )
@@ -15,23 +16,23 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 // ユーザー作成
-func (repo *userRepository) CreateUser(user models.User) error {
-	return repo.DB.Create(&user).Error
+func (repo *userRepository) CreateUser(ctx context.Context, user models.User) error {
+	return translateError(ctx, repo.DB.WithContext(ctx).Create(&user).Error)
 }
 
 // ユーザー取得
-func (repo *userRepository) GetUserByUsername(username string) (models.User, error) {
+func (repo *userRepository) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
 	var user models.User
-	err := repo.DB.Where("username = ?", username).First(&user).Error
-	return user, err
+	err := repo.DB.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	return user, translateError(ctx, err)
 }
 
 // パスワード取得
-func (repo *userRepository) GetPasswordByUsername(username string) (string, error) {
+func (repo *userRepository) GetPasswordByUsername(ctx context.Context, username string) (string, error) {
 	var user models.User
-	err := repo.DB.Where("username = ?", username).First(&user).Error
+	err := repo.DB.WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
-		return "", err
+		return "", translateError(ctx, err)
 	}
 	return user.Password, nil
 }
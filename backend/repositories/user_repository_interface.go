@@ -1,9 +1,12 @@
 package repositories
 
-import "chat/models"
+import (
+	"chat/models"
+	"context"
+)
 
 type UserRepository interface {
-	CreateUser(user models.User) error
-	GetUserByUsername(username string) (models.User, error)
-	GetPasswordByUsername(username string) (string, error)
+	CreateUser(ctx context.Context, user models.User) error
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+	GetPasswordByUsername(ctx context.Context, username string) (string, error)
 }
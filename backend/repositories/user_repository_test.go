@@ -1,12 +1,15 @@
 package repositories_test
 
 import (
+	"chat/apperrors"
 	"chat/models"
 	"chat/repositories"
+	"context"
 	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -44,12 +47,12 @@ func TestCreateUser(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "users" ("username","password") VALUES ($1,$2)`)).
-		WithArgs(user.Username, user.Password).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("username","password","role") VALUES ($1,$2,$3) RETURNING "id"`)).
+		WithArgs(user.Username, user.Password, models.RoleMember).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
-	err := repo.CreateUser(user)
+	err := repo.CreateUser(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -62,17 +65,19 @@ func TestGetUserByUsername(t *testing.T) {
 		Password: "securepassword",
 	}
 
-	rows := sqlmock.NewRows([]string{"username", "password"}).
-		AddRow(user.Username, user.Password)
+	rows := sqlmock.NewRows([]string{"id", "username", "password", "role"}).
+		AddRow(1, user.Username, user.Password, models.RoleMember)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."username" LIMIT $2`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."id" LIMIT $2`)).
 		WithArgs(user.Username, 1). // `LIMIT 1` を `WithArgs` に明示
 		WillReturnRows(rows)
 
-	result, err := repo.GetUserByUsername("testuser")
+	result, err := repo.GetUserByUsername(context.Background(), "testuser")
 	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ID)
 	assert.Equal(t, user.Username, result.Username)
 	assert.Equal(t, user.Password, result.Password)
+	assert.Equal(t, models.RoleMember, result.Role)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -84,14 +89,14 @@ func TestGetPasswordByUsername_Success(t *testing.T) {
 		Password: "hashedpassword123",
 	}
 
-	rows := sqlmock.NewRows([]string{"username", "password"}).
-		AddRow(user.Username, user.Password)
+	rows := sqlmock.NewRows([]string{"id", "username", "password", "role"}).
+		AddRow(1, user.Username, user.Password, models.RoleMember)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."username" LIMIT $2`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."id" LIMIT $2`)).
 		WithArgs(user.Username, 1).
 		WillReturnRows(rows)
 
-	password, err := repo.GetPasswordByUsername("testuser")
+	password, err := repo.GetPasswordByUsername(context.Background(), "testuser")
 	assert.NoError(t, err)
 	assert.Equal(t, user.Password, password)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -100,13 +105,33 @@ func TestGetPasswordByUsername_Success(t *testing.T) {
 func TestGetPasswordByUsername_NotFound(t *testing.T) {
 	repo, mock := setupMockUserDB(t)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."username" LIMIT $2`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."id" LIMIT $2`)).
 		WithArgs("unknownuser", 1).
 		WillReturnError(gorm.ErrRecordNotFound)
 
-	password, err := repo.GetPasswordByUsername("unknownuser")
+	password, err := repo.GetPasswordByUsername(context.Background(), "unknownuser")
 	assert.Error(t, err)
 	assert.Equal(t, "", password)
-	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUser_Duplicate(t *testing.T) {
+	repo, mock := setupMockUserDB(t)
+
+	user := models.User{
+		Username: "testuser",
+		Password: "securepassword",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("username","password","role") VALUES ($1,$2,$3) RETURNING "id"`)).
+		WithArgs(user.Username, user.Password, models.RoleMember).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "users_username_key"})
+	mock.ExpectRollback()
+
+	err := repo.CreateUser(context.Background(), user)
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookRepository struct {
+	DB *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{DB: db}
+}
+
+// Webhook を登録し、ID と作成日時を設定して返す
+func (repo *webhookRepository) CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	err := repo.DB.WithContext(ctx).Create(&hook).Error
+	return hook, translateError(ctx, err)
+}
+
+// スペースの Webhook 一覧を取得
+func (repo *webhookRepository) ListWebhooks(ctx context.Context, spaceID int) ([]models.Webhook, error) {
+	var hooks []models.Webhook
+	err := repo.DB.WithContext(ctx).Where("space_id = ?", spaceID).Order("id ASC").Find(&hooks).Error
+	return hooks, translateError(ctx, err)
+}
+
+// スペース内の Webhook を ID で取得
+func (repo *webhookRepository) GetWebhook(ctx context.Context, webhookID, spaceID int) (models.Webhook, error) {
+	var hook models.Webhook
+	err := repo.DB.WithContext(ctx).Where("id = ? AND space_id = ?", webhookID, spaceID).First(&hook).Error
+	return hook, translateError(ctx, err)
+}
+
+// Webhook を削除する（配信の記録も外部キーで削除される）
+func (repo *webhookRepository) DeleteWebhook(ctx context.Context, webhookID, spaceID int) error {
+	result := repo.DB.WithContext(ctx).Delete(&models.Webhook{}, "id = ? AND space_id = ?", webhookID, spaceID)
+	if result.Error != nil {
+		return translateError(ctx, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: Webhook が見つかりませんでした", apperrors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// 送信待ちの配信をまとめて登録する
+func (repo *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return translateError(ctx, repo.DB.WithContext(ctx).Create(&deliveries).Error)
+}
+
+// 送信時刻になった配信を最大 limit 件取り出す（送信先の Webhook も読み込む）
+//
+// 取り出した配信は次の送信時刻を lease だけ先に延ばし、送信中に他のワーカー・インスタンスが
+// 同じ配信を取り出さないようにする。送信中にプロセスが落ちた場合は lease の後に再送される。
+// 返す配信の NextAttemptAt は延ばした時刻で、結果の記録はこの値が変わっていない場合だけ行う。
+func (repo *webhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	// DB の精度（マイクロ秒）に合わせ、記録時に同じ値で比較できるようにする
+	leaseUntil := now.Add(lease).Truncate(time.Microsecond)
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("next_attempt_at ASC").Limit(limit).Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].NextAttemptAt = leaseUntil
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", leaseUntil).Error
+	})
+	if err != nil || len(deliveries) == 0 {
+		return nil, translateError(ctx, err)
+	}
+
+	hookIDs := make([]int, 0, len(deliveries))
+	for _, d := range deliveries {
+		hookIDs = append(hookIDs, d.WebhookID)
+	}
+	var hooks []models.Webhook
+	if err := repo.DB.WithContext(ctx).Where("id IN ?", hookIDs).Find(&hooks).Error; err != nil {
+		return nil, translateError(ctx, err)
+	}
+	byID := make(map[int]*models.Webhook, len(hooks))
+	for i := range hooks {
+		byID[hooks[i].ID] = &hooks[i]
+	}
+	for i := range deliveries {
+		deliveries[i].Webhook = byID[deliveries[i].WebhookID]
+	}
+	return deliveries, nil
+}
+
+// 送信の結果を記録し、配信の状態（回数・次の送信時刻）を更新する
+// leaseUntil は取り出した時に延ばした次の送信時刻。lease が切れて他のワーカーが取り出し直した配信・
+// 送信済みになった配信は見つからない扱いにし、状態も送信の記録も残さない。
+func (repo *webhookRepository) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, leaseUntil time.Time, attempt models.WebhookAttempt) error {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, models.DeliveryPending, leaseUntil).
+			Updates(map[string]any{
+				"status":          delivery.Status,
+				"attempts":        delivery.Attempts,
+				"next_attempt_at": delivery.NextAttemptAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: 配信は他のワーカーが取り出したか、送信済みです", apperrors.ErrNotFound)
+		}
+		return tx.Create(&attempt).Error
+	})
+	return translateError(ctx, err)
+}
+
+// Webhook の配信を新しい順に最大 limit 件取得（送信の記録も含む）
+func (repo *webhookRepository) ListDeliveries(ctx context.Context, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := repo.DB.WithContext(ctx).
+		Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("attempt ASC") }).
+		Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, translateError(ctx, err)
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"chat/models"
+	"context"
+	"time"
+)
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, spaceID int) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, webhookID, spaceID int) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID, spaceID int) error
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// leaseUntil は取り出した時の NextAttemptAt。取り出し直された配信には記録しない（ErrNotFound）
+	RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, leaseUntil time.Time, attempt models.WebhookAttempt) error
+	ListDeliveries(ctx context.Context, webhookID, limit int) ([]models.WebhookDelivery, error)
+}
@@ -0,0 +1,161 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockWebhookDB(t *testing.T) (repositories.WebhookRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: mockDB,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm DB with sqlmock: %v", err)
+	}
+
+	return repositories.NewWebhookRepository(gormDB), mock
+}
+
+func TestCreateWebhook(t *testing.T) {
+	repo, mock := setupMockWebhookDB(t)
+
+	createdBy := 7
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "webhooks" \("space_id","url","secret","events","created_by"\) VALUES \(\$1,\$2,\$3,\$4,\$5\) RETURNING "created_at","id"`).
+		WithArgs(1, "https://example.com/hook", "s3cret", "message.created", createdBy).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).AddRow(time.Now(), 3))
+	mock.ExpectCommit()
+
+	hook, err := repo.CreateWebhook(context.Background(), models.Webhook{
+		SpaceID: 1, URL: "https://example.com/hook", Secret: "s3cret", Events: "message.created", CreatedBy: &createdBy,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, hook.ID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteWebhook_NotFound(t *testing.T) {
+	repo, mock := setupMockWebhookDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "webhooks" WHERE id = \$1 AND space_id = \$2`).
+		WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteWebhook(context.Background(), 3, 1)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 送信時刻になった配信を行ロックして取り出し、次の送信時刻を延ばす
+func TestClaimDueDeliveries(t *testing.T) {
+	repo, mock := setupMockWebhookDB(t)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 123456789, time.UTC)
+	// DB の精度に合わせてマイクロ秒に切り捨てる
+	leaseUntil := time.Date(2026, 10, 19, 12, 1, 0, 123456000, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries" WHERE status = \$1 AND next_attempt_at <= \$2 ORDER BY next_attempt_at ASC LIMIT \$3 FOR UPDATE SKIP LOCKED`).
+		WithArgs(models.DeliveryPending, now, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event", "payload", "status", "attempts"}).
+			AddRow(10, 3, "message.created", `{}`, "pending", 0).
+			AddRow(11, 3, "message.deleted", `{}`, "pending", 2))
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET "next_attempt_at"=\$1,"updated_at"=\$2 WHERE id IN \(\$3,\$4\)`).
+		WithArgs(leaseUntil, sqlmock.AnyArg(), 10, 11).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "webhooks" WHERE id IN \(\$1,\$2\)`).
+		WithArgs(3, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space_id", "url", "secret"}).AddRow(3, 1, "https://example.com/hook", "s3cret"))
+
+	deliveries, err := repo.ClaimDueDeliveries(context.Background(), now, time.Minute, 4)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, "https://example.com/hook", deliveries[1].Webhook.URL)
+	// 結果の記録で照合できるよう、延ばした時刻を返す
+	assert.Equal(t, leaseUntil, deliveries[0].NextAttemptAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDueDeliveries_Empty(t *testing.T) {
+	repo, mock := setupMockWebhookDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	deliveries, err := repo.ClaimDueDeliveries(context.Background(), time.Now(), time.Minute, 4)
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 送信の記録と配信の更新を同じトランザクションで行う
+func TestRecordAttempt(t *testing.T) {
+	repo, mock := setupMockWebhookDB(t)
+	leaseUntil := time.Date(2026, 10, 19, 12, 0, 30, 0, time.UTC)
+	next := time.Date(2026, 10, 19, 12, 1, 0, 0, time.UTC)
+	status := 500
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET "attempts"=\$1,"next_attempt_at"=\$2,"status"=\$3,"updated_at"=\$4 WHERE id = \$5 AND status = \$6 AND next_attempt_at = \$7`).
+		WithArgs(1, next, models.DeliveryPending, sqlmock.AnyArg(), 10, models.DeliveryPending, leaseUntil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "webhook_attempts" \("delivery_id","attempt","status_code","error","duration_ms"\) VALUES \(\$1,\$2,\$3,\$4,\$5\) RETURNING "created_at","id"`).
+		WithArgs(10, 1, status, "", 120).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "id"}).AddRow(time.Now(), 1))
+	mock.ExpectCommit()
+
+	err := repo.RecordAttempt(context.Background(),
+		models.WebhookDelivery{ID: 10, Status: models.DeliveryPending, Attempts: 1, NextAttemptAt: next},
+		leaseUntil,
+		models.WebhookAttempt{DeliveryID: 10, Attempt: 1, StatusCode: &status, DurationMs: 120},
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// lease が切れて他のワーカーが取り出し直した配信・送信済みの配信は上書きせず、送信の記録も残さない
+func TestRecordAttempt_LostLease(t *testing.T) {
+	repo, mock := setupMockWebhookDB(t)
+	leaseUntil := time.Date(2026, 10, 19, 12, 0, 30, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET .* WHERE id = \$5 AND status = \$6 AND next_attempt_at = \$7`).
+		WithArgs(2, sqlmock.AnyArg(), models.DeliverySucceeded, sqlmock.AnyArg(), 10, models.DeliveryPending, leaseUntil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.RecordAttempt(context.Background(),
+		models.WebhookDelivery{ID: 10, Status: models.DeliverySucceeded, Attempts: 2},
+		leaseUntil,
+		models.WebhookAttempt{DeliveryID: 10, Attempt: 2},
+	)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
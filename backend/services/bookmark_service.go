@@ -0,0 +1,115 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 保存したメッセージ一覧の既定の件数と上限
+const (
+	defaultBookmarkLimit = 50
+	maxBookmarkLimit     = 100
+)
+
+type bookmarkService struct {
+	Repo        repositories.BookmarkRepository
+	MessageRepo repositories.MessageRepository
+	UserRepo    repositories.UserRepository
+}
+
+func NewBookmarkService(repo repositories.BookmarkRepository, messageRepo repositories.MessageRepository, userRepo repositories.UserRepository) BookmarkService {
+	return &bookmarkService{Repo: repo, MessageRepo: messageRepo, UserRepo: userRepo}
+}
+
+// スペースのメッセージを保存する
+// メッセージが削除されると保存も外部キーで削除されるため、一覧に削除済みのメッセージは残らない。
+func (s *bookmarkService) SaveMessage(ctx context.Context, actor string, spaceID, messageID int) (err error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.SaveMessage", attribute.Int("space_id", spaceID), attribute.Int("message_id", messageID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.user(ctx, actor)
+	if err != nil {
+		return err
+	}
+	if _, err := s.MessageRepo.GetMessage(ctx, messageID, spaceID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return ErrMessageNotFound.Wrap(err)
+		}
+		return err
+	}
+
+	err = s.Repo.CreateBookmark(ctx, user.ID, messageID)
+	// 保存の直前にメッセージが削除された場合は外部キー違反になる
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrMessageNotFound.Wrap(err)
+	}
+	return err
+}
+
+// 保存を取り消す
+// 別のスペースのメッセージを指定した場合は保存が見つからない扱いにする。
+func (s *bookmarkService) RemoveBookmark(ctx context.Context, actor string, spaceID, messageID int) (err error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.RemoveBookmark", attribute.Int("space_id", spaceID), attribute.Int("message_id", messageID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.user(ctx, actor)
+	if err != nil {
+		return err
+	}
+	if _, err := s.MessageRepo.GetMessage(ctx, messageID, spaceID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return ErrBookmarkNotFound.Wrap(err)
+		}
+		return err
+	}
+	err = s.Repo.DeleteBookmark(ctx, user.ID, messageID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrBookmarkNotFound.Wrap(err)
+	}
+	return err
+}
+
+// 保存したメッセージを新しく保存した順に取得する
+// 次のページの有無を判定するため limit より1件多く取得する。
+func (s *bookmarkService) ListBookmarks(ctx context.Context, actor string, before, limit int) (page BookmarkPage, err error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.ListBookmarks", attribute.Int("before", before), attribute.Int("limit", limit))
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 {
+		limit = defaultBookmarkLimit
+	}
+	limit = min(limit, maxBookmarkLimit)
+
+	user, err := s.user(ctx, actor)
+	if err != nil {
+		return page, err
+	}
+	bookmarks, err := s.Repo.ListBookmarks(ctx, user.ID, before, limit+1)
+	if err != nil {
+		return page, err
+	}
+
+	if len(bookmarks) > limit {
+		bookmarks = bookmarks[:limit]
+		page.NextBefore = bookmarks[limit-1].ID
+	}
+	if bookmarks == nil {
+		bookmarks = []models.Bookmark{}
+	}
+	page.Bookmarks = bookmarks
+	return page, nil
+}
+
+func (s *bookmarkService) user(ctx context.Context, actor string) (models.User, error) {
+	user, err := s.UserRepo.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return user, ErrUserNotFound.Wrap(err)
+	}
+	return user, err
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"chat/models"
+	"context"
+)
+
+// 保存したメッセージの一覧の1ページ分
+type BookmarkPage struct {
+	Bookmarks []models.Bookmark `json:"bookmarks"`
+	// 次のページを取得するときの before（最後のページなら省略）
+	NextBefore int `json:"next_before,omitempty"`
+}
+
+// メッセージの保存（あとで見返す）。actor はログイン中のユーザー名で、保存は本人にしか見えない
+type BookmarkService interface {
+	// 保存済みでもエラーにしない
+	SaveMessage(ctx context.Context, actor string, spaceID, messageID int) error
+	// メッセージが spaceID のスペースにない場合も保存が見つからないエラーにする
+	RemoveBookmark(ctx context.Context, actor string, spaceID, messageID int) error
+	// 全スペースの保存を新しい順に取得する（before が 0 なら最新から、limit が 0 なら既定の件数）
+	ListBookmarks(ctx context.Context, actor string, before, limit int) (BookmarkPage, error)
+}
@@ -0,0 +1,139 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/services"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBookmarkRepository は BookmarkRepository のモック
+type MockBookmarkRepository struct {
+	mock.Mock
+}
+
+func (m *MockBookmarkRepository) CreateBookmark(ctx context.Context, userID, messageID int) error {
+	args := m.Called(userID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockBookmarkRepository) DeleteBookmark(ctx context.Context, userID, messageID int) error {
+	args := m.Called(userID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockBookmarkRepository) ListBookmarks(ctx context.Context, userID, beforeID, limit int) ([]models.Bookmark, error) {
+	args := m.Called(userID, beforeID, limit)
+	return args.Get(0).([]models.Bookmark), args.Error(1)
+}
+
+func newBookmarkService() (services.BookmarkService, *MockBookmarkRepository, *MockMessageRepository, *MockUserRepository) {
+	repo := new(MockBookmarkRepository)
+	messageRepo := new(MockMessageRepository)
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	return services.NewBookmarkService(repo, messageRepo, userRepo), repo, messageRepo, userRepo
+}
+
+func TestSaveMessage(t *testing.T) {
+	service, repo, messageRepo, _ := newBookmarkService()
+
+	messageRepo.On("GetMessage", 10, 1).Return(models.Message{ID: 10, SpaceID: 1}, nil)
+	repo.On("CreateBookmark", 7, 10).Return(nil).Once()
+
+	assert.NoError(t, service.SaveMessage(context.Background(), "alice", 1, 10))
+
+	repo.AssertExpectations(t)
+}
+
+// 別のスペースのメッセージ・削除済みのメッセージは保存できない
+func TestSaveMessage_MessageNotFound(t *testing.T) {
+	service, repo, messageRepo, _ := newBookmarkService()
+
+	messageRepo.On("GetMessage", 10, 2).Return(models.Message{}, fmt.Errorf("%w: record not found", apperrors.ErrNotFound))
+	messageRepo.On("GetMessage", 11, 1).Return(models.Message{ID: 11, SpaceID: 1}, nil)
+	repo.On("CreateBookmark", 7, 11).Return(fmt.Errorf("%w: foreign key", apperrors.ErrNotFound))
+
+	err := service.SaveMessage(context.Background(), "alice", 2, 10)
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+
+	err = service.SaveMessage(context.Background(), "alice", 1, 11)
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+
+	repo.AssertNotCalled(t, "CreateBookmark", 7, 10)
+}
+
+func TestRemoveBookmark(t *testing.T) {
+	service, repo, messageRepo, _ := newBookmarkService()
+
+	messageRepo.On("GetMessage", 10, 1).Return(models.Message{ID: 10, SpaceID: 1}, nil)
+	repo.On("DeleteBookmark", 7, 10).Return(nil).Once()
+
+	assert.NoError(t, service.RemoveBookmark(context.Background(), "alice", 1, 10))
+
+	repo.AssertExpectations(t)
+}
+
+func TestRemoveBookmark_NotFound(t *testing.T) {
+	service, repo, messageRepo, _ := newBookmarkService()
+
+	messageRepo.On("GetMessage", 10, 1).Return(models.Message{ID: 10, SpaceID: 1}, nil)
+	repo.On("DeleteBookmark", 7, 10).Return(fmt.Errorf("%w: not found", apperrors.ErrNotFound))
+
+	err := service.RemoveBookmark(context.Background(), "alice", 1, 10)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+}
+
+// 別のスペースのメッセージの保存は取り消さない
+func TestRemoveBookmark_SpaceMismatch(t *testing.T) {
+	service, repo, messageRepo, _ := newBookmarkService()
+
+	messageRepo.On("GetMessage", 10, 999).Return(models.Message{}, fmt.Errorf("%w: record not found", apperrors.ErrNotFound))
+
+	err := service.RemoveBookmark(context.Background(), "alice", 999, 10)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+
+	repo.AssertNotCalled(t, "DeleteBookmark", mock.Anything, mock.Anything)
+}
+
+// 1件多く取得できたら次のページのカーソルを返す
+func TestListBookmarks_Pagination(t *testing.T) {
+	service, repo, _, _ := newBookmarkService()
+
+	repo.On("ListBookmarks", 7, 0, 3).Return([]models.Bookmark{{ID: 30}, {ID: 25}, {ID: 21}}, nil).Once()
+	repo.On("ListBookmarks", 7, 25, 3).Return([]models.Bookmark{{ID: 21}}, nil).Once()
+
+	page, err := service.ListBookmarks(context.Background(), "alice", 0, 2)
+	assert.NoError(t, err)
+	assert.Len(t, page.Bookmarks, 2)
+	assert.Equal(t, 25, page.NextBefore)
+
+	page, err = service.ListBookmarks(context.Background(), "alice", page.NextBefore, 2)
+	assert.NoError(t, err)
+	assert.Len(t, page.Bookmarks, 1)
+	assert.Zero(t, page.NextBefore)
+
+	repo.AssertExpectations(t)
+}
+
+// limit を省略すると既定の件数、上限を超える値は上限に丸める
+func TestListBookmarks_Limit(t *testing.T) {
+	service, repo, _, _ := newBookmarkService()
+
+	repo.On("ListBookmarks", 7, 0, 51).Return([]models.Bookmark(nil), nil).Once()
+	repo.On("ListBookmarks", 7, 0, 101).Return([]models.Bookmark(nil), nil).Once()
+
+	page, err := service.ListBookmarks(context.Background(), "alice", 0, 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, page.Bookmarks)
+
+	_, err = service.ListBookmarks(context.Background(), "alice", 0, 500)
+	assert.NoError(t, err)
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"chat/webhook"
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ボットのコマンド名（小文字の英字で始まる英数字と _ -）
+var botCommandPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+type botService struct {
+	Repo     repositories.BotRepository
+	UserRepo repositories.UserRepository
+	// 組み込みのコマンドと同じ名前は登録できない（ボットより優先されるため）
+	Commands CommandService
+}
+
+func NewBotService(repo repositories.BotRepository, userRepo repositories.UserRepository, commands CommandService) BotService {
+	return &botService{Repo: repo, UserRepo: userRepo, Commands: commands}
+}
+
+// ボットを登録する。署名の鍵はここで生成し、返したボットの Secret でのみ渡す
+func (s *botService) CreateBot(ctx context.Context, actor string, bot models.Bot) (created models.Bot, err error) {
+	ctx, span := tracing.Start(ctx, "BotService.CreateBot", attribute.String("command", bot.Command))
+	defer func() { tracing.End(span, err) }()
+
+	if !validBot(bot) {
+		return created, ErrBotInvalid
+	}
+	user, err := authorizeAdmin(ctx, s.UserRepo, actor)
+	if err != nil {
+		return created, err
+	}
+	if s.Commands.Builtin(bot.Command) {
+		return created, ErrBotCommandTaken
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return created, err
+	}
+	created, err = s.Repo.CreateBot(ctx, models.Bot{
+		Name:        bot.Name,
+		Command:     bot.Command,
+		CallbackURL: bot.CallbackURL,
+		Secret:      secret,
+		CreatedBy:   &user.ID,
+	})
+	if errors.Is(err, apperrors.ErrConflict) {
+		return created, ErrBotCommandTaken.Wrap(err)
+	}
+	return created, err
+}
+
+// ボット一覧
+func (s *botService) ListBots(ctx context.Context, actor string) (bots []models.Bot, err error) {
+	ctx, span := tracing.Start(ctx, "BotService.ListBots")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeAdmin(ctx, s.UserRepo, actor); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListBots(ctx)
+}
+
+// ボットを削除する（以後そのコマンドは不明なコマンドになる）
+func (s *botService) DeleteBot(ctx context.Context, actor string, botID int) (err error) {
+	ctx, span := tracing.Start(ctx, "BotService.DeleteBot", attribute.Int("bot_id", botID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeAdmin(ctx, s.UserRepo, actor); err != nil {
+		return err
+	}
+	err = s.Repo.DeleteBot(ctx, botID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrBotNotFound.Wrap(err)
+	}
+	return err
+}
+
+func validBot(bot models.Bot) bool {
+	if bot.Name == "" || !botCommandPattern.MatchString(bot.Command) {
+		return false
+	}
+	u, err := url.Parse(bot.CallbackURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"chat/models"
+	"context"
+)
+
+// スラッシュコマンドに HTTP コールバックで応答するボットの管理（管理者のみ）
+// 登録したボットは CommandService が同じ名前のコマンドで呼び出す。
+type BotService interface {
+	CreateBot(ctx context.Context, actor string, bot models.Bot) (models.Bot, error)
+	ListBots(ctx context.Context, actor string) ([]models.Bot, error)
+	DeleteBot(ctx context.Context, actor string, botID int) error
+}
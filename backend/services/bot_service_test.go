@@ -0,0 +1,68 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/services"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateBot(t *testing.T) {
+	f := newCommandFixture()
+	service := services.NewBotService(f.bots, f.users, f.service)
+
+	f.users.On("GetUserByUsername", "admin").Return(models.User{ID: 1, Username: "admin", Role: models.RoleAdmin}, nil)
+	f.users.On("GetUserByUsername", "bob").Return(models.User{ID: 8, Username: "bob", Role: models.RoleMember}, nil)
+	f.bots.On("CreateBot", mock.MatchedBy(func(b models.Bot) bool {
+		return b.Command == "deploy" && b.Secret != "" && *b.CreatedBy == 1
+	})).Return(models.Bot{ID: 3, Command: "deploy", Secret: "generated"}, nil).Once()
+
+	input := models.Bot{Name: "Deploy", Command: "deploy", CallbackURL: "https://ci.example.com/hook"}
+	bot, err := service.CreateBot(context.Background(), "admin", input)
+	assert.NoError(t, err)
+	assert.Equal(t, "generated", bot.Secret)
+
+	// 管理者以外は登録できない
+	_, err = service.CreateBot(context.Background(), "bob", input)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	// 組み込みのコマンドと同じ名前・不正な URL
+	builtin := input
+	builtin.Command = "topic"
+	_, err = service.CreateBot(context.Background(), "admin", builtin)
+	assert.ErrorIs(t, err, services.ErrBotCommandTaken)
+
+	invalid := input
+	invalid.CallbackURL = "ftp://ci.example.com"
+	_, err = service.CreateBot(context.Background(), "admin", invalid)
+	assert.ErrorIs(t, err, services.ErrBotInvalid)
+
+	f.bots.AssertExpectations(t)
+}
+
+// 登録済みのコマンド名は DB の一意制約で拒否する
+func TestCreateBot_Conflict(t *testing.T) {
+	f := newCommandFixture()
+	service := services.NewBotService(f.bots, f.users, f.service)
+
+	f.users.On("GetUserByUsername", "admin").Return(models.User{ID: 1, Username: "admin", Role: models.RoleAdmin}, nil)
+	f.bots.On("CreateBot", mock.AnythingOfType("models.Bot")).Return(models.Bot{}, apperrors.ErrConflict)
+
+	_, err := service.CreateBot(context.Background(), "admin", models.Bot{Name: "Deploy", Command: "deploy", CallbackURL: "https://ci.example.com/hook"})
+	assert.ErrorIs(t, err, services.ErrBotCommandTaken)
+}
+
+func TestDeleteBot_NotFound(t *testing.T) {
+	f := newCommandFixture()
+	service := services.NewBotService(f.bots, f.users, f.service)
+
+	f.users.On("GetUserByUsername", "admin").Return(models.User{ID: 1, Username: "admin", Role: models.RoleAdmin}, nil)
+	f.bots.On("DeleteBot", 9).Return(apperrors.ErrNotFound)
+
+	err := service.DeleteBot(context.Background(), "admin", 9)
+	assert.ErrorIs(t, err, services.ErrBotNotFound)
+}
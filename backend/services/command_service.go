@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"chat/apperrors"
+	"chat/config"
+	"chat/i18n"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"chat/webhook"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// トピックの最大文字数
+	maxTopicLength = 250
+	// ボットの応答として読み込む最大サイズ
+	botResponseLimit = 64 << 10
+	// ボットが投稿できる最大文字数（メッセージの投稿と同じ）
+	maxBotTextLength = 2000
+	// コマンドがスペースに投稿するメッセージの言語
+	// スペースの全員が読むため、実行したユーザーの言語ではなくスペースの既定（スペースごとの設定はないためサーバーの既定）にする。
+	spaceLanguage = i18n.DefaultLanguage
+)
+
+type command struct {
+	usage string
+	run   CommandFunc
+}
+
+type commandService struct {
+	Messages  MessageService
+	SpaceRepo repositories.SpaceRepository
+	UserRepo  repositories.UserRepository
+	BotRepo   repositories.BotRepository
+	Client    *http.Client
+	Logger    *slog.Logger
+
+	commands map[string]command
+	// /help で表示する順序（登録順）
+	order []string
+}
+
+// 組み込みのコマンド（/help /me /topic /invite）を登録したコマンドサービスを返す
+// ボットへの問い合わせは Webhook と同じ設定（タイムアウト・プライベートアドレスの拒否）で送る。
+func NewCommandService(messages MessageService, spaceRepo repositories.SpaceRepository, userRepo repositories.UserRepository, botRepo repositories.BotRepository, cfg config.WebhookConfig, logger *slog.Logger) CommandService {
+	s := &commandService{
+		Messages:  messages,
+		SpaceRepo: spaceRepo,
+		UserRepo:  userRepo,
+		BotRepo:   botRepo,
+		Client:    webhook.NewClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+		Logger:    logger,
+		commands:  make(map[string]command),
+	}
+	s.Register("help", "command.usage.help", s.help)
+	s.Register("me", "command.usage.me", s.me)
+	s.Register("topic", "command.usage.topic", s.topic)
+	s.Register("invite", "command.usage.invite", s.invite)
+	return s
+}
+
+func (s *commandService) Register(name, usage string, fn CommandFunc) {
+	if _, ok := s.commands[name]; !ok {
+		s.order = append(s.order, name)
+	}
+	s.commands[name] = command{usage: usage, run: fn}
+}
+
+func (s *commandService) Builtin(name string) bool {
+	_, ok := s.commands[name]
+	return ok
+}
+
+// コマンドを実行する。組み込みのコマンドになければ同じ名前のボットに問い合わせる
+func (s *commandService) Execute(ctx context.Context, call CommandCall) (res CommandResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CommandService.Execute", attribute.String("command", call.Name), attribute.Int("space_id", call.SpaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if cmd, ok := s.commands[call.Name]; ok {
+		res, err = cmd.run(ctx, call)
+	} else {
+		res, err = s.callBot(ctx, call)
+	}
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	res.Command = call.Name
+	return res, nil
+}
+
+// /help
+func (s *commandService) help(ctx context.Context, call CommandCall) (CommandResponse, error) {
+	lines := make([]string, 0, len(s.order))
+	for _, name := range s.order {
+		lines = append(lines, i18n.T(call.Language, s.commands[name].usage))
+	}
+
+	bots, err := s.BotRepo.ListBots(ctx)
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	for _, bot := range bots {
+		lines = append(lines, i18n.T(call.Language, "command.usage.bot", bot.Command, bot.Name))
+	}
+	return ephemeral(strings.Join(lines, "\n")), nil
+}
+
+// /me <動作>
+// 他のユーザーになりすませないよう、認証済みのユーザーとして投稿する。
+func (s *commandService) me(ctx context.Context, call CommandCall) (CommandResponse, error) {
+	if call.Actor == "" {
+		return ephemeral(i18n.T(call.Language, "command.login_required", call.Name)), nil
+	}
+	if call.Args == "" {
+		return ephemeral(i18n.T(call.Language, s.commands["me"].usage)), nil
+	}
+	return s.post(ctx, call.SpaceID, call.Actor, fmt.Sprintf("* %s %s", call.Actor, call.Args))
+}
+
+// /topic [トピック]
+// 引数がなければ現在のトピックを返す。変更はスペースの所有者か管理者のみ。
+func (s *commandService) topic(ctx context.Context, call CommandCall) (CommandResponse, error) {
+	if call.Args == "" {
+		space, err := s.SpaceRepo.GetSpaceByID(ctx, call.SpaceID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return CommandResponse{}, ErrSpaceNotFound.Wrap(err)
+		}
+		if err != nil {
+			return CommandResponse{}, err
+		}
+		if space.Topic == "" {
+			return ephemeral(i18n.T(call.Language, "command.no_topic")), nil
+		}
+		return ephemeral(i18n.T(call.Language, "command.topic", space.Topic)), nil
+	}
+
+	if utf8.RuneCountInString(call.Args) > maxTopicLength {
+		return CommandResponse{}, ErrTopicInvalid
+	}
+	user, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, call.Actor, call.SpaceID)
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	err = s.SpaceRepo.UpdateSpaceTopic(ctx, call.SpaceID, call.Args)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return CommandResponse{}, ErrSpaceNotFound.Wrap(err)
+	}
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	return s.post(ctx, call.SpaceID, user.Username, i18n.T(spaceLanguage, "command.topic_changed", call.Args))
+}
+
+// /invite @<ユーザー>
+// スペースは誰でも参加できるため、招待は相手へのメンションとして認証済みのユーザーが投稿する。
+func (s *commandService) invite(ctx context.Context, call CommandCall) (CommandResponse, error) {
+	if call.Actor == "" {
+		return ephemeral(i18n.T(call.Language, "command.login_required", call.Name)), nil
+	}
+	username := strings.TrimPrefix(call.Args, "@")
+	if username == "" || strings.ContainsAny(username, " \t\n") {
+		return ephemeral(i18n.T(call.Language, s.commands["invite"].usage)), nil
+	}
+
+	if _, err := s.UserRepo.GetUserByUsername(ctx, username); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return ephemeral(i18n.T(call.Language, "command.user_not_found", username)), nil
+		}
+		return CommandResponse{}, err
+	}
+	return s.post(ctx, call.SpaceID, call.Actor, i18n.T(spaceLanguage, "command.invited", username))
+}
+
+// ボットへの問い合わせ
+type botRequest struct {
+	Command  string `json:"command"`
+	Text     string `json:"text"`
+	SpaceID  int    `json:"space_id"`
+	Username string `json:"username"`
+	// 認証済みのユーザー（未ログインなら省略）
+	Actor string `json:"actor,omitempty"`
+}
+
+// ボットの応答
+type botResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// コマンド名のボットに署名付きで問い合わせ、応答を返す
+// ボットに届かない・応答が不正な場合もエラーにはせず、実行したユーザーにだけ知らせる。
+func (s *commandService) callBot(ctx context.Context, call CommandCall) (CommandResponse, error) {
+	bot, err := s.BotRepo.GetBotByCommand(ctx, call.Name)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ephemeral(i18n.T(call.Language, "command.unknown", call.Name)), nil
+	}
+	if err != nil {
+		return CommandResponse{}, err
+	}
+
+	reply, err := s.send(ctx, bot, call)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "ボットの応答エラー", "bot_id", bot.ID, "command", bot.Command, "error", err)
+		return ephemeral(i18n.T(call.Language, "command.bot_no_response", bot.Name)), nil
+	}
+
+	if reply.ResponseType != ResponsePublic || reply.Text == "" {
+		return ephemeral(reply.Text), nil
+	}
+	msg, err := s.Messages.CreateBotMessage(ctx, models.Message{SpaceID: call.SpaceID, Username: bot.Name, Text: reply.Text, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	return CommandResponse{ResponseType: ResponsePublic, Text: msg.Text, Message: &msg}, nil
+}
+
+func (s *commandService) send(ctx context.Context, bot models.Bot, call CommandCall) (botResponse, error) {
+	var reply botResponse
+	body, err := json.Marshal(botRequest{
+		Command:  call.Name,
+		Text:     call.Args,
+		SpaceID:  call.SpaceID,
+		Username: call.Username,
+		Actor:    call.Actor,
+	})
+	if err != nil {
+		return reply, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, bot.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return reply, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chat-webhook/1")
+	req.Header.Set(webhook.HeaderEvent, "command")
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(bot.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return reply, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return reply, fmt.Errorf("ボットが %d を返しました", resp.StatusCode)
+	}
+	// 空の応答は受け付けたことだけを示す
+	data, err := io.ReadAll(io.LimitReader(resp.Body, botResponseLimit))
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return reply, err
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return reply, fmt.Errorf("ボットの応答を解析できません: %w", err)
+	}
+	if utf8.RuneCountInString(reply.Text) > maxBotTextLength {
+		return reply, errors.New("ボットの応答が長すぎます")
+	}
+	return reply, nil
+}
+
+// 指定した名前でスペースに投稿し、公開の応答にする
+func (s *commandService) post(ctx context.Context, spaceID int, username, text string) (CommandResponse, error) {
+	msg, err := s.Messages.CreateMessage(ctx, models.Message{SpaceID: spaceID, Username: username, Text: text, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	return CommandResponse{ResponseType: ResponsePublic, Text: text, Message: &msg}, nil
+}
+
+func ephemeral(text string) CommandResponse {
+	return CommandResponse{ResponseType: ResponseEphemeral, Text: text}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"chat/models"
+	"context"
+	"regexp"
+	"strings"
+)
+
+// コマンドの応答の種類
+const (
+	// 実行したユーザーにだけ返す（保存・配信しない）
+	ResponseEphemeral = "ephemeral"
+	// スペースにメッセージとして投稿する
+	ResponsePublic = "public"
+)
+
+// スラッシュコマンドの呼び出し
+type CommandCall struct {
+	// "/" を除いたコマンド名（小文字）
+	Name string
+	// コマンド名の後ろの文字列（前後の空白は除く）
+	Args    string
+	SpaceID int
+	// 認証済みのユーザー名（未ログインなら空）。権限が必要なコマンドはこのユーザーで確認する
+	Actor string
+	// メッセージの投稿者名（認証していない値）。ボットへの問い合わせにだけ渡し、組み込みのコマンドは Actor として投稿する
+	Username string
+	// 実行したユーザーの言語（ja / en）。自分だけに見える応答はこの言語で返す（空ならデフォルト言語）
+	Language string
+}
+
+// コマンドの応答
+type CommandResponse struct {
+	Command      string `json:"command"`
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text,omitempty"`
+	// 公開の応答として投稿したメッセージ（配信済み）
+	Message *models.Message `json:"message,omitempty"`
+}
+
+// コマンドの処理
+type CommandFunc func(ctx context.Context, call CommandCall) (CommandResponse, error)
+
+// "/" で始まるメッセージを保存する前に実行するコマンドの一覧
+// 組み込みのコマンドを優先し、登録されていない名前はボット（BotService）に HTTP で問い合わせる。
+type CommandService interface {
+	// コマンドを追加する（起動時、リクエストを受け付ける前に呼ぶ）
+	// usage は /help に表示する説明の i18n のキー（辞書にない場合はそのまま表示する）。
+	Register(name, usage string, fn CommandFunc)
+	// 登録済みのコマンドか（同じ名前のボットは呼び出されない）
+	Builtin(name string) bool
+	Execute(ctx context.Context, call CommandCall) (CommandResponse, error)
+}
+
+var commandPattern = regexp.MustCompile(`(?s)^/([A-Za-z][A-Za-z0-9_-]*)(?:\s+(.*))?$`)
+
+// メッセージがコマンドならコマンドの呼び出しを返す
+// "/path/to" のように名前の後ろに空白がないものは通常のメッセージとして扱う。
+func ParseCommand(msg models.Message, actor string) (CommandCall, bool) {
+	m := commandPattern.FindStringSubmatch(msg.Text)
+	if m == nil {
+		return CommandCall{}, false
+	}
+	return CommandCall{
+		Name:     strings.ToLower(m[1]),
+		Args:     strings.TrimSpace(m[2]),
+		SpaceID:  msg.SpaceID,
+		Actor:    actor,
+		Username: msg.Username,
+	}, true
+}
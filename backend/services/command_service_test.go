@@ -0,0 +1,273 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/logging"
+	"chat/models"
+	"chat/services"
+	"chat/webhook"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBotRepository は BotRepository のモック
+type MockBotRepository struct {
+	mock.Mock
+}
+
+func (m *MockBotRepository) CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error) {
+	args := m.Called(bot)
+	return args.Get(0).(models.Bot), args.Error(1)
+}
+
+func (m *MockBotRepository) ListBots(ctx context.Context) ([]models.Bot, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Bot), args.Error(1)
+}
+
+func (m *MockBotRepository) GetBotByCommand(ctx context.Context, command string) (models.Bot, error) {
+	args := m.Called(command)
+	return args.Get(0).(models.Bot), args.Error(1)
+}
+
+func (m *MockBotRepository) DeleteBot(ctx context.Context, id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type commandFixture struct {
+	messages *MockBotMessageService
+	spaces   *MockSpaceRepository
+	users    *MockUserRepository
+	bots     *MockBotRepository
+	service  services.CommandService
+}
+
+func newCommandFixture() commandFixture {
+	f := commandFixture{
+		messages: new(MockBotMessageService),
+		spaces:   new(MockSpaceRepository),
+		users:    new(MockUserRepository),
+		bots:     new(MockBotRepository),
+	}
+	cfg := config.Default().Webhook
+	cfg.AllowPrivateNetworks = true
+	f.service = services.NewCommandService(f.messages, f.spaces, f.users, f.bots, cfg, logging.Discard())
+	return f
+}
+
+func TestParseCommand(t *testing.T) {
+	call, ok := services.ParseCommand(models.Message{SpaceID: 1, Username: "alice", Text: "/Topic  リリース準備 \n"}, "alice")
+	assert.True(t, ok)
+	assert.Equal(t, services.CommandCall{Name: "topic", Args: "リリース準備", SpaceID: 1, Actor: "alice", Username: "alice"}, call)
+
+	call, ok = services.ParseCommand(models.Message{SpaceID: 1, Username: "alice", Text: "/help"}, "")
+	assert.True(t, ok)
+	assert.Equal(t, "help", call.Name)
+
+	// パスや "/" だけのメッセージはそのまま投稿する
+	for _, text := range []string{"/usr/local/bin", "/", "/ hello", "hello /me"} {
+		_, ok := services.ParseCommand(models.Message{Text: text}, "")
+		assert.False(t, ok, text)
+	}
+}
+
+// /me は認証済みのユーザーの投稿として保存・配信する
+func TestCommand_Me(t *testing.T) {
+	f := newCommandFixture()
+	f.messages.On("CreateMessage", mock.MatchedBy(func(m models.Message) bool {
+		return m.SpaceID == 1 && m.Username == "alice" && m.Text == "* alice waves" && !m.Bot
+	})).Return(models.Message{ID: 10}, nil).Once()
+
+	// 投稿者名ではなく認証済みのユーザーとして投稿する
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "me", Args: "waves", SpaceID: 1, Actor: "alice", Username: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, "me", res.Command)
+	assert.Equal(t, services.ResponsePublic, res.ResponseType)
+	assert.Equal(t, 10, res.Message.ID)
+
+	// 引数がなければ使い方を本人にだけ返す
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "me", SpaceID: 1, Actor: "alice", Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+
+	// 未ログインでは他のユーザーとして投稿できない
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "me", Args: "waves", SpaceID: 1, Username: "alice", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Equal(t, "Log in to use /me", res.Text)
+
+	f.messages.AssertExpectations(t)
+}
+
+// トピックの変更はスペースの所有者（認証済み）のみ
+func TestCommand_Topic(t *testing.T) {
+	f := newCommandFixture()
+	f.users.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	f.users.On("GetUserByUsername", "bob").Return(models.User{ID: 8, Username: "bob"}, nil)
+	f.users.On("GetUserByUsername", "").Return(models.User{}, apperrors.ErrNotFound)
+	f.spaces.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+	f.spaces.On("UpdateSpaceTopic", 1, "リリース準備").Return(nil).Once()
+	f.messages.On("CreateMessage", mock.MatchedBy(func(m models.Message) bool {
+		return m.Username == "alice" && m.Text == "トピックを「リリース準備」に変更しました"
+	})).Return(models.Message{ID: 11}, nil).Once()
+
+	// スペースへの投稿は実行したユーザーの言語ではなく、スペースの既定の言語にする
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "topic", Args: "リリース準備", SpaceID: 1, Actor: "alice", Username: "alice", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponsePublic, res.ResponseType)
+
+	// 所有者以外・未ログインでは変更できない（投稿者名は権限に使わない）
+	_, err = f.service.Execute(context.Background(), services.CommandCall{Name: "topic", Args: "乗っ取り", SpaceID: 1, Actor: "bob", Username: "bob"})
+	assert.ErrorIs(t, err, services.ErrForbidden)
+	_, err = f.service.Execute(context.Background(), services.CommandCall{Name: "topic", Args: "乗っ取り", SpaceID: 1, Username: "alice"})
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	// 引数がなければ現在のトピックを返す
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "topic", SpaceID: 1, Username: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Equal(t, "トピックは設定されていません", res.Text)
+
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "topic", SpaceID: 1, Username: "bob", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "No topic is set", res.Text)
+
+	f.spaces.AssertExpectations(t)
+	f.messages.AssertExpectations(t)
+}
+
+func TestCommand_Invite(t *testing.T) {
+	f := newCommandFixture()
+	f.users.On("GetUserByUsername", "bob").Return(models.User{ID: 8, Username: "bob"}, nil)
+	f.users.On("GetUserByUsername", "ghost").Return(models.User{}, apperrors.ErrNotFound)
+	f.messages.On("CreateMessage", mock.MatchedBy(func(m models.Message) bool {
+		return m.Username == "alice" && m.Text == "@bob をこのスペースに招待しました"
+	})).Return(models.Message{ID: 12}, nil).Once()
+
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "invite", Args: "@bob", SpaceID: 1, Actor: "alice", Username: "mallory", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponsePublic, res.ResponseType)
+
+	// 未ログインでは招待を投稿しない
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "invite", Args: "@bob", SpaceID: 1, Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/invite を使うにはログインしてください", res.Text)
+
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "invite", Args: "@ghost", SpaceID: 1, Actor: "alice", Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Equal(t, "ユーザー @ghost が見つかりません", res.Text)
+
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "invite", Args: "@ghost", SpaceID: 1, Actor: "alice", Username: "alice", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "User @ghost not found", res.Text)
+
+	f.messages.AssertExpectations(t)
+}
+
+func TestCommand_Unknown(t *testing.T) {
+	f := newCommandFixture()
+	f.bots.On("GetBotByCommand", "deploy").Return(models.Bot{}, apperrors.ErrNotFound)
+
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "deploy", SpaceID: 1, Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Contains(t, res.Text, "/deploy")
+
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "deploy", SpaceID: 1, Username: "alice", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "There is no command /deploy (type /help to list commands)", res.Text)
+	f.messages.AssertNotCalled(t, "CreateMessage", mock.Anything)
+}
+
+// 登録したコマンドは /help に表示され、ボットより優先される
+func TestCommand_RegisterAndHelp(t *testing.T) {
+	f := newCommandFixture()
+	f.service.Register("ping", "/ping — 応答を返す", func(ctx context.Context, call services.CommandCall) (services.CommandResponse, error) {
+		return services.CommandResponse{ResponseType: services.ResponseEphemeral, Text: "pong"}, nil
+	})
+	f.bots.On("ListBots").Return([]models.Bot{{Name: "Deploy", Command: "deploy"}}, nil)
+
+	assert.True(t, f.service.Builtin("ping"))
+	assert.True(t, f.service.Builtin("topic"))
+	assert.False(t, f.service.Builtin("deploy"))
+
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "ping", SpaceID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, services.CommandResponse{Command: "ping", ResponseType: services.ResponseEphemeral, Text: "pong"}, res)
+
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "help", SpaceID: 1})
+	assert.NoError(t, err)
+	assert.Contains(t, res.Text, "/ping — 応答を返す")
+	assert.Contains(t, res.Text, "/help — 使えるコマンドの一覧")
+	assert.Contains(t, res.Text, "/deploy — Deploy（ボット）")
+
+	// 一覧は実行したユーザーの言語で返す（辞書にない説明はそのまま）
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "help", SpaceID: 1, Language: "en"})
+	assert.NoError(t, err)
+	assert.Contains(t, res.Text, "/ping — 応答を返す")
+	assert.Contains(t, res.Text, "/help — List available commands")
+	assert.Contains(t, res.Text, "/deploy — Deploy (bot)")
+	f.bots.AssertNotCalled(t, "GetBotByCommand", "ping")
+}
+
+// ボットには署名付きで問い合わせ、公開の応答はボットの名前で投稿する
+func TestCommand_BotCallback(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if !webhook.Verify("s3cret", ts, body, r.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"response_type":"public","text":"production にデプロイしました"}`))
+	}))
+	defer server.Close()
+
+	f := newCommandFixture()
+	f.bots.On("GetBotByCommand", "deploy").Return(models.Bot{ID: 3, Name: "Deploy", Command: "deploy", CallbackURL: server.URL, Secret: "s3cret"}, nil)
+	f.messages.On("CreateBotMessage", mock.MatchedBy(func(m models.Message) bool {
+		return m.SpaceID == 1 && m.Username == "Deploy" && m.Text == "production にデプロイしました"
+	})).Return(models.Message{ID: 20}, nil).Once()
+
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "deploy", Args: "production", SpaceID: 1, Actor: "alice", Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponsePublic, res.ResponseType)
+	assert.Equal(t, 20, res.Message.ID)
+	assert.Equal(t, map[string]any{"command": "deploy", "text": "production", "space_id": 1.0, "username": "alice", "actor": "alice"}, received)
+
+	f.messages.AssertExpectations(t)
+}
+
+// ボットが失敗してもエラーにはせず、本人にだけ知らせる
+func TestCommand_BotFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	f := newCommandFixture()
+	f.bots.On("GetBotByCommand", "deploy").Return(models.Bot{ID: 3, Name: "Deploy", Command: "deploy", CallbackURL: server.URL, Secret: "s3cret"}, nil)
+
+	res, err := f.service.Execute(context.Background(), services.CommandCall{Name: "deploy", SpaceID: 1, Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Equal(t, "Deploy から応答がありませんでした", res.Text)
+
+	res, err = f.service.Execute(context.Background(), services.CommandCall{Name: "deploy", SpaceID: 1, Username: "alice", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "No response from Deploy", res.Text)
+	f.messages.AssertNotCalled(t, "CreateBotMessage", mock.Anything)
+}
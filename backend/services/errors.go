@@ -0,0 +1,27 @@
+package services
+
+import "chat/apperrors"
+
+// サービス層のドメインエラー
+var (
+	ErrMessageInvalid     = apperrors.New(apperrors.ErrValidation, "message_invalid", "メッセージまたはユーザー名が空です")
+	ErrMessageIDInvalid   = apperrors.New(apperrors.ErrValidation, "message_id_invalid", "メッセージIDまたはスペースIDが無効です")
+	ErrMessageNotFound    = apperrors.New(apperrors.ErrNotFound, "message_not_found", "メッセージが見つかりませんでした")
+	ErrSpaceNotFound      = apperrors.New(apperrors.ErrNotFound, "space_not_found", "スペースが見つかりません")
+	ErrUserNotFound       = apperrors.New(apperrors.ErrNotFound, "user_not_found", "ユーザーが見つかりません")
+	ErrUsernameTaken      = apperrors.New(apperrors.ErrConflict, "username_taken", "ユーザー名が既に使用されています")
+	ErrInvalidCredentials = apperrors.New(apperrors.ErrUnauthorized, "invalid_credentials", "認証失敗")
+	ErrInvalidToken       = apperrors.New(apperrors.ErrUnauthorized, "invalid_token", "トークンが無効です")
+	ErrAccountLocked      = apperrors.New(apperrors.ErrRateLimited, "account_locked", "ログイン失敗が続いたため一時的にロックされています")
+	ErrForbidden          = apperrors.New(apperrors.ErrForbidden, "forbidden", "この操作を行う権限がありません")
+	ErrWebhookNotFound    = apperrors.New(apperrors.ErrNotFound, "webhook_not_found", "Webhook が見つかりません")
+	ErrWebhookInvalid     = apperrors.New(apperrors.ErrValidation, "webhook_invalid", "URL または通知するイベントが無効です")
+	ErrHookTokenInvalid   = apperrors.New(apperrors.ErrUnauthorized, "hook_token_invalid", "Webhook のトークンが無効です")
+	ErrTopicInvalid       = apperrors.New(apperrors.ErrValidation, "topic_invalid", "トピックが長すぎます")
+	ErrBotNotFound        = apperrors.New(apperrors.ErrNotFound, "bot_not_found", "ボットが見つかりません")
+	ErrBotInvalid         = apperrors.New(apperrors.ErrValidation, "bot_invalid", "コマンド名またはコールバック URL が無効です")
+	ErrBotCommandTaken    = apperrors.New(apperrors.ErrConflict, "bot_command_taken", "コマンド名が既に使用されています")
+	ErrScheduleInvalid    = apperrors.New(apperrors.ErrValidation, "schedule_invalid", "送信日時・種類・本文のいずれかが無効です")
+	ErrScheduleNotFound   = apperrors.New(apperrors.ErrNotFound, "schedule_not_found", "予約が見つかりません")
+	ErrBookmarkNotFound   = apperrors.New(apperrors.ErrNotFound, "bookmark_not_found", "保存したメッセージが見つかりません")
+)
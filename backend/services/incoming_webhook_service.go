@@ -0,0 +1,105 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"chat/webhook"
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type incomingWebhookService struct {
+	Repo      repositories.IncomingWebhookRepository
+	SpaceRepo repositories.SpaceRepository
+	UserRepo  repositories.UserRepository
+	Messages  MessageService
+}
+
+func NewIncomingWebhookService(repo repositories.IncomingWebhookRepository, spaceRepo repositories.SpaceRepository, userRepo repositories.UserRepository, messages MessageService) IncomingWebhookService {
+	return &incomingWebhookService{Repo: repo, SpaceRepo: spaceRepo, UserRepo: userRepo, Messages: messages}
+}
+
+// 受信用 Webhook を作成する。トークンはここでのみ返し、DB にはハッシュを保存する
+func (s *incomingWebhookService) CreateIncomingWebhook(ctx context.Context, actor string, spaceID int, name string) (hook models.IncomingWebhook, token string, err error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.CreateIncomingWebhook", attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID)
+	if err != nil {
+		return hook, "", err
+	}
+
+	token, err = webhook.NewSecret()
+	if err != nil {
+		return hook, "", err
+	}
+	hook, err = s.Repo.CreateIncomingWebhook(ctx, models.IncomingWebhook{
+		SpaceID:   spaceID,
+		Name:      name,
+		TokenHash: webhook.HashToken(token),
+		CreatedBy: &user.ID,
+	})
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return hook, "", ErrSpaceNotFound.Wrap(err)
+	}
+	if err != nil {
+		return hook, "", err
+	}
+	return hook, token, nil
+}
+
+// スペースの受信用 Webhook 一覧
+func (s *incomingWebhookService) ListIncomingWebhooks(ctx context.Context, actor string, spaceID int) (hooks []models.IncomingWebhook, err error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.ListIncomingWebhooks", attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListIncomingWebhooks(ctx, spaceID)
+}
+
+// 受信用 Webhook を削除する
+func (s *incomingWebhookService) DeleteIncomingWebhook(ctx context.Context, actor string, spaceID, hookID int) (err error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.DeleteIncomingWebhook", attribute.Int("space_id", spaceID), attribute.Int("hook_id", hookID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID); err != nil {
+		return err
+	}
+	err = s.Repo.DeleteIncomingWebhook(ctx, hookID, spaceID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrWebhookNotFound.Wrap(err)
+	}
+	return err
+}
+
+// トークンを確認し、Webhook のスペースにメッセージを投稿する
+// 表示名は username（省略時は Webhook の名前）。存在しない Webhook と誤ったトークンは区別しない。
+func (s *incomingWebhookService) PostMessage(ctx context.Context, hookID int, token, username, text string) (msg models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.PostMessage", attribute.Int("hook_id", hookID))
+	defer func() { tracing.End(span, err) }()
+
+	hook, err := s.Repo.GetIncomingWebhook(ctx, hookID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return msg, ErrHookTokenInvalid
+	}
+	if err != nil {
+		return msg, err
+	}
+	if token == "" || !webhook.VerifyToken(token, hook.TokenHash) {
+		return msg, ErrHookTokenInvalid
+	}
+
+	if username == "" {
+		username = hook.Name
+	}
+	// 保存した内容をそのまま返せるよう、投稿日時はここで決める
+	msg = models.Message{SpaceID: hook.SpaceID, Username: username, Text: text, Bot: true, CreatedAt: time.Now().UTC()}
+	return s.Messages.CreateBotMessage(ctx, msg)
+}
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"chat/models"
+	"context"
+)
+
+// 外部のシステムからの投稿を受け付ける受信用 Webhook
+// 管理（作成・一覧・削除）はスペースの所有者か管理者のみ。投稿はトークンで認証する。
+type IncomingWebhookService interface {
+	CreateIncomingWebhook(ctx context.Context, actor string, spaceID int, name string) (hook models.IncomingWebhook, token string, err error)
+	ListIncomingWebhooks(ctx context.Context, actor string, spaceID int) ([]models.IncomingWebhook, error)
+	DeleteIncomingWebhook(ctx context.Context, actor string, spaceID, hookID int) error
+	PostMessage(ctx context.Context, hookID int, token, username, text string) (models.Message, error)
+}
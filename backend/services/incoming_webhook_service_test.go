@@ -0,0 +1,119 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/services"
+	"chat/webhook"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIncomingWebhookRepository は IncomingWebhookRepository のモック
+type MockIncomingWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockIncomingWebhookRepository) CreateIncomingWebhook(ctx context.Context, hook models.IncomingWebhook) (models.IncomingWebhook, error) {
+	args := m.Called(hook)
+	return args.Get(0).(models.IncomingWebhook), args.Error(1)
+}
+
+func (m *MockIncomingWebhookRepository) ListIncomingWebhooks(ctx context.Context, spaceID int) ([]models.IncomingWebhook, error) {
+	args := m.Called(spaceID)
+	return args.Get(0).([]models.IncomingWebhook), args.Error(1)
+}
+
+func (m *MockIncomingWebhookRepository) GetIncomingWebhook(ctx context.Context, id int) (models.IncomingWebhook, error) {
+	args := m.Called(id)
+	return args.Get(0).(models.IncomingWebhook), args.Error(1)
+}
+
+func (m *MockIncomingWebhookRepository) DeleteIncomingWebhook(ctx context.Context, id, spaceID int) error {
+	args := m.Called(id, spaceID)
+	return args.Error(0)
+}
+
+// MockBotMessageService は受信用 Webhook・コマンドから使う MessageService のモック
+type MockBotMessageService struct {
+	services.MessageService
+	mock.Mock
+}
+
+func (m *MockBotMessageService) CreateMessage(ctx context.Context, msg models.Message) (models.Message, error) {
+	args := m.Called(msg)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+func (m *MockBotMessageService) CreateBotMessage(ctx context.Context, msg models.Message) (models.Message, error) {
+	args := m.Called(msg)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+// トークンは作成時にのみ返し、DB にはハッシュを保存する
+func TestCreateIncomingWebhook(t *testing.T) {
+	repo := new(MockIncomingWebhookRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	service := services.NewIncomingWebhookService(repo, spaceRepo, userRepo, new(MockBotMessageService))
+
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	userRepo.On("GetUserByUsername", "bob").Return(models.User{ID: 8, Username: "bob"}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+
+	var stored models.IncomingWebhook
+	repo.On("CreateIncomingWebhook", mock.AnythingOfType("models.IncomingWebhook")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(models.IncomingWebhook) }).
+		Return(models.IncomingWebhook{ID: 2, SpaceID: 1, Name: "CI"}, nil)
+
+	hook, token, err := service.CreateIncomingWebhook(context.Background(), "alice", 1, "CI")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, hook.ID)
+	assert.Len(t, token, 64)
+	assert.Equal(t, webhook.HashToken(token), stored.TokenHash)
+	assert.Equal(t, 7, *stored.CreatedBy)
+
+	_, _, err = service.CreateIncomingWebhook(context.Background(), "bob", 1, "CI")
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	repo.AssertNumberOfCalls(t, "CreateIncomingWebhook", 1)
+}
+
+func TestIncomingWebhook_PostMessage(t *testing.T) {
+	repo := new(MockIncomingWebhookRepository)
+	messages := new(MockBotMessageService)
+	service := services.NewIncomingWebhookService(repo, new(MockSpaceRepository), new(MockUserRepository), messages)
+
+	repo.On("GetIncomingWebhook", 2).Return(models.IncomingWebhook{ID: 2, SpaceID: 1, Name: "CI", TokenHash: webhook.HashToken("t0ken")}, nil)
+	repo.On("GetIncomingWebhook", 99).Return(models.IncomingWebhook{}, apperrors.ErrNotFound)
+	messages.On("CreateBotMessage", mock.MatchedBy(func(m models.Message) bool {
+		return m.SpaceID == 1 && m.Username == "CI" && m.Text == "build passed" && m.Bot && !m.CreatedAt.IsZero()
+	})).Return(models.Message{ID: 10, SpaceID: 1, Username: "CI", Text: "build passed", HTML: "<p>build passed</p>", Bot: true}, nil).Once()
+	messages.On("CreateBotMessage", mock.MatchedBy(func(m models.Message) bool {
+		return m.Username == "deploy-bot"
+	})).Return(models.Message{ID: 11}, nil).Once()
+
+	// 表示名を省略すると Webhook の名前で投稿する
+	msg, err := service.PostMessage(context.Background(), 2, "t0ken", "", "build passed")
+	assert.NoError(t, err)
+	// 保存したメッセージ（HTML を含む）を返す
+	assert.Equal(t, 10, msg.ID)
+	assert.Equal(t, "<p>build passed</p>", msg.HTML)
+
+	msg, err = service.PostMessage(context.Background(), 2, "t0ken", "deploy-bot", "deployed")
+	assert.NoError(t, err)
+	assert.Equal(t, 11, msg.ID)
+
+	// 誤ったトークン・存在しない Webhook は同じエラーにする
+	_, err = service.PostMessage(context.Background(), 2, "wrong", "", "hi")
+	assert.ErrorIs(t, err, services.ErrHookTokenInvalid)
+	_, err = service.PostMessage(context.Background(), 2, "", "", "hi")
+	assert.ErrorIs(t, err, services.ErrHookTokenInvalid)
+	_, err = service.PostMessage(context.Background(), 99, "t0ken", "", "hi")
+	assert.ErrorIs(t, err, services.ErrHookTokenInvalid)
+
+	messages.AssertExpectations(t)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/linkpreview"
+	"chat/markdown"
+	"chat/repositories"
+	"chat/tracing"
+	"context"
+	"errors"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type linkPreviewService struct {
+	MessageRepo repositories.MessageRepository
+	Fetcher     PreviewFetcher
+	Config      config.PreviewConfig
+}
+
+func NewLinkPreviewService(messageRepo repositories.MessageRepository, fetcher PreviewFetcher, cfg config.PreviewConfig) LinkPreviewService {
+	return &linkPreviewService{MessageRepo: messageRepo, Fetcher: fetcher, Config: cfg}
+}
+
+// メッセージの URL のプレビューを並行して取得する（無効にしている場合は空）
+func (s *linkPreviewService) GetMessagePreviews(ctx context.Context, spaceID, messageID int) (previews []linkpreview.Preview, err error) {
+	ctx, span := tracing.Start(ctx, "LinkPreviewService.GetMessagePreviews", attribute.Int("space_id", spaceID), attribute.Int("message_id", messageID))
+	defer func() { tracing.End(span, err) }()
+
+	msg, err := s.MessageRepo.GetMessage(ctx, messageID, spaceID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, ErrMessageNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+	previews = []linkpreview.Preview{}
+	if !s.Config.Enabled {
+		return previews, nil
+	}
+
+	urls := markdown.ExtractURLs(msg.Text)
+	urls = urls[:min(len(urls), s.Config.MaxURLs)]
+	results := make([]*linkpreview.Preview, len(urls))
+	var wg sync.WaitGroup
+	for i, rawURL := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 取得できないページ（HTML 以外・タイムアウトなど）はプレビューを出さないだけにする
+			if preview, err := s.Fetcher.Fetch(ctx, rawURL); err == nil {
+				results[i] = &preview
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 待っている間にリクエストが打ち切られた場合は結果を返さない
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, preview := range results {
+		if preview != nil {
+			previews = append(previews, *preview)
+		}
+	}
+	span.SetAttributes(attribute.Int("urls", len(urls)), attribute.Int("previews", len(previews)))
+	return previews, nil
+}
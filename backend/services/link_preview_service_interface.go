@@ -0,0 +1,17 @@
+package services
+
+import (
+	"chat/linkpreview"
+	"context"
+)
+
+// URL のプレビューの取得（linkpreview.Fetcher。取得結果はキャッシュされる）
+type PreviewFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (linkpreview.Preview, error)
+}
+
+// メッセージ中のリンクのプレビュー
+type LinkPreviewService interface {
+	// 本文の先頭から MaxURLs 件の URL のプレビュー（URL の出現順）。取得できなかった URL は含めない
+	GetMessagePreviews(ctx context.Context, spaceID, messageID int) ([]linkpreview.Preview, error)
+}
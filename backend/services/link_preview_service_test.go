@@ -0,0 +1,76 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/linkpreview"
+	"chat/models"
+	"chat/services"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPreviewFetcher は PreviewFetcher のモック
+type MockPreviewFetcher struct {
+	mock.Mock
+}
+
+func (m *MockPreviewFetcher) Fetch(ctx context.Context, rawURL string) (linkpreview.Preview, error) {
+	args := m.Called(rawURL)
+	return args.Get(0).(linkpreview.Preview), args.Error(1)
+}
+
+// 取得できた URL のプレビューだけを出現順に返し、MaxURLs を超える URL は取得しない
+func TestGetMessagePreviews(t *testing.T) {
+	messageRepo := new(MockMessageRepository)
+	fetcher := new(MockPreviewFetcher)
+	cfg := config.Default().Preview
+	cfg.MaxURLs = 3
+	service := services.NewLinkPreviewService(messageRepo, fetcher, cfg)
+
+	messageRepo.On("GetMessage", 10, 1).Return(models.Message{ID: 10, SpaceID: 1,
+		Text: "https://a.example.com https://b.example.com `https://code.example.com` https://c.example.com https://d.example.com"}, nil)
+	fetcher.On("Fetch", "https://a.example.com").Return(linkpreview.Preview{URL: "https://a.example.com", Title: "A"}, nil)
+	fetcher.On("Fetch", "https://b.example.com").Return(linkpreview.Preview{}, linkpreview.ErrNoPreview)
+	fetcher.On("Fetch", "https://c.example.com").Return(linkpreview.Preview{URL: "https://c.example.com", Title: "C"}, nil)
+
+	previews, err := service.GetMessagePreviews(context.Background(), 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []linkpreview.Preview{
+		{URL: "https://a.example.com", Title: "A"},
+		{URL: "https://c.example.com", Title: "C"},
+	}, previews)
+
+	fetcher.AssertNumberOfCalls(t, "Fetch", 3)
+}
+
+func TestGetMessagePreviews_MessageNotFound(t *testing.T) {
+	messageRepo := new(MockMessageRepository)
+	service := services.NewLinkPreviewService(messageRepo, new(MockPreviewFetcher), config.Default().Preview)
+
+	messageRepo.On("GetMessage", 10, 2).Return(models.Message{}, fmt.Errorf("%w: record not found", apperrors.ErrNotFound))
+
+	_, err := service.GetMessagePreviews(context.Background(), 2, 10)
+	assert.True(t, errors.Is(err, services.ErrMessageNotFound))
+}
+
+// 無効にしている場合はリンク先にアクセスしない
+func TestGetMessagePreviews_Disabled(t *testing.T) {
+	messageRepo := new(MockMessageRepository)
+	fetcher := new(MockPreviewFetcher)
+	cfg := config.Default().Preview
+	cfg.Enabled = false
+	service := services.NewLinkPreviewService(messageRepo, fetcher, cfg)
+
+	messageRepo.On("GetMessage", 10, 1).Return(models.Message{ID: 10, SpaceID: 1, Text: "https://a.example.com"}, nil)
+
+	previews, err := service.GetMessagePreviews(context.Background(), 1, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, previews)
+	fetcher.AssertNotCalled(t, "Fetch", mock.Anything)
+}
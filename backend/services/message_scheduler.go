@@ -0,0 +1,209 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/metrics"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// リマインダーを投稿するときの表示名
+const reminderUsername = "リマインダー"
+
+type messageScheduler struct {
+	Repo     repositories.ScheduledMessageRepository
+	Messages MessageService
+	Config   config.SchedulerConfig
+	Logger   *slog.Logger
+	Metrics  *metrics.Metrics
+
+	quit     chan struct{}
+	stopped  chan struct{}
+	quitOnce sync.Once
+	// Shutdown の期限を過ぎたら投稿中の処理を打ち切る
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// 予約をメッセージサービス経由で投稿するスケジューラーを返す（保存・ハブからの配信・Webhook の通知は通常の投稿と同じ）
+func NewMessageScheduler(repo repositories.ScheduledMessageRepository, messages MessageService, cfg config.SchedulerConfig, logger *slog.Logger, m *metrics.Metrics) MessageScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &messageScheduler{
+		Repo:     repo,
+		Messages: messages,
+		Config:   cfg,
+		Logger:   logger,
+		Metrics:  m,
+		quit:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// PollInterval ごとに送信時刻になった予約を投稿する
+func (s *messageScheduler) Run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.Config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatch()
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// 新しい投稿を止め、投稿中の処理の完了を ctx の期限まで待つ
+// 期限を過ぎた投稿は打ち切り、取り出した予約は lease の後に再送される。
+func (s *messageScheduler) Shutdown(ctx context.Context) error {
+	s.quitOnce.Do(func() { close(s.quit) })
+
+	select {
+	case <-s.stopped:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-s.stopped
+		return ctx.Err()
+	}
+}
+
+// 送信時刻になった予約がなくなるまで、BatchSize 件ずつ送信日時の順に投稿する
+func (s *messageScheduler) dispatch() {
+	for {
+		list, err := s.Repo.ClaimDueScheduledMessages(s.ctx, time.Now(), s.Config.Lease, s.Config.BatchSize)
+		if err != nil {
+			s.Logger.Error("予約を取得できませんでした", "error", err)
+			return
+		}
+
+		for _, sm := range list {
+			s.deliver(s.ctx, sm)
+		}
+
+		if len(list) < s.Config.BatchSize {
+			return
+		}
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+	}
+}
+
+// 1件投稿し、結果を記録する。失敗した場合は待ち時間を倍々に延ばして再送するか、上限に達したら諦める
+// 結果は取り出した時のまま送信待ちの場合だけ記録し、送信中の取り消しや他のインスタンスの処理を上書きしない。
+func (s *messageScheduler) deliver(ctx context.Context, sm models.ScheduledMessage) {
+	ctx, span := tracing.Start(ctx, "MessageScheduler.Deliver",
+		attribute.Int("space_id", sm.SpaceID),
+		attribute.Int("scheduled_id", sm.ID),
+		attribute.String("kind", sm.Kind),
+		attribute.Int("attempt", sm.Attempts+1),
+	)
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	// 取り出した時の next_attempt_at（結果を記録する前に他のインスタンスが取り出していないかの確認に使う）
+	leaseUntil := sm.NextAttemptAt
+	messageID, sendErr := s.send(ctx, sm)
+	// 投稿の打ち切り後も結果は残す
+	recordCtx := context.WithoutCancel(ctx)
+	if sendErr == nil {
+		if err = s.Repo.MarkScheduledMessageSent(recordCtx, sm, messageID, time.Now().UTC()); err != nil {
+			s.logRecordError(ctx, sm, err)
+			return
+		}
+		s.Metrics.ScheduledMessages.WithLabelValues("sent").Inc()
+		return
+	}
+
+	// 入力値・投稿者・スペースの誤り（ドメインエラー）は再送しても成功しない
+	sm.Attempts++
+	sm.Error = sendErr.Error()
+	result := "retry"
+	var appErr *apperrors.Error
+	if errors.As(sendErr, &appErr) || sm.Attempts >= s.Config.MaxAttempts {
+		sm.Status = models.ScheduledFailed
+		result = "failed"
+	} else {
+		sm.NextAttemptAt = time.Now().UTC().Add(s.backoff(sm.Attempts))
+	}
+
+	if err = s.Repo.RecordScheduledMessageFailure(recordCtx, sm, leaseUntil); err != nil {
+		s.logRecordError(ctx, sm, err)
+		return
+	}
+	s.Metrics.ScheduledMessages.WithLabelValues(result).Inc()
+	if result == "failed" {
+		s.Logger.WarnContext(ctx, "予約の送信を諦めました", "scheduled_id", sm.ID, "attempts", sm.Attempts, "error", sendErr)
+	}
+}
+
+// n 回目の失敗の後の待ち時間（BaseBackoff から倍々に増やし、MaxBackoff で止める）
+func (s *messageScheduler) backoff(n int) time.Duration {
+	wait := s.Config.BaseBackoff
+	for i := 1; i < n; i++ {
+		wait *= 2
+		if wait >= s.Config.MaxBackoff {
+			return s.Config.MaxBackoff
+		}
+	}
+	return min(wait, s.Config.MaxBackoff)
+}
+
+// 予約から投稿し、メッセージの ID を返す
+// 前回の送信で投稿だけ済んでいた場合は投稿し直さず、そのメッセージを返す。
+func (s *messageScheduler) send(ctx context.Context, sm models.ScheduledMessage) (int, error) {
+	messageID, err := s.Repo.GetScheduledMessagePost(ctx, sm.ID)
+	if err == nil {
+		return messageID, nil
+	}
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		return 0, err
+	}
+
+	// lease が切れて再送される前に結果を記録できるよう、投稿の時間を区切る
+	ctx, cancel := context.WithTimeout(ctx, s.Config.SendTimeout)
+	defer cancel()
+
+	saved, err := s.post(ctx, sm)
+	return saved.ID, err
+}
+
+// 通常の予約は予約したユーザーとして、リマインダーはユーザーへのメンションとして投稿する
+// 同じ予約からの投稿は1件だけ保存される（並行して再送された場合は一意制約の違反で失敗し、次の送信で投稿済みを確認する）。
+func (s *messageScheduler) post(ctx context.Context, sm models.ScheduledMessage) (models.Message, error) {
+	msg := models.Message{SpaceID: sm.SpaceID, Username: sm.Username, Text: sm.Text, ScheduledMessageID: &sm.ID, CreatedAt: time.Now().UTC()}
+	if sm.Kind == models.ScheduledKindReminder {
+		msg.Username = reminderUsername
+		msg.Text = fmt.Sprintf("@%s %s", sm.Username, sm.Text)
+		return s.Messages.CreateBotMessage(ctx, msg)
+	}
+	return s.Messages.CreateMessage(ctx, msg)
+}
+
+// 送信中に取り消された・他のインスタンスが取り出した予約は結果を記録しない
+func (s *messageScheduler) logRecordError(ctx context.Context, sm models.ScheduledMessage, err error) {
+	if errors.Is(err, apperrors.ErrNotFound) {
+		s.Logger.WarnContext(ctx, "予約の送信結果を記録しませんでした", "scheduled_id", sm.ID, "error", err)
+		return
+	}
+	s.Logger.ErrorContext(ctx, "予約の送信結果を記録できませんでした", "scheduled_id", sm.ID, "error", err)
+}
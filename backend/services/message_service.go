@@ -1,37 +1,234 @@
 package services
 
 import (
+	"chat/apperrors"
+	"chat/markdown"
+	"chat/metrics"
 	"chat/models"
 	"chat/repositories"
+	"chat/tracing"
+	"context"
 	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type messageService struct {
-	repo repositories.MessageRepository
+	repo      repositories.MessageRepository
+	spaceRepo repositories.SpaceRepository
+	userRepo  repositories.UserRepository
+	publisher MessagePublisher
+	notifier  EventNotifier
+	metrics   *metrics.Metrics
+}
+
+func NewMessageService(repo repositories.MessageRepository, spaceRepo repositories.SpaceRepository, userRepo repositories.UserRepository, publisher MessagePublisher, notifier EventNotifier, m *metrics.Metrics) MessageService {
+	return &messageService{repo: repo, spaceRepo: spaceRepo, userRepo: userRepo, publisher: publisher, notifier: notifier, metrics: m}
+}
+
+func (s *messageService) GetMessages(ctx context.Context, spaceId int) (messages []models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetMessages", attribute.Int("space_id", spaceId))
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetMessages(ctx, spaceId)
 }
 
-func NewMessageService(repo repositories.MessageRepository) MessageService {
-	return &messageService{repo: repo}
+func (s *messageService) GetMessage(ctx context.Context, messageID, spaceID int) (msg models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetMessage", attribute.Int("message_id", messageID), attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	msg, err = s.repo.GetMessage(ctx, messageID, spaceID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return msg, ErrMessageNotFound.Wrap(err)
+	}
+	return msg, err
 }
 
-func (s *messageService) GetMessages(spaceId int) ([]models.Message, error) {
-	return s.repo.GetMessages(spaceId)
+// 指定した ID より後のメッセージ（SSE の再接続時の再送用）
+func (s *messageService) GetMessagesAfter(ctx context.Context, spaceID, afterID, limit int) (messages []models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetMessagesAfter", attribute.Int("space_id", spaceID), attribute.Int("after_id", afterID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetMessagesAfter(ctx, spaceID, afterID, limit)
 }
 
 // メッセージ登録
-func (s *messageService) CreateMessage(msg models.Message) (int, error) {
+func (s *messageService) CreateMessage(ctx context.Context, msg models.Message) (saved models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.CreateMessage", attribute.Int("space_id", msg.SpaceID))
+	defer func() { tracing.End(span, err) }()
+
 	// 入力値のバリデーション
 	if msg.Text == "" || msg.Username == "" || msg.SpaceID == 0 {
-		return 0, errors.New("メッセージまたはユーザー名が空です")
+		return models.Message{}, ErrMessageInvalid
+	}
+
+	// 投稿先のスペースが存在するか確認
+	if _, err := s.spaceRepo.GetSpaceByID(ctx, msg.SpaceID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return models.Message{}, ErrSpaceNotFound.Wrap(err)
+		}
+		return models.Message{}, err
 	}
 
-	// メッセージ保存
-	return s.repo.CreateMessage(msg)
+	// 投稿者を登録済みユーザーに紐づける
+	user, err := s.userRepo.GetUserByUsername(ctx, msg.Username)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return models.Message{}, ErrUserNotFound.Wrap(err)
+		}
+		return models.Message{}, err
+	}
+	msg.UserID = &user.ID
+
+	return s.save(ctx, msg, messageSource(ctx))
 }
 
-func (s *messageService) DeleteMessage(messageID, spaceID int) error {
+// ユーザー以外（受信用 Webhook など）からのメッセージ登録。Username は表示名で、ユーザーには紐づけない
+func (s *messageService) CreateBotMessage(ctx context.Context, msg models.Message) (saved models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.CreateBotMessage", attribute.Int("space_id", msg.SpaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if msg.Text == "" || msg.Username == "" || msg.SpaceID == 0 {
+		return models.Message{}, ErrMessageInvalid
+	}
+	msg.UserID = nil
+	msg.Bot = true
+
+	return s.save(ctx, msg, "webhook")
+}
+
+type messageSourceKey struct{}
+
+// CreateMessage の投稿元（メトリクスの source ラベル）を設定する。未設定なら "rest"
+func WithMessageSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, messageSourceKey{}, source)
+}
+
+func messageSource(ctx context.Context) string {
+	if source, ok := ctx.Value(messageSourceKey{}).(string); ok {
+		return source
+	}
+	return "rest"
+}
+
+// メッセージを保存し、ユーザーの投稿と同じようにハブから配信して Webhook に通知する
+func (s *messageService) save(ctx context.Context, msg models.Message, source string) (models.Message, error) {
+	msg.HTML = markdown.Parse(msg.Text).HTML
+
+	// 保存直前にスペースが削除された場合は外部キー違反になる
+	id, err := s.repo.CreateMessage(ctx, msg)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return models.Message{}, ErrSpaceNotFound.Wrap(err)
+	}
+	if err != nil {
+		return models.Message{}, err
+	}
+	s.metrics.MessagesCreated.WithLabelValues(source).Inc()
+
+	// 保存は済んでいるため、配信できなくても投稿は成功とする
+	msg.ID = id
+	if perr := s.publisher.Publish(ctx, msg); perr != nil {
+		trace.SpanFromContext(ctx).RecordError(perr)
+	}
+	s.notifier.Notify(ctx, SpaceEvent{Type: models.EventMessageCreated, SpaceID: msg.SpaceID, Data: msg})
+	return msg, nil
+}
+
+func (s *messageService) DeleteMessage(ctx context.Context, messageID, spaceID int) (err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.DeleteMessage", attribute.Int("message_id", messageID), attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
 	if messageID == 0 || spaceID == 0 {
-		return errors.New("メッセージIDまたはスペースIDが無効です")
+		return ErrMessageIDInvalid
+	}
+
+	err = s.repo.DeleteMessage(ctx, messageID, spaceID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrMessageNotFound.Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.notifier.Notify(ctx, SpaceEvent{
+		Type:    models.EventMessageDeleted,
+		SpaceID: spaceID,
+		Data:    map[string]int{"id": messageID, "space_id": spaceID},
+	})
+	return nil
+}
+
+// メッセージをピン留めし、スペースの WebSocket クライアントに知らせる
+func (s *messageService) PinMessage(ctx context.Context, actor string, spaceID, messageID int) (msg models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.PinMessage", attribute.Int("message_id", messageID), attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := authorizeSpaceManager(ctx, s.userRepo, s.spaceRepo, actor, spaceID)
+	if err != nil {
+		return msg, err
+	}
+	err = s.repo.PinMessage(ctx, messageID, spaceID, user.ID, time.Now().UTC())
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return msg, ErrMessageNotFound.Wrap(err)
+	}
+	if err != nil {
+		return msg, err
+	}
+
+	msg, err = s.repo.GetMessage(ctx, messageID, spaceID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return msg, ErrMessageNotFound.Wrap(err)
+	}
+	if err != nil {
+		return msg, err
+	}
+	s.publishEvent(ctx, HubEvent{Type: models.EventMessagePinned, SpaceID: spaceID, Data: msg})
+	return msg, nil
+}
+
+// ピン留めを外し、スペースの WebSocket クライアントに知らせる
+func (s *messageService) UnpinMessage(ctx context.Context, actor string, spaceID, messageID int) (err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.UnpinMessage", attribute.Int("message_id", messageID), attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeSpaceManager(ctx, s.userRepo, s.spaceRepo, actor, spaceID); err != nil {
+		return err
+	}
+	err = s.repo.UnpinMessage(ctx, messageID, spaceID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrMessageNotFound.Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publishEvent(ctx, HubEvent{
+		Type:    models.EventMessageUnpinned,
+		SpaceID: spaceID,
+		Data:    map[string]int{"id": messageID, "space_id": spaceID},
+	})
+	return nil
+}
+
+// スペースのピン留めされたメッセージ（新しくピン留めした順）
+func (s *messageService) GetPinnedMessages(ctx context.Context, spaceID int) (messages []models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetPinnedMessages", attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.spaceRepo.GetSpaceByID(ctx, spaceID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, ErrSpaceNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+	return s.repo.GetPinnedMessages(ctx, spaceID)
+}
+
+// 変更は済んでいるため、配信できなくても成功とする
+func (s *messageService) publishEvent(ctx context.Context, event HubEvent) {
+	if perr := s.publisher.PublishEvent(ctx, event); perr != nil {
+		trace.SpanFromContext(ctx).RecordError(perr)
 	}
-	return s.repo.DeleteMessage(messageID, spaceID)
 }
@@ -1,9 +1,35 @@
 package services
 
-import "chat/models"
+import (
+	"chat/models"
+	"context"
+)
 
 type MessageService interface {
-	GetMessages(spaceId int) ([]models.Message, error)
-	CreateMessage(msg models.Message) (int, error)
-	DeleteMessage(messageID, spaceID int) error
+	GetMessages(ctx context.Context, spaceId int) ([]models.Message, error)
+	GetMessage(ctx context.Context, messageID, spaceID int) (models.Message, error)
+	GetMessagesAfter(ctx context.Context, spaceID, afterID, limit int) ([]models.Message, error)
+	// 保存したメッセージ（ID・HTML などを設定したもの）を返す
+	CreateMessage(ctx context.Context, msg models.Message) (models.Message, error)
+	CreateBotMessage(ctx context.Context, msg models.Message) (models.Message, error)
+	DeleteMessage(ctx context.Context, messageID, spaceID int) error
+	// ピン留め（actor はログイン中のユーザー名で、スペースの所有者か管理者のみ操作できる）
+	PinMessage(ctx context.Context, actor string, spaceID, messageID int) (models.Message, error)
+	UnpinMessage(ctx context.Context, actor string, spaceID, messageID int) error
+	GetPinnedMessages(ctx context.Context, spaceID int) ([]models.Message, error)
+}
+
+// メッセージ以外にハブから配信するイベント（ピン留めの変更など）
+// WebSocket ではメッセージと区別できるよう type を付けて送る。
+type HubEvent struct {
+	// models.EventMessagePinned など
+	Type    string `json:"type"`
+	SpaceID int    `json:"space_id"`
+	Data    any    `json:"data"`
+}
+
+// 保存したメッセージのリアルタイム配信先（WebSocketService が実装する）
+type MessagePublisher interface {
+	Publish(ctx context.Context, msg models.Message) error
+	PublishEvent(ctx context.Context, event HubEvent) error
 }
@@ -1,21 +1,32 @@
 package services_test
 
 import (
+	"chat/apperrors"
+	"chat/metrics"
 	"chat/models"
 	"chat/services"
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
-func (m *MockMessageRepository) GetMessages(spaceId int) ([]models.Message, error) {
+func (m *MockMessageRepository) GetMessage(ctx context.Context, messageID, spaceID int) (models.Message, error) {
+	args := m.Called(messageID, spaceID)
+	return args.Get(0).(models.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) GetMessages(ctx context.Context, spaceId int) ([]models.Message, error) {
 	args := m.Called(spaceId)
 	return args.Get(0).([]models.Message), args.Error(1)
 }
 
 func TestGetMessages(t *testing.T) {
 	mockRepo := new(MockMessageRepository)
-	service := services.NewMessageService(mockRepo)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
 
 	expectedMessages := []models.Message{
 		{ID: 1, SpaceID: 1, Username: "alice", Text: "Hello"},
@@ -24,7 +35,7 @@ func TestGetMessages(t *testing.T) {
 
 	mockRepo.On("GetMessages", 1).Return(expectedMessages, nil)
 
-	messages, err := service.GetMessages(1)
+	messages, err := service.GetMessages(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedMessages, messages)
 
@@ -33,47 +44,259 @@ func TestGetMessages(t *testing.T) {
 
 func TestCreateMessage_Success(t *testing.T) {
 	mockRepo := new(MockMessageRepository)
-	service := services.NewMessageService(mockRepo)
+	mockSpaceRepo := new(MockSpaceRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockPublisher := new(MockMessagePublisher)
+	notifier := new(MockEventNotifier)
+	service := services.NewMessageService(mockRepo, mockSpaceRepo, mockUserRepo, mockPublisher, notifier, metrics.New())
 
 	message := models.Message{SpaceID: 1, Username: "alice", Text: "Hello"}
-	mockRepo.On("CreateMessage", message).Return(1, nil)
+	userID := 7
+	// 本文の Markdown を変換した HTML も保存する
+	stored := models.Message{SpaceID: 1, UserID: &userID, Username: "alice", Text: "Hello", HTML: "<p>Hello</p>"}
+	mockSpaceRepo.On("GetSpaceByID", 1).Return(models.Space{ID: 1, Name: "general"}, nil)
+	mockUserRepo.On("GetUserByUsername", "alice").Return(models.User{ID: userID, Username: "alice"}, nil)
+	mockRepo.On("CreateMessage", stored).Return(1, nil)
+	// 保存したメッセージを ID 付きで配信する
+	published := stored
+	published.ID = 1
+	mockPublisher.On("Publish", published).Return(nil)
+	notifier.On("Notify", services.SpaceEvent{Type: models.EventMessageCreated, SpaceID: 1, Data: published}).Once()
+
+	saved, err := service.CreateMessage(context.Background(), message)
+	assert.NoError(t, err)
+	assert.Equal(t, published, saved)
+
+	mockRepo.AssertExpectations(t)
+	mockSpaceRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+// 配信できなくても保存済みの投稿は成功とする
+func TestCreateMessage_PublishError(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockSpaceRepo := new(MockSpaceRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockPublisher := new(MockMessagePublisher)
+	notifier := new(MockEventNotifier)
+	service := services.NewMessageService(mockRepo, mockSpaceRepo, mockUserRepo, mockPublisher, notifier, metrics.New())
+
+	mockSpaceRepo.On("GetSpaceByID", 1).Return(models.Space{ID: 1}, nil)
+	mockUserRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	mockRepo.On("CreateMessage", mock.AnythingOfType("models.Message")).Return(3, nil)
+	mockPublisher.On("Publish", mock.AnythingOfType("models.Message")).Return(errors.New("hub stopped"))
+	notifier.On("Notify", mock.Anything).Once()
+
+	saved, err := service.CreateMessage(context.Background(), models.Message{SpaceID: 1, Username: "alice", Text: "Hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, saved.ID)
+	mockPublisher.AssertExpectations(t)
+}
+
+// WebSocket からの投稿は投稿元を "websocket" として数える
+func TestCreateMessage_Source(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockSpaceRepo := new(MockSpaceRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockPublisher := new(MockMessagePublisher)
+	notifier := new(MockEventNotifier)
+	m := metrics.New()
+	service := services.NewMessageService(mockRepo, mockSpaceRepo, mockUserRepo, mockPublisher, notifier, m)
+
+	mockSpaceRepo.On("GetSpaceByID", 1).Return(models.Space{ID: 1}, nil)
+	mockUserRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	mockRepo.On("CreateMessage", mock.AnythingOfType("models.Message")).Return(1, nil)
+	mockPublisher.On("Publish", mock.AnythingOfType("models.Message")).Return(nil)
+	notifier.On("Notify", mock.Anything)
+
+	msg := models.Message{SpaceID: 1, Username: "alice", Text: "Hello"}
+	_, err := service.CreateMessage(context.Background(), msg)
+	assert.NoError(t, err)
+	_, err = service.CreateMessage(services.WithMessageSource(context.Background(), "websocket"), msg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.MessagesCreated.WithLabelValues("rest")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.MessagesCreated.WithLabelValues("websocket")))
+}
+
+// ユーザー以外の投稿はユーザーに紐づけず、bot として保存・配信する
+func TestCreateBotMessage(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockPublisher := new(MockMessagePublisher)
+	notifier := new(MockEventNotifier)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), mockPublisher, notifier, metrics.New())
 
-	id, err := service.CreateMessage(message)
+	stored := models.Message{SpaceID: 1, Username: "CI", Text: "build passed", HTML: "<p>build passed</p>", Bot: true}
+	mockRepo.On("CreateMessage", stored).Return(5, nil)
+	published := stored
+	published.ID = 5
+	mockPublisher.On("Publish", published).Return(nil)
+	notifier.On("Notify", services.SpaceEvent{Type: models.EventMessageCreated, SpaceID: 1, Data: published}).Once()
+
+	userID := 7
+	saved, err := service.CreateBotMessage(context.Background(), models.Message{SpaceID: 1, UserID: &userID, Username: "CI", Text: "build passed"})
 	assert.NoError(t, err)
-	assert.Equal(t, 1, id)
+	assert.Equal(t, published, saved)
+
+	_, err = service.CreateBotMessage(context.Background(), models.Message{SpaceID: 1, Text: "no name"})
+	assert.ErrorIs(t, err, services.ErrMessageInvalid)
 
 	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+func TestCreateMessage_SpaceNotFound(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockSpaceRepo := new(MockSpaceRepository)
+	service := services.NewMessageService(mockRepo, mockSpaceRepo, new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
+
+	mockSpaceRepo.On("GetSpaceByID", 99).Return(models.Space{}, apperrors.ErrNotFound)
+
+	saved, err := service.CreateMessage(context.Background(), models.Message{SpaceID: 99, Username: "alice", Text: "Hello"})
+	assert.ErrorIs(t, err, services.ErrSpaceNotFound)
+	assert.Zero(t, saved.ID)
+
+	mockRepo.AssertNotCalled(t, "CreateMessage")
+}
+
+func TestCreateMessage_UserNotFound(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockSpaceRepo := new(MockSpaceRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewMessageService(mockRepo, mockSpaceRepo, mockUserRepo, new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
+
+	mockSpaceRepo.On("GetSpaceByID", 1).Return(models.Space{ID: 1}, nil)
+	mockUserRepo.On("GetUserByUsername", "ghost").Return(models.User{}, apperrors.ErrNotFound)
+
+	saved, err := service.CreateMessage(context.Background(), models.Message{SpaceID: 1, Username: "ghost", Text: "Hello"})
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+	assert.Zero(t, saved.ID)
+
+	mockRepo.AssertNotCalled(t, "CreateMessage")
 }
 
 func TestCreateMessage_ValidationError(t *testing.T) {
 	mockRepo := new(MockMessageRepository)
-	service := services.NewMessageService(mockRepo)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
 
 	message := models.Message{SpaceID: 1, Username: "", Text: "Hello"}
 
-	id, err := service.CreateMessage(message)
+	saved, err := service.CreateMessage(context.Background(), message)
 	assert.Error(t, err)
 	assert.Equal(t, "メッセージまたはユーザー名が空です", err.Error())
-	assert.Equal(t, 0, id)
+	assert.Zero(t, saved.ID)
 }
 
 func TestDeleteMessage_Success(t *testing.T) {
 	mockRepo := new(MockMessageRepository)
-	service := services.NewMessageService(mockRepo)
+	notifier := new(MockEventNotifier)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), new(MockMessagePublisher), notifier, metrics.New())
 
 	mockRepo.On("DeleteMessage", 1, 1).Return(nil)
+	notifier.On("Notify", services.SpaceEvent{Type: models.EventMessageDeleted, SpaceID: 1, Data: map[string]int{"id": 1, "space_id": 1}}).Once()
 
-	err := service.DeleteMessage(1, 1)
+	err := service.DeleteMessage(context.Background(), 1, 1)
 	assert.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	notifier.AssertExpectations(t)
 }
 
 func TestDeleteMessage_ValidationError(t *testing.T) {
 	mockRepo := new(MockMessageRepository)
-	service := services.NewMessageService(mockRepo)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
 
-	err := service.DeleteMessage(0, 1)
+	err := service.DeleteMessage(context.Background(), 0, 1)
 	assert.Error(t, err)
 	assert.Equal(t, "メッセージIDまたはスペースIDが無効です", err.Error())
 }
+
+func TestDeleteMessage_NotFound(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
+
+	mockRepo.On("DeleteMessage", 5, 1).Return(apperrors.ErrNotFound)
+
+	err := service.DeleteMessage(context.Background(), 5, 1)
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetMessage_NotFound(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	service := services.NewMessageService(mockRepo, new(MockSpaceRepository), new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
+
+	mockRepo.On("GetMessage", 5, 1).Return(models.Message{}, apperrors.ErrNotFound)
+
+	_, err := service.GetMessage(context.Background(), 5, 1)
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	mockRepo.AssertExpectations(t)
+}
+
+// ピン留めはスペースの所有者のみ。ピン留めしたメッセージをハブから配信する
+func TestPinMessage(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	publisher := new(MockMessagePublisher)
+	service := services.NewMessageService(mockRepo, spaceRepo, userRepo, publisher, new(MockEventNotifier), metrics.New())
+
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	userRepo.On("GetUserByUsername", "bob").Return(models.User{ID: 8, Username: "bob"}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+	mockRepo.On("PinMessage", 10, 1, 7).Return(nil).Once()
+	mockRepo.On("PinMessage", 99, 1, 7).Return(apperrors.ErrNotFound).Once()
+	pinned := models.Message{ID: 10, SpaceID: 1, Text: "お知らせ", Pinned: true}
+	mockRepo.On("GetMessage", 10, 1).Return(pinned, nil).Once()
+	publisher.On("PublishEvent", services.HubEvent{Type: models.EventMessagePinned, SpaceID: 1, Data: pinned}).Return(nil).Once()
+
+	msg, err := service.PinMessage(context.Background(), "alice", 1, 10)
+	assert.NoError(t, err)
+	assert.True(t, msg.Pinned)
+
+	_, err = service.PinMessage(context.Background(), "bob", 1, 10)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	_, err = service.PinMessage(context.Background(), "alice", 1, 99)
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+
+	mockRepo.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+// 配信できなくてもピン留めの解除は成功とする
+func TestUnpinMessage_PublishError(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	publisher := new(MockMessagePublisher)
+	service := services.NewMessageService(mockRepo, spaceRepo, userRepo, publisher, new(MockEventNotifier), metrics.New())
+
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+	mockRepo.On("UnpinMessage", 10, 1).Return(nil).Once()
+	publisher.On("PublishEvent", services.HubEvent{Type: models.EventMessageUnpinned, SpaceID: 1, Data: map[string]int{"id": 10, "space_id": 1}}).
+		Return(errors.New("hub stopped")).Once()
+
+	assert.NoError(t, service.UnpinMessage(context.Background(), "alice", 1, 10))
+
+	mockRepo.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestGetPinnedMessages_SpaceNotFound(t *testing.T) {
+	spaceRepo := new(MockSpaceRepository)
+	service := services.NewMessageService(new(MockMessageRepository), spaceRepo, new(MockUserRepository), new(MockMessagePublisher), new(MockEventNotifier), metrics.New())
+
+	spaceRepo.On("GetSpaceByID", 99).Return(models.Space{}, apperrors.ErrNotFound)
+
+	_, err := service.GetPinnedMessages(context.Background(), 99)
+	assert.ErrorIs(t, err, services.ErrSpaceNotFound)
+}
@@ -2,6 +2,9 @@ package services_test
 
 import (
 	"chat/models"
+	"chat/services"
+	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -12,13 +15,57 @@ type MockMessageRepository struct {
 }
 
 // `CreateMessage` の戻り値を `(int, error)` に統一
-func (m *MockMessageRepository) CreateMessage(msg models.Message) (int, error) {
+func (m *MockMessageRepository) CreateMessage(ctx context.Context, msg models.Message) (int, error) {
 	args := m.Called(msg)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockMessageRepository) GetMessagesAfter(ctx context.Context, spaceID, afterID, limit int) ([]models.Message, error) {
+	args := m.Called(spaceID, afterID, limit)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) PinMessage(ctx context.Context, messageID, spaceID, userID int, pinnedAt time.Time) error {
+	args := m.Called(messageID, spaceID, userID)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) UnpinMessage(ctx context.Context, messageID, spaceID int) error {
+	args := m.Called(messageID, spaceID)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) GetPinnedMessages(ctx context.Context, spaceID int) ([]models.Message, error) {
+	args := m.Called(spaceID)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+// MockMessagePublisher は MessagePublisher（WebSocket ハブ）のモック
+type MockMessagePublisher struct {
+	mock.Mock
+}
+
+func (m *MockMessagePublisher) Publish(ctx context.Context, msg models.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
+func (m *MockMessagePublisher) PublishEvent(ctx context.Context, event services.HubEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+// MockEventNotifier は EventNotifier（Webhook）のモック
+type MockEventNotifier struct {
+	mock.Mock
+}
+
+func (m *MockEventNotifier) Notify(ctx context.Context, event services.SpaceEvent) {
+	m.Called(event)
+}
+
 // `DeleteMessage` を追加（必要な場合）
-func (m *MockMessageRepository) DeleteMessage(messageID int, spaceID int) error {
+func (m *MockMessageRepository) DeleteMessage(ctx context.Context, messageID int, spaceID int) error {
 	args := m.Called(messageID, spaceID)
 	return args.Error(0)
 }
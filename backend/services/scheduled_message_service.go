@@ -0,0 +1,165 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/i18n"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// /remind の使い方の i18n のキー（/help で表示する）
+const RemindUsage = "command.usage.remind"
+
+// /remind の時間の日数指定（time.ParseDuration は "d" を受け付けない。上限は予約の MaxDelay で確認する）
+var remindDaysPattern = regexp.MustCompile(`^([0-9]{1,4})d$`)
+
+type scheduledMessageService struct {
+	Repo      repositories.ScheduledMessageRepository
+	SpaceRepo repositories.SpaceRepository
+	UserRepo  repositories.UserRepository
+	Config    config.SchedulerConfig
+}
+
+func NewScheduledMessageService(repo repositories.ScheduledMessageRepository, spaceRepo repositories.SpaceRepository, userRepo repositories.UserRepository, cfg config.SchedulerConfig) ScheduledMessageService {
+	return &scheduledMessageService{Repo: repo, SpaceRepo: spaceRepo, UserRepo: userRepo, Config: cfg}
+}
+
+// 予約を登録する。送信日時は現在より後で、MaxDelay 以内であること
+func (s *scheduledMessageService) Schedule(ctx context.Context, actor string, sm models.ScheduledMessage) (created models.ScheduledMessage, err error) {
+	ctx, span := tracing.Start(ctx, "ScheduledMessageService.Schedule", attribute.Int("space_id", sm.SpaceID), attribute.String("kind", sm.Kind))
+	defer func() { tracing.End(span, err) }()
+
+	if sm.Kind == "" {
+		sm.Kind = models.ScheduledKindMessage
+	}
+	if !s.validSchedule(sm) {
+		return created, ErrScheduleInvalid
+	}
+
+	user, err := s.UserRepo.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return created, ErrUserNotFound.Wrap(err)
+	}
+	if err != nil {
+		return created, err
+	}
+	if _, err := s.SpaceRepo.GetSpaceByID(ctx, sm.SpaceID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return created, ErrSpaceNotFound.Wrap(err)
+		}
+		return created, err
+	}
+
+	created, err = s.Repo.CreateScheduledMessage(ctx, models.ScheduledMessage{
+		SpaceID:       sm.SpaceID,
+		UserID:        user.ID,
+		Username:      user.Username,
+		Kind:          sm.Kind,
+		Text:          sm.Text,
+		SendAt:        sm.SendAt.UTC(),
+		Status:        models.ScheduledPending,
+		NextAttemptAt: sm.SendAt.UTC(),
+	})
+	// 登録の直前にスペースが削除された場合は外部キー違反になる
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return created, ErrSpaceNotFound.Wrap(err)
+	}
+	return created, err
+}
+
+func (s *scheduledMessageService) validSchedule(sm models.ScheduledMessage) bool {
+	if sm.Kind != models.ScheduledKindMessage && sm.Kind != models.ScheduledKindReminder {
+		return false
+	}
+	if sm.SpaceID == 0 || strings.TrimSpace(sm.Text) == "" || utf8.RuneCountInString(sm.Text) > maxBotTextLength {
+		return false
+	}
+	now := time.Now()
+	return sm.SendAt.After(now) && !sm.SendAt.After(now.Add(s.Config.MaxDelay))
+}
+
+// スペースにある自分の送信待ちの予約
+func (s *scheduledMessageService) ListScheduledMessages(ctx context.Context, actor string, spaceID int) (list []models.ScheduledMessage, err error) {
+	ctx, span := tracing.Start(ctx, "ScheduledMessageService.ListScheduledMessages", attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.UserRepo.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, ErrUserNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.ListScheduledMessages(ctx, spaceID, user.ID)
+}
+
+// 自分の送信待ちの予約を取り消す
+func (s *scheduledMessageService) CancelScheduledMessage(ctx context.Context, actor string, spaceID, scheduledID int) (err error) {
+	ctx, span := tracing.Start(ctx, "ScheduledMessageService.CancelScheduledMessage", attribute.Int("space_id", spaceID), attribute.Int("scheduled_id", scheduledID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.UserRepo.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrUserNotFound.Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+	err = s.Repo.CancelScheduledMessage(ctx, scheduledID, spaceID, user.ID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrScheduleNotFound.Wrap(err)
+	}
+	return err
+}
+
+// /remind <時間> <内容>
+// 時間は 30m・2h・1h30m・1d の形式。ログイン中のユーザーへのリマインダーを予約し、実行したユーザーにだけ知らせる。
+func (s *scheduledMessageService) Remind(ctx context.Context, call CommandCall) (CommandResponse, error) {
+	if call.Actor == "" {
+		return ephemeral(i18n.T(call.Language, "command.remind_login")), nil
+	}
+	when, text, _ := strings.Cut(call.Args, " ")
+	text = strings.TrimSpace(text)
+	delay, ok := parseRemindDelay(when)
+	if !ok || text == "" {
+		return ephemeral(i18n.T(call.Language, RemindUsage)), nil
+	}
+
+	_, err := s.Schedule(ctx, call.Actor, models.ScheduledMessage{
+		SpaceID: call.SpaceID,
+		Kind:    models.ScheduledKindReminder,
+		Text:    text,
+		SendAt:  time.Now().Add(delay),
+	})
+	if err != nil {
+		return CommandResponse{}, err
+	}
+	return ephemeral(i18n.T(call.Language, "command.remind_scheduled", when, text)), nil
+}
+
+// /remind の時間を解釈する（正の値のみ）
+func parseRemindDelay(s string) (time.Duration, bool) {
+	if m := remindDaysPattern.FindStringSubmatch(s); m != nil {
+		days, err := strconv.Atoi(m[1])
+		if err != nil || days <= 0 {
+			return 0, false
+		}
+		return time.Duration(days) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"chat/models"
+	"context"
+)
+
+// 予約投稿・リマインダーの管理。actor はログイン中のユーザー名で、自分の予約のみ操作できる
+type ScheduledMessageService interface {
+	// sm の SpaceID・Kind・Text・SendAt で予約する（Kind が空なら通常のメッセージ）
+	Schedule(ctx context.Context, actor string, sm models.ScheduledMessage) (models.ScheduledMessage, error)
+	// スペースにある自分の送信待ちの予約
+	ListScheduledMessages(ctx context.Context, actor string, spaceID int) ([]models.ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, actor string, spaceID, scheduledID int) error
+	// /remind コマンド（CommandService に登録する）
+	Remind(ctx context.Context, call CommandCall) (CommandResponse, error)
+}
+
+// 送信時刻になった予約をバックグラウンドで投稿する
+// 予約は DB に残るため、再起動の後も送信される。複数のインスタンスで動かしても同じ予約は1つのインスタンスだけが送る。
+type MessageScheduler interface {
+	// 送信のループ（Shutdown まで戻らない）
+	Run()
+	Shutdown(ctx context.Context) error
+}
@@ -0,0 +1,304 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/i18n"
+	"chat/logging"
+	"chat/metrics"
+	"chat/models"
+	"chat/services"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockScheduledMessageRepository は ScheduledMessageRepository のモック
+type MockScheduledMessageRepository struct {
+	mock.Mock
+}
+
+func (m *MockScheduledMessageRepository) CreateScheduledMessage(ctx context.Context, sm models.ScheduledMessage) (models.ScheduledMessage, error) {
+	args := m.Called(sm)
+	return args.Get(0).(models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepository) ListScheduledMessages(ctx context.Context, spaceID, userID int) ([]models.ScheduledMessage, error) {
+	args := m.Called(spaceID, userID)
+	return args.Get(0).([]models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepository) CancelScheduledMessage(ctx context.Context, scheduledID, spaceID, userID int) error {
+	args := m.Called(scheduledID, spaceID, userID)
+	return args.Error(0)
+}
+
+func (m *MockScheduledMessageRepository) ClaimDueScheduledMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ScheduledMessage, error) {
+	args := m.Called(lease, limit)
+	return args.Get(0).([]models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockScheduledMessageRepository) MarkScheduledMessageSent(ctx context.Context, sm models.ScheduledMessage, messageID int, sentAt time.Time) error {
+	args := m.Called(sm.ID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockScheduledMessageRepository) RecordScheduledMessageFailure(ctx context.Context, sm models.ScheduledMessage, leaseUntil time.Time) error {
+	args := m.Called(sm, leaseUntil)
+	return args.Error(0)
+}
+
+func (m *MockScheduledMessageRepository) GetScheduledMessagePost(ctx context.Context, scheduledID int) (int, error) {
+	args := m.Called(scheduledID)
+	return args.Int(0), args.Error(1)
+}
+
+func newScheduledMessageService() (services.ScheduledMessageService, *MockScheduledMessageRepository, *MockSpaceRepository, *MockUserRepository) {
+	repo := new(MockScheduledMessageRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	return services.NewScheduledMessageService(repo, spaceRepo, userRepo, config.Default().Scheduler), repo, spaceRepo, userRepo
+}
+
+// 予約はログイン中のユーザーの名前で登録し、送信時刻から取り出せるようにする
+func TestSchedule(t *testing.T) {
+	service, repo, spaceRepo, userRepo := newScheduledMessageService()
+	sendAt := time.Now().Add(time.Hour)
+
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(models.Space{ID: 1}, nil)
+	repo.On("CreateScheduledMessage", mock.MatchedBy(func(sm models.ScheduledMessage) bool {
+		return sm.UserID == 7 && sm.Username == "alice" && sm.Kind == models.ScheduledKindMessage &&
+			sm.Status == models.ScheduledPending && sm.NextAttemptAt.Equal(sendAt)
+	})).Return(models.ScheduledMessage{ID: 5, SpaceID: 1}, nil).Once()
+
+	sm, err := service.Schedule(context.Background(), "alice", models.ScheduledMessage{SpaceID: 1, Text: "おはよう", SendAt: sendAt})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, sm.ID)
+
+	repo.AssertExpectations(t)
+}
+
+// 過去・上限より先の日時、空の本文、不明な種類は登録しない
+func TestSchedule_Invalid(t *testing.T) {
+	service, repo, _, _ := newScheduledMessageService()
+	now := time.Now()
+
+	for name, sm := range map[string]models.ScheduledMessage{
+		"past":     {SpaceID: 1, Text: "a", SendAt: now.Add(-time.Minute)},
+		"too_far":  {SpaceID: 1, Text: "a", SendAt: now.Add(config.Default().Scheduler.MaxDelay + time.Hour)},
+		"blank":    {SpaceID: 1, Text: "  ", SendAt: now.Add(time.Hour)},
+		"bad_kind": {SpaceID: 1, Text: "a", SendAt: now.Add(time.Hour), Kind: "email"},
+	} {
+		_, err := service.Schedule(context.Background(), "alice", sm)
+		assert.ErrorIs(t, err, services.ErrScheduleInvalid, name)
+	}
+	repo.AssertNotCalled(t, "CreateScheduledMessage", mock.Anything)
+}
+
+func TestSchedule_SpaceNotFound(t *testing.T) {
+	service, _, spaceRepo, userRepo := newScheduledMessageService()
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	spaceRepo.On("GetSpaceByID", 99).Return(models.Space{}, apperrors.ErrNotFound)
+
+	_, err := service.Schedule(context.Background(), "alice", models.ScheduledMessage{SpaceID: 99, Text: "a", SendAt: time.Now().Add(time.Hour)})
+	assert.ErrorIs(t, err, services.ErrSpaceNotFound)
+}
+
+// 取り消せるのは自分の送信待ちの予約のみ
+func TestCancelScheduledMessage(t *testing.T) {
+	service, repo, _, userRepo := newScheduledMessageService()
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	repo.On("CancelScheduledMessage", 5, 1, 7).Return(nil).Once()
+	repo.On("CancelScheduledMessage", 6, 1, 7).Return(apperrors.ErrNotFound).Once()
+
+	assert.NoError(t, service.CancelScheduledMessage(context.Background(), "alice", 1, 5))
+	assert.ErrorIs(t, service.CancelScheduledMessage(context.Background(), "alice", 1, 6), services.ErrScheduleNotFound)
+}
+
+// /remind は実行したユーザーへのリマインダーを予約し、本人にだけ知らせる
+func TestRemind(t *testing.T) {
+	service, repo, spaceRepo, userRepo := newScheduledMessageService()
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(models.Space{ID: 1}, nil)
+
+	start := time.Now()
+	repo.On("CreateScheduledMessage", mock.MatchedBy(func(sm models.ScheduledMessage) bool {
+		return sm.Kind == models.ScheduledKindReminder && sm.Text == "請求書を送る" && sm.Username == "alice" &&
+			sm.SendAt.Sub(start) >= 24*time.Hour && sm.SendAt.Sub(start) < 24*time.Hour+time.Minute
+	})).Return(models.ScheduledMessage{ID: 5}, nil).Once()
+
+	res, err := service.Remind(context.Background(), services.CommandCall{Name: "remind", Args: "1d 請求書を送る", SpaceID: 1, Actor: "alice", Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Equal(t, "1d後にリマインドします: 請求書を送る", res.Text)
+
+	// 時間が不正・本文がない・未ログインなら予約しない
+	for _, args := range []string{"soon 会議", "30m", "-5m 会議", "0s 会議"} {
+		res, err := service.Remind(context.Background(), services.CommandCall{Name: "remind", Args: args, SpaceID: 1, Actor: "alice"})
+		assert.NoError(t, err)
+		assert.Equal(t, i18n.T(i18n.Japanese, services.RemindUsage), res.Text, args)
+	}
+	// 応答は実行したユーザーの言語で返す
+	res, err = service.Remind(context.Background(), services.CommandCall{Name: "remind", Args: "30m 会議", SpaceID: 1, Username: "guest", Language: "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, services.ResponseEphemeral, res.ResponseType)
+	assert.Equal(t, "Log in to use reminders", res.Text)
+
+	repo.AssertExpectations(t)
+}
+
+// 通常の予約はユーザーとして、リマインダーはメンションとして投稿し、送信済みにする
+func TestMessageScheduler_Deliver(t *testing.T) {
+	cfg := config.Default().Scheduler
+	cfg.PollInterval = time.Hour
+	m := metrics.New()
+	repo := new(MockScheduledMessageRepository)
+	messages := new(MockBotMessageService)
+	scheduler := services.NewMessageScheduler(repo, messages, cfg, logging.Discard(), m)
+
+	due := []models.ScheduledMessage{
+		{ID: 5, SpaceID: 1, UserID: 7, Username: "alice", Kind: models.ScheduledKindMessage, Text: "おはよう"},
+		{ID: 6, SpaceID: 2, UserID: 7, Username: "alice", Kind: models.ScheduledKindReminder, Text: "会議"},
+	}
+	repo.On("ClaimDueScheduledMessages", cfg.Lease, cfg.BatchSize).Return(due, nil).Once()
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return([]models.ScheduledMessage{}, nil)
+	repo.On("GetScheduledMessagePost", mock.Anything).Return(0, apperrors.ErrNotFound)
+	// 同じ予約から二重に投稿しないよう、予約の ID を付けて投稿する
+	messages.On("CreateMessage", mock.MatchedBy(func(msg models.Message) bool {
+		return msg.SpaceID == 1 && msg.Username == "alice" && msg.Text == "おはよう" && *msg.ScheduledMessageID == 5
+	})).Return(models.Message{ID: 40}, nil).Once()
+	messages.On("CreateBotMessage", mock.MatchedBy(func(msg models.Message) bool {
+		return msg.SpaceID == 2 && msg.Username == "リマインダー" && msg.Text == "@alice 会議" && *msg.ScheduledMessageID == 6
+	})).Return(models.Message{ID: 41}, nil).Once()
+
+	sent := make(chan int, 2)
+	repo.On("MarkScheduledMessageSent", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		sent <- args.Int(1)
+	})
+
+	go scheduler.Run()
+	assert.Equal(t, 40, <-sent)
+	assert.Equal(t, 41, <-sent)
+	require.NoError(t, scheduler.Shutdown(context.Background()))
+
+	messages.AssertExpectations(t)
+	repo.AssertCalled(t, "MarkScheduledMessageSent", 5, 40)
+	repo.AssertCalled(t, "MarkScheduledMessageSent", 6, 41)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.ScheduledMessages.WithLabelValues("sent")))
+}
+
+// DB 障害などは待ち時間を倍々に延ばして再送し、ドメインエラー（スペースの削除など）・上限に達した場合は諦める
+func TestMessageScheduler_Failure(t *testing.T) {
+	cfg := config.Default().Scheduler
+	cfg.PollInterval = time.Hour
+	m := metrics.New()
+	repo := new(MockScheduledMessageRepository)
+	messages := new(MockBotMessageService)
+	scheduler := services.NewMessageScheduler(repo, messages, cfg, logging.Discard(), m)
+
+	leaseUntil := time.Now().UTC().Add(cfg.Lease).Truncate(time.Microsecond)
+	due := []models.ScheduledMessage{
+		{ID: 5, SpaceID: 1, Username: "alice", Kind: models.ScheduledKindMessage, Text: "a", Status: models.ScheduledPending, NextAttemptAt: leaseUntil},
+		{ID: 6, SpaceID: 2, Username: "alice", Kind: models.ScheduledKindMessage, Text: "b", Status: models.ScheduledPending, NextAttemptAt: leaseUntil},
+		{ID: 7, SpaceID: 1, Username: "alice", Kind: models.ScheduledKindMessage, Text: "c", Status: models.ScheduledPending, NextAttemptAt: leaseUntil, Attempts: cfg.MaxAttempts - 1},
+		{ID: 8, SpaceID: 1, Username: "alice", Kind: models.ScheduledKindMessage, Text: "d", Status: models.ScheduledPending, NextAttemptAt: leaseUntil, Attempts: 2},
+	}
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return(due, nil).Once()
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return([]models.ScheduledMessage{}, nil)
+	repo.On("GetScheduledMessagePost", mock.Anything).Return(0, apperrors.ErrNotFound)
+	messages.On("CreateMessage", mock.MatchedBy(func(msg models.Message) bool { return msg.SpaceID == 1 })).Return(models.Message{}, errors.New("db down"))
+	messages.On("CreateMessage", mock.MatchedBy(func(msg models.Message) bool { return msg.SpaceID == 2 })).Return(models.Message{}, services.ErrSpaceNotFound)
+
+	recorded := make(chan models.ScheduledMessage, 4)
+	// 取り出した時の next_attempt_at で、取り出した時のまま送信待ちかを確認する
+	repo.On("RecordScheduledMessageFailure", mock.Anything, leaseUntil).Return(nil).Run(func(args mock.Arguments) {
+		recorded <- args.Get(0).(models.ScheduledMessage)
+	})
+
+	go scheduler.Run()
+	retry, gone, exhausted, again := <-recorded, <-recorded, <-recorded, <-recorded
+	require.NoError(t, scheduler.Shutdown(context.Background()))
+
+	assert.Equal(t, models.ScheduledPending, retry.Status)
+	assert.Equal(t, 1, retry.Attempts)
+	assert.Equal(t, "db down", retry.Error)
+	assert.WithinDuration(t, time.Now().Add(cfg.BaseBackoff), retry.NextAttemptAt, 5*time.Second)
+	// 3回目の失敗の後は BaseBackoff の4倍待つ
+	assert.Equal(t, models.ScheduledPending, again.Status)
+	assert.Equal(t, 3, again.Attempts)
+	assert.WithinDuration(t, time.Now().Add(4*cfg.BaseBackoff), again.NextAttemptAt, 5*time.Second)
+	assert.Equal(t, models.ScheduledFailed, gone.Status)
+	assert.Equal(t, models.ScheduledFailed, exhausted.Status)
+	assert.Equal(t, cfg.MaxAttempts, exhausted.Attempts)
+	repo.AssertNotCalled(t, "MarkScheduledMessageSent", mock.Anything, mock.Anything)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.ScheduledMessages.WithLabelValues("retry")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.ScheduledMessages.WithLabelValues("failed")))
+}
+
+// 待ち時間は MaxBackoff で止める
+func TestMessageScheduler_BackoffCap(t *testing.T) {
+	cfg := config.Default().Scheduler
+	cfg.PollInterval = time.Hour
+	cfg.MaxAttempts = 100
+	repo := new(MockScheduledMessageRepository)
+	messages := new(MockBotMessageService)
+	scheduler := services.NewMessageScheduler(repo, messages, cfg, logging.Discard(), metrics.New())
+
+	due := []models.ScheduledMessage{{ID: 5, SpaceID: 1, Username: "alice", Kind: models.ScheduledKindMessage, Text: "a", Status: models.ScheduledPending, Attempts: 40}}
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return(due, nil).Once()
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return([]models.ScheduledMessage{}, nil)
+	repo.On("GetScheduledMessagePost", mock.Anything).Return(0, apperrors.ErrNotFound)
+	messages.On("CreateMessage", mock.Anything).Return(models.Message{}, errors.New("db down"))
+
+	recorded := make(chan models.ScheduledMessage, 1)
+	repo.On("RecordScheduledMessageFailure", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		recorded <- args.Get(0).(models.ScheduledMessage)
+	})
+
+	go scheduler.Run()
+	sm := <-recorded
+	require.NoError(t, scheduler.Shutdown(context.Background()))
+
+	assert.WithinDuration(t, time.Now().Add(cfg.MaxBackoff), sm.NextAttemptAt, 5*time.Second)
+}
+
+// 前回の送信で投稿だけ済んでいた予約は投稿し直さず、送信中に取り消された予約は送信済みにしない
+func TestMessageScheduler_Redeliver(t *testing.T) {
+	cfg := config.Default().Scheduler
+	cfg.PollInterval = time.Hour
+	m := metrics.New()
+	repo := new(MockScheduledMessageRepository)
+	messages := new(MockBotMessageService)
+	scheduler := services.NewMessageScheduler(repo, messages, cfg, logging.Discard(), m)
+
+	due := []models.ScheduledMessage{
+		{ID: 5, SpaceID: 1, Username: "alice", Kind: models.ScheduledKindMessage, Text: "a", Status: models.ScheduledPending, Attempts: 1},
+		{ID: 6, SpaceID: 1, Username: "alice", Kind: models.ScheduledKindMessage, Text: "b", Status: models.ScheduledPending},
+	}
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return(due, nil).Once()
+	repo.On("ClaimDueScheduledMessages", mock.Anything, mock.Anything).Return([]models.ScheduledMessage{}, nil)
+	repo.On("GetScheduledMessagePost", 5).Return(40, nil)
+	repo.On("GetScheduledMessagePost", 6).Return(0, apperrors.ErrNotFound)
+	messages.On("CreateMessage", mock.MatchedBy(func(msg models.Message) bool { return msg.Text == "b" })).Return(models.Message{ID: 41}, nil).Once()
+
+	marked := make(chan int, 2)
+	repo.On("MarkScheduledMessageSent", 5, 40).Return(nil).Run(func(args mock.Arguments) { marked <- args.Int(0) })
+	repo.On("MarkScheduledMessageSent", 6, 41).Return(fmt.Errorf("%w: canceled", apperrors.ErrNotFound)).Run(func(args mock.Arguments) { marked <- args.Int(0) })
+
+	go scheduler.Run()
+	assert.Equal(t, 5, <-marked)
+	assert.Equal(t, 6, <-marked)
+	require.NoError(t, scheduler.Shutdown(context.Background()))
+
+	messages.AssertNumberOfCalls(t, "CreateMessage", 1)
+	repo.AssertNotCalled(t, "RecordScheduledMessageFailure", mock.Anything, mock.Anything)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.ScheduledMessages.WithLabelValues("sent")))
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+	"context"
+	"errors"
+)
+
+// スペースの所有者か管理者であることを確認し、操作するユーザーを返す（Webhook などスペースの設定の管理用）
+func authorizeSpaceManager(ctx context.Context, users repositories.UserRepository, spaces repositories.SpaceRepository, actor string, spaceID int) (models.User, error) {
+	user, err := users.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return user, ErrForbidden
+	}
+	if err != nil {
+		return user, err
+	}
+
+	space, err := spaces.GetSpaceByID(ctx, spaceID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return user, ErrSpaceNotFound.Wrap(err)
+		}
+		return user, err
+	}
+
+	if user.Role != models.RoleAdmin && (space.OwnerID == nil || *space.OwnerID != user.ID) {
+		return user, ErrForbidden
+	}
+	return user, nil
+}
+
+// 管理者であることを確認し、操作するユーザーを返す（ボットなど全スペースに関わる設定の管理用）
+func authorizeAdmin(ctx context.Context, users repositories.UserRepository, actor string) (models.User, error) {
+	user, err := users.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return user, ErrForbidden
+	}
+	if err != nil {
+		return user, err
+	}
+	if user.Role != models.RoleAdmin {
+		return user, ErrForbidden
+	}
+	return user, nil
+}
@@ -1,24 +1,62 @@
 package services
 
 import (
+	"chat/apperrors"
 	"chat/models"
 	"chat/repositories"
+	"chat/tracing"
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type spaceService struct {
-	Repo repositories.SpaceRepository
+	Repo     repositories.SpaceRepository
+	UserRepo repositories.UserRepository
 }
 
-func NewSpaceService(repo repositories.SpaceRepository) SpaceService {
-	return &spaceService{Repo: repo}
+func NewSpaceService(repo repositories.SpaceRepository, userRepo repositories.UserRepository) SpaceService {
+	return &spaceService{Repo: repo, UserRepo: userRepo}
 }
 
 // スペースを作成
-func (s *spaceService) CreateSpace(name string) error {
-	return s.Repo.CreateSpace(name)
+// owner（ログイン中のユーザー名）をスペースの所有者にする。未ログインなら空文字で、所有者なしになる。
+func (s *spaceService) CreateSpace(ctx context.Context, name, owner string) (space models.Space, err error) {
+	ctx, span := tracing.Start(ctx, "SpaceService.CreateSpace")
+	defer func() { tracing.End(span, err) }()
+
+	var ownerID *int
+	if owner != "" {
+		user, err := s.UserRepo.GetUserByUsername(ctx, owner)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrNotFound) {
+				return space, ErrUserNotFound.Wrap(err)
+			}
+			return space, err
+		}
+		ownerID = &user.ID
+	}
+
+	return s.Repo.CreateSpace(ctx, name, ownerID)
 }
 
 // スペース一覧を取得
-func (s *spaceService) GetSpaces() ([]models.Space, error) {
-	return s.Repo.GetSpaces()
+func (s *spaceService) GetSpaces(ctx context.Context) (spaces []models.Space, err error) {
+	ctx, span := tracing.Start(ctx, "SpaceService.GetSpaces")
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repo.GetSpaces(ctx)
+}
+
+// スペースを取得
+func (s *spaceService) GetSpace(ctx context.Context, id int) (space models.Space, err error) {
+	ctx, span := tracing.Start(ctx, "SpaceService.GetSpace", attribute.Int("space_id", id))
+	defer func() { tracing.End(span, err) }()
+
+	space, err = s.Repo.GetSpaceByID(ctx, id)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return space, ErrSpaceNotFound.Wrap(err)
+	}
+	return space, err
 }
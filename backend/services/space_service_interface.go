@@ -1,8 +1,12 @@
 package services
 
-import "chat/models"
+import (
+	"chat/models"
+	"context"
+)
 
 type SpaceService interface {
-	CreateSpace(name string) error
-	GetSpaces() ([]models.Space, error)
+	CreateSpace(ctx context.Context, name, owner string) (models.Space, error)
+	GetSpaces(ctx context.Context) ([]models.Space, error)
+	GetSpace(ctx context.Context, id int) (models.Space, error)
 }
@@ -1,8 +1,10 @@
 package services_test
 
 import (
+	"chat/apperrors"
 	"chat/models"
 	"chat/services"
+	"context"
 	"errors"
 	"testing"
 
@@ -15,44 +17,77 @@ type MockSpaceRepository struct {
 	mock.Mock
 }
 
-func (m *MockSpaceRepository) CreateSpace(name string) error {
-	args := m.Called(name)
-	return args.Error(0)
+func (m *MockSpaceRepository) CreateSpace(ctx context.Context, name string, ownerID *int) (models.Space, error) {
+	args := m.Called(name, ownerID)
+	return args.Get(0).(models.Space), args.Error(1)
 }
 
-func (m *MockSpaceRepository) GetSpaces() ([]models.Space, error) {
+func (m *MockSpaceRepository) GetSpaces(ctx context.Context) ([]models.Space, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Space), args.Error(1)
 }
 
+func (m *MockSpaceRepository) GetSpaceByID(ctx context.Context, id int) (models.Space, error) {
+	args := m.Called(id)
+	return args.Get(0).(models.Space), args.Error(1)
+}
+
+func (m *MockSpaceRepository) UpdateSpaceTopic(ctx context.Context, id int, topic string) error {
+	args := m.Called(id, topic)
+	return args.Error(0)
+}
+
 func TestCreateSpace_Success(t *testing.T) {
 	mockRepo := new(MockSpaceRepository)
-	service := services.NewSpaceService(mockRepo)
+	service := services.NewSpaceService(mockRepo, new(MockUserRepository))
 
-	mockRepo.On("CreateSpace", "Test Space").Return(nil)
+	mockRepo.On("CreateSpace", "Test Space", (*int)(nil)).Return(models.Space{ID: 1, Name: "Test Space"}, nil)
 
-	err := service.CreateSpace("Test Space")
+	space, err := service.CreateSpace(context.Background(), "Test Space", "")
 
 	assert.NoError(t, err)
+	assert.Equal(t, 1, space.ID)
 	mockRepo.AssertExpectations(t)
 }
 
 func TestCreateSpace_Error(t *testing.T) {
 	mockRepo := new(MockSpaceRepository)
-	service := services.NewSpaceService(mockRepo)
+	service := services.NewSpaceService(mockRepo, new(MockUserRepository))
 
-	mockRepo.On("CreateSpace", "Test Space").Return(errors.New("DB error"))
+	mockRepo.On("CreateSpace", "Test Space", (*int)(nil)).Return(models.Space{}, errors.New("DB error"))
 
-	err := service.CreateSpace("Test Space")
+	_, err := service.CreateSpace(context.Background(), "Test Space", "")
 
 	assert.Error(t, err)
 	assert.Equal(t, "DB error", err.Error())
 	mockRepo.AssertExpectations(t)
 }
 
+// ログイン中のユーザーを所有者にする
+func TestCreateSpace_Owner(t *testing.T) {
+	mockRepo := new(MockSpaceRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSpaceService(mockRepo, mockUserRepo)
+
+	ownerID := 7
+	mockUserRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	mockUserRepo.On("GetUserByUsername", "ghost").Return(models.User{}, apperrors.ErrNotFound)
+	mockRepo.On("CreateSpace", "Test Space", &ownerID).Return(models.Space{ID: 1, Name: "Test Space", OwnerID: &ownerID}, nil)
+
+	space, err := service.CreateSpace(context.Background(), "Test Space", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, *space.OwnerID)
+
+	_, err = service.CreateSpace(context.Background(), "Test Space", "ghost")
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+
+	mockRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
 func TestGetSpaces_Success(t *testing.T) {
 	mockRepo := new(MockSpaceRepository)
-	service := services.NewSpaceService(mockRepo)
+	service := services.NewSpaceService(mockRepo, new(MockUserRepository))
 
 	spaces := []models.Space{
 		{ID: 1, Name: "Space 1"},
@@ -61,7 +96,7 @@ func TestGetSpaces_Success(t *testing.T) {
 
 	mockRepo.On("GetSpaces").Return(spaces, nil)
 
-	result, err := service.GetSpaces()
+	result, err := service.GetSpaces(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, spaces, result)
@@ -70,14 +105,26 @@ func TestGetSpaces_Success(t *testing.T) {
 
 func TestGetSpaces_Error(t *testing.T) {
 	mockRepo := new(MockSpaceRepository)
-	service := services.NewSpaceService(mockRepo)
+	service := services.NewSpaceService(mockRepo, new(MockUserRepository))
 
 	mockRepo.On("GetSpaces").Return([]models.Space{}, errors.New("DB error"))
 
-	result, err := service.GetSpaces()
+	result, err := service.GetSpaces(context.Background())
 
 	assert.Error(t, err)
 	assert.Equal(t, "DB error", err.Error())
 	assert.Empty(t, result)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGetSpace_NotFound(t *testing.T) {
+	mockRepo := new(MockSpaceRepository)
+	service := services.NewSpaceService(mockRepo, new(MockUserRepository))
+
+	mockRepo.On("GetSpaceByID", 99).Return(models.Space{}, apperrors.ErrNotFound)
+
+	_, err := service.GetSpace(context.Background(), 99)
+
+	assert.ErrorIs(t, err, services.ErrSpaceNotFound)
+	mockRepo.AssertExpectations(t)
+}
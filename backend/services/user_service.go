@@ -1,53 +1,174 @@
 package services
 
 import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/loginguard"
 	"chat/models"
 	"chat/repositories"
+	"chat/tracing"
+	"context"
 	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
 type userService struct {
-	Repo repositories.UserRepository
+	Repo   repositories.UserRepository
+	Events repositories.LoginEventRepository
+	JWT    config.JWTConfig
+	Login  config.LoginGuardConfig
+	// ログイン失敗の追跡（無効の場合は nil）
+	Guard  *loginguard.Guard
+	Logger *slog.Logger
 }
 
-func NewUserService(repo repositories.UserRepository) UserService {
-	return &userService{Repo: repo}
+func NewUserService(repo repositories.UserRepository, events repositories.LoginEventRepository, jwtConfig config.JWTConfig, loginConfig config.LoginGuardConfig, logger *slog.Logger) UserService {
+	s := &userService{Repo: repo, Events: events, JWT: jwtConfig, Login: loginConfig, Logger: logger}
+	if loginConfig.Enabled {
+		s.Guard = loginguard.New(loginConfig)
+	}
+	return s
 }
 
 // ユーザー登録
-func (s *userService) RegisterUser(user models.User) error {
+func (s *userService) RegisterUser(ctx context.Context, user models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.RegisterUser")
+	defer func() { tracing.End(span, err) }()
+
 	// すでにユーザーが存在するか確認
-	existingUser, err := s.Repo.GetUserByUsername(user.Username)
+	existingUser, err := s.Repo.GetUserByUsername(ctx, user.Username)
 	if err == nil && existingUser.Username != "" {
-		return errors.New("ユーザー名が既に使用されています")
+		return ErrUsernameTaken
+	}
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		return err
 	}
 
-	// 新規ユーザーを登録
-	return s.Repo.CreateUser(user)
+	// 新規ユーザーを登録（同時登録は一意制約違反になる）
+	err = s.Repo.CreateUser(ctx, user)
+	if errors.Is(err, apperrors.ErrConflict) {
+		return ErrUsernameTaken.Wrap(err)
+	}
+	return err
 }
 
-func (s *userService) AuthenticateUser(user models.User) (string, error) {
-	storedPassword, err := s.Repo.GetPasswordByUsername(user.Username)
-	if err != nil {
-		return "", errors.New("認証失敗")
+// ログイン。失敗が続いたユーザー名・IP は一定時間ログインを受け付けない
+func (s *userService) AuthenticateUser(ctx context.Context, user models.User, clientIP string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.AuthenticateUser")
+	defer func() { tracing.End(span, err) }()
+
+	if s.Guard != nil {
+		if wait := s.Guard.Check(userKey(user.Username), ipKey(clientIP)); wait > 0 {
+			return "", ErrAccountLocked.WithRetryAfter(wait)
+		}
+	}
+
+	storedPassword, err := s.Repo.GetPasswordByUsername(ctx, user.Username)
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		return "", err
+	}
+
+	// 存在しないユーザー名も失敗として数える（ユーザーの有無を推測させない）
+	if err != nil || storedPassword != user.Password {
+		s.loginFailed(ctx, user.Username, clientIP)
+		return "", ErrInvalidCredentials
 	}
 
-	if storedPassword != user.Password {
-		return "", errors.New("認証失敗")
+	// IP の失敗回数は消さない（自分のアカウントでログインして総当たりの回数を戻せないように）
+	if s.Guard != nil {
+		s.Guard.Reset(userKey(user.Username))
 	}
+	s.record(ctx, models.LoginEvent{Event: models.LoginSucceeded, Username: user.Username, IP: clientIP})
 
 	// トークンの作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"username": user.Username,
-		"exp":      time.Now().Add(time.Hour * 1).Unix(),
+		"exp":      time.Now().Add(s.JWT.TTL).Unix(),
 	})
-	tokenString, err := token.SignedString([]byte("your-secret-key"))
+	tokenString, err := token.SignedString([]byte(s.JWT.Secret))
 	if err != nil {
 		return "", errors.New("トークン生成エラー")
 	}
 
 	return tokenString, nil
 }
+
+// トークンを検証し、ユーザー名を返す
+func (s *userService) VerifyToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("署名方式が不正です")
+		}
+		return []byte(s.JWT.Secret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", ErrInvalidToken.Wrap(err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	username, _ := claims["username"].(string)
+	if username == "" {
+		return "", ErrInvalidToken
+	}
+	return username, nil
+}
+
+// 管理者がアカウントのロックを解除する
+func (s *userService) UnlockUser(ctx context.Context, actor, username, clientIP string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UnlockUser")
+	defer func() { tracing.End(span, err) }()
+
+	admin, err := s.Repo.GetUserByUsername(ctx, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if admin.Role != models.RoleAdmin {
+		return ErrForbidden
+	}
+
+	if _, err := s.Repo.GetUserByUsername(ctx, username); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return ErrUserNotFound.Wrap(err)
+		}
+		return err
+	}
+
+	if s.Guard != nil {
+		s.Guard.Reset(userKey(username))
+	}
+	s.record(ctx, models.LoginEvent{Event: models.AccountUnlocked, Username: username, IP: clientIP, Actor: actor})
+	return nil
+}
+
+// 失敗を記録し、しきい値に達したらロックを監査ログに残す
+func (s *userService) loginFailed(ctx context.Context, username, clientIP string) {
+	s.record(ctx, models.LoginEvent{Event: models.LoginFailed, Username: username, IP: clientIP})
+	if s.Guard == nil {
+		return
+	}
+	if s.Guard.Fail(userKey(username), s.Login.MaxFailures) > 0 {
+		s.record(ctx, models.LoginEvent{Event: models.AccountLocked, Username: username, IP: clientIP})
+	}
+	if s.Guard.Fail(ipKey(clientIP), s.Login.IPMaxFailures) > 0 {
+		s.record(ctx, models.LoginEvent{Event: models.IPLocked, Username: username, IP: clientIP})
+	}
+}
+
+// 監査ログの記録に失敗してもログイン処理は続ける
+func (s *userService) record(ctx context.Context, event models.LoginEvent) {
+	if err := s.Events.CreateLoginEvent(ctx, event); err != nil {
+		s.Logger.ErrorContext(ctx, "監査ログの記録に失敗しました", "event", event.Event, "username", event.Username, "error", err)
+	}
+}
+
+func userKey(username string) string { return "user:" + username }
+func ipKey(ip string) string         { return "ip:" + ip }
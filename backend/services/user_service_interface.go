@@ -1,8 +1,13 @@
 package services
 
-import "chat/models"
+import (
+	"chat/models"
+	"context"
+)
 
 type UserService interface {
-	RegisterUser(user models.User) error
-	AuthenticateUser(user models.User) (string, error)
+	RegisterUser(ctx context.Context, user models.User) error
+	AuthenticateUser(ctx context.Context, user models.User, clientIP string) (string, error)
+	VerifyToken(token string) (string, error)
+	UnlockUser(ctx context.Context, actor, username, clientIP string) error
 }
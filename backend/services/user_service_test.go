@@ -1,10 +1,16 @@
 package services_test
 
 import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/logging"
 	"chat/models"
 	"chat/services"
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/assert"
@@ -16,31 +22,66 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) CreateUser(user models.User) error {
+func (m *MockUserRepository) CreateUser(ctx context.Context, user models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) GetUserByUsername(username string) (models.User, error) {
+func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
 	args := m.Called(username)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetPasswordByUsername(username string) (string, error) {
+func (m *MockUserRepository) GetPasswordByUsername(ctx context.Context, username string) (string, error) {
 	args := m.Called(username)
 	return args.String(0), args.Error(1)
 }
 
+// MockLoginEventRepository は LoginEventRepository のモック
+type MockLoginEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginEventRepository) CreateLoginEvent(ctx context.Context, event models.LoginEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+var testJWTConfig = config.JWTConfig{Secret: "your-secret-key", TTL: time.Hour}
+
+// ロック前の待ち時間はほぼなし、ロックは3回目の失敗から
+var testLoginConfig = config.LoginGuardConfig{
+	Enabled:         true,
+	MaxFailures:     3,
+	IPMaxFailures:   5,
+	BaseDelay:       time.Nanosecond,
+	LockoutDuration: time.Hour,
+	MaxLockout:      24 * time.Hour,
+}
+
+const testClientIP = "10.0.0.1"
+
+// 監査ログの記録をすべて受け付けるモック
+func allowEvents() *MockLoginEventRepository {
+	events := new(MockLoginEventRepository)
+	events.On("CreateLoginEvent", mock.Anything).Return(nil).Maybe()
+	return events
+}
+
+func newUserService(repo *MockUserRepository) services.UserService {
+	return services.NewUserService(repo, allowEvents(), testJWTConfig, testLoginConfig, logging.Discard())
+}
+
 func TestRegisterUser_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	service := newUserService(mockRepo)
 
 	newUser := models.User{Username: "testuser", Password: "securepassword"}
 
-	mockRepo.On("GetUserByUsername", "testuser").Return(models.User{}, errors.New("not found"))
+	mockRepo.On("GetUserByUsername", "testuser").Return(models.User{}, apperrors.ErrNotFound)
 	mockRepo.On("CreateUser", newUser).Return(nil)
 
-	err := service.RegisterUser(newUser)
+	err := service.RegisterUser(context.Background(), newUser)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
@@ -48,13 +89,13 @@ func TestRegisterUser_Success(t *testing.T) {
 
 func TestRegisterUser_AlreadyExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	service := newUserService(mockRepo)
 
 	existingUser := models.User{Username: "testuser", Password: "oldpassword"}
 
 	mockRepo.On("GetUserByUsername", "testuser").Return(existingUser, nil)
 
-	err := service.RegisterUser(existingUser)
+	err := service.RegisterUser(context.Background(), existingUser)
 
 	assert.Error(t, err)
 	assert.Equal(t, "ユーザー名が既に使用されています", err.Error())
@@ -63,13 +104,13 @@ func TestRegisterUser_AlreadyExists(t *testing.T) {
 
 func TestAuthenticateUser_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	service := newUserService(mockRepo)
 
 	user := models.User{Username: "testuser", Password: "securepassword"}
 
 	mockRepo.On("GetPasswordByUsername", "testuser").Return("securepassword", nil)
 
-	token, err := service.AuthenticateUser(user)
+	token, err := service.AuthenticateUser(context.Background(), user, testClientIP)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
@@ -85,13 +126,13 @@ func TestAuthenticateUser_Success(t *testing.T) {
 
 func TestAuthenticateUser_WrongPassword(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	service := newUserService(mockRepo)
 
 	user := models.User{Username: "testuser", Password: "wrongpassword"}
 
 	mockRepo.On("GetPasswordByUsername", "testuser").Return("securepassword", nil)
 
-	token, err := service.AuthenticateUser(user)
+	token, err := service.AuthenticateUser(context.Background(), user, testClientIP)
 
 	assert.Error(t, err)
 	assert.Empty(t, token)
@@ -101,11 +142,11 @@ func TestAuthenticateUser_WrongPassword(t *testing.T) {
 
 func TestAuthenticateUser_NotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	service := newUserService(mockRepo)
 
-	mockRepo.On("GetPasswordByUsername", "unknownuser").Return("", errors.New("not found"))
+	mockRepo.On("GetPasswordByUsername", "unknownuser").Return("", apperrors.ErrNotFound)
 
-	token, err := service.AuthenticateUser(models.User{Username: "unknownuser", Password: "password"})
+	token, err := service.AuthenticateUser(context.Background(), models.User{Username: "unknownuser", Password: "password"}, testClientIP)
 
 	assert.Error(t, err)
 	assert.Empty(t, token)
@@ -115,16 +156,196 @@ func TestAuthenticateUser_NotFound(t *testing.T) {
 
 func TestAuthenticateUser_EmptyPasswordInDB(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	service := newUserService(mockRepo)
 
 	user := models.User{Username: "testuser", Password: "securepassword"}
 
 	mockRepo.On("GetPasswordByUsername", "testuser").Return("", nil)
 
-	token, err := service.AuthenticateUser(user)
+	token, err := service.AuthenticateUser(context.Background(), user, testClientIP)
 
 	assert.Error(t, err)
 	assert.Empty(t, token)
 	assert.Equal(t, "認証失敗", err.Error())
 	mockRepo.AssertExpectations(t)
 }
+
+func TestRegisterUser_ConcurrentDuplicate(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo)
+
+	newUser := models.User{Username: "testuser", Password: "securepassword"}
+	mockRepo.On("GetUserByUsername", "testuser").Return(models.User{}, apperrors.ErrNotFound)
+	mockRepo.On("CreateUser", newUser).Return(apperrors.ErrConflict)
+
+	err := service.RegisterUser(context.Background(), newUser)
+
+	assert.ErrorIs(t, err, services.ErrUsernameTaken)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRegisterUser_DBError(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo)
+
+	dbErr := errors.New("connection refused")
+	mockRepo.On("GetUserByUsername", "testuser").Return(models.User{}, dbErr)
+
+	err := service.RegisterUser(context.Background(), models.User{Username: "testuser", Password: "securepassword"})
+
+	// DB障害はユーザー名の重複として扱わない
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, apperrors.ErrConflict)
+	mockRepo.AssertNotCalled(t, "CreateUser")
+}
+
+func TestAuthenticateUser_DBError(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo)
+
+	dbErr := errors.New("connection refused")
+	mockRepo.On("GetPasswordByUsername", "testuser").Return("", dbErr)
+
+	token, err := service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "password"}, testClientIP)
+
+	assert.Empty(t, token)
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, apperrors.ErrUnauthorized)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVerifyToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo)
+
+	mockRepo.On("GetPasswordByUsername", "testuser").Return("securepassword", nil)
+	token, err := service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "securepassword"}, testClientIP)
+	assert.NoError(t, err)
+
+	username, err := service.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "testuser", username)
+
+	// 別の鍵で署名されたトークン
+	other := services.NewUserService(mockRepo, allowEvents(), config.JWTConfig{Secret: "other-secret", TTL: time.Hour}, testLoginConfig, logging.Discard())
+	otherToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "testuser"}).
+		SignedString([]byte("other-secret"))
+	_, err = service.VerifyToken(otherToken)
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+	_, err = other.VerifyToken(token)
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+
+	// 期限切れ
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": "testuser",
+		"exp":      time.Now().Add(-time.Minute).Unix(),
+	}).SignedString([]byte(testJWTConfig.Secret))
+	_, err = service.VerifyToken(expired)
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+
+	_, err = service.VerifyToken("garbage")
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+}
+
+func TestAuthenticateUser_LocksAfterRepeatedFailures(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	events := new(MockLoginEventRepository)
+	service := services.NewUserService(mockRepo, events, testJWTConfig, testLoginConfig, logging.Discard())
+
+	mockRepo.On("GetPasswordByUsername", "testuser").Return("securepassword", nil).Times(3)
+	events.On("CreateLoginEvent", models.LoginEvent{Event: models.LoginFailed, Username: "testuser", IP: testClientIP}).Return(nil).Times(3)
+	events.On("CreateLoginEvent", models.LoginEvent{Event: models.AccountLocked, Username: "testuser", IP: testClientIP}).Return(nil).Once()
+
+	wrong := models.User{Username: "testuser", Password: "wrongpassword"}
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond)
+		_, err := service.AuthenticateUser(context.Background(), wrong, testClientIP)
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	}
+
+	// ロック中は正しいパスワードでも DB を見ずに拒否する
+	_, err := service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "securepassword"}, testClientIP)
+	assert.ErrorIs(t, err, services.ErrAccountLocked)
+	var appErr *apperrors.Error
+	assert.True(t, errors.As(err, &appErr))
+	assert.InDelta(t, time.Hour.Seconds(), appErr.RetryAfter.Seconds(), 1)
+
+	mockRepo.AssertExpectations(t)
+	events.AssertExpectations(t)
+}
+
+func TestAuthenticateUser_LocksIPAcrossUsernames(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := services.NewUserService(mockRepo, allowEvents(), testJWTConfig, testLoginConfig, logging.Discard())
+
+	mockRepo.On("GetPasswordByUsername", mock.Anything).Return("", apperrors.ErrNotFound)
+
+	// ユーザー名を変えながら総当たりしても IP 単位でロックされる
+	for i := 0; i < testLoginConfig.IPMaxFailures; i++ {
+		time.Sleep(time.Millisecond)
+		_, err := service.AuthenticateUser(context.Background(), models.User{Username: fmt.Sprintf("user%d", i), Password: "password1"}, testClientIP)
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	}
+	_, err := service.AuthenticateUser(context.Background(), models.User{Username: "another", Password: "password1"}, testClientIP)
+	assert.ErrorIs(t, err, services.ErrAccountLocked)
+
+	// 別の IP からは試行できる
+	_, err = service.AuthenticateUser(context.Background(), models.User{Username: "another", Password: "password1"}, "10.0.0.2")
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+}
+
+func TestAuthenticateUser_AuditsSuccess(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	events := new(MockLoginEventRepository)
+	service := services.NewUserService(mockRepo, events, testJWTConfig, testLoginConfig, logging.Discard())
+
+	mockRepo.On("GetPasswordByUsername", "testuser").Return("securepassword", nil)
+	// 監査ログの失敗でログインは失敗しない
+	events.On("CreateLoginEvent", models.LoginEvent{Event: models.LoginSucceeded, Username: "testuser", IP: testClientIP}).
+		Return(errors.New("db down"))
+
+	token, err := service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "securepassword"}, testClientIP)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	events.AssertExpectations(t)
+}
+
+func TestUnlockUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	events := new(MockLoginEventRepository)
+	service := services.NewUserService(mockRepo, events, testJWTConfig, testLoginConfig, logging.Discard())
+
+	mockRepo.On("GetPasswordByUsername", "testuser").Return("securepassword", nil)
+	mockRepo.On("GetUserByUsername", "admin").Return(models.User{Username: "admin", Role: models.RoleAdmin}, nil)
+	mockRepo.On("GetUserByUsername", "testuser").Return(models.User{Username: "testuser", Role: models.RoleMember}, nil)
+	events.On("CreateLoginEvent", mock.MatchedBy(func(e models.LoginEvent) bool { return e.Event != models.AccountUnlocked })).Return(nil)
+	events.On("CreateLoginEvent", models.LoginEvent{Event: models.AccountUnlocked, Username: "testuser", IP: "10.0.0.9", Actor: "admin"}).Return(nil).Once()
+
+	// 別々の IP から失敗させてアカウントだけをロックする
+	for i := 0; i < testLoginConfig.MaxFailures; i++ {
+		time.Sleep(time.Millisecond)
+		service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "wrongpassword"}, fmt.Sprintf("10.0.1.%d", i))
+	}
+	_, err := service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "securepassword"}, testClientIP)
+	assert.ErrorIs(t, err, services.ErrAccountLocked)
+
+	// 一般ユーザーは解除できない
+	err = service.UnlockUser(context.Background(), "testuser", "testuser", "10.0.0.9")
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	assert.NoError(t, service.UnlockUser(context.Background(), "admin", "testuser", "10.0.0.9"))
+	_, err = service.AuthenticateUser(context.Background(), models.User{Username: "testuser", Password: "securepassword"}, testClientIP)
+	assert.NoError(t, err)
+	events.AssertExpectations(t)
+}
+
+func TestUnlockUser_NotFound(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo)
+
+	mockRepo.On("GetUserByUsername", "admin").Return(models.User{Username: "admin", Role: models.RoleAdmin}, nil)
+	mockRepo.On("GetUserByUsername", "ghost").Return(models.User{}, apperrors.ErrNotFound)
+
+	err := service.UnlockUser(context.Background(), "admin", "ghost", testClientIP)
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+}
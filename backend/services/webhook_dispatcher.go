@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"chat/apperrors"
+	"chat/config"
+	"chat/metrics"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"chat/webhook"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 応答の本文は読み捨てる（コネクションを再利用するため、上限までは読む）
+const webhookResponseLimit = 64 << 10
+
+type webhookDispatcher struct {
+	Repo    repositories.WebhookRepository
+	Client  *http.Client
+	Config  config.WebhookConfig
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+
+	wake     chan struct{}
+	quit     chan struct{}
+	stopped  chan struct{}
+	quitOnce sync.Once
+	// Shutdown の期限を過ぎたら送信中のリクエストを打ち切る
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewWebhookDispatcher(repo repositories.WebhookRepository, cfg config.WebhookConfig, logger *slog.Logger, m *metrics.Metrics) WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookDispatcher{
+		Repo:    repo,
+		Client:  webhook.NewClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+		Config:  cfg,
+		Logger:  logger,
+		Metrics: m,
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// PollInterval ごと（Wake された場合はすぐ）に送信時刻になった配信を送る
+func (d *webhookDispatcher) Run() {
+	defer close(d.stopped)
+
+	ticker := time.NewTicker(d.Config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatch()
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// 送信待ちがあることを知らせる（ブロックしない）
+func (d *webhookDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 新しい送信を止め、送信中のリクエストの完了を ctx の期限まで待つ
+// 期限を過ぎた送信は打ち切り、取り出した配信は lease の後に再送される。
+func (d *webhookDispatcher) Shutdown(ctx context.Context) error {
+	d.quitOnce.Do(func() { close(d.quit) })
+
+	select {
+	case <-d.stopped:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.stopped
+		return ctx.Err()
+	}
+}
+
+// 送信時刻になった配信がなくなるまで、Concurrency 件ずつ並行して送る
+func (d *webhookDispatcher) dispatch() {
+	// 送信中にプロセスが落ちても、タイムアウトの後には再送されるようにする
+	lease := 2 * d.Config.Timeout
+
+	for {
+		deliveries, err := d.Repo.ClaimDueDeliveries(d.ctx, time.Now(), lease, d.Config.Concurrency)
+		if err != nil {
+			d.Logger.Error("Webhook の配信を取得できませんでした", "error", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery models.WebhookDelivery) {
+				defer wg.Done()
+				d.deliver(d.ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < d.Config.Concurrency {
+			return
+		}
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+	}
+}
+
+// 1件送信し、結果を記録する。失敗した場合は次の送信時刻を決めるか、上限に達したら諦める
+func (d *webhookDispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	ctx, span := tracing.Start(ctx, "WebhookDispatcher.Deliver",
+		attribute.Int("webhook_id", delivery.WebhookID),
+		attribute.Int("delivery_id", delivery.ID),
+		attribute.Int("attempt", delivery.Attempts+1),
+	)
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	leaseUntil := delivery.NextAttemptAt
+	attempt := models.WebhookAttempt{DeliveryID: delivery.ID, Attempt: delivery.Attempts + 1}
+	start := time.Now()
+	status, sendErr := d.send(ctx, delivery)
+	attempt.DurationMs = int(time.Since(start).Milliseconds())
+	if status != 0 {
+		attempt.StatusCode = &status
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+	span.SetAttributes(attribute.Int("http.status_code", status))
+
+	delivery.Attempts = attempt.Attempt
+	result := "retry"
+	switch {
+	case sendErr == nil && status >= 200 && status < 300:
+		delivery.Status = models.DeliverySucceeded
+		result = "succeeded"
+	case delivery.Attempts >= d.Config.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		result = "failed"
+	default:
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	}
+
+	// 送信の打ち切り後も結果は残す
+	if err = d.Repo.RecordAttempt(context.WithoutCancel(ctx), delivery, leaseUntil, attempt); err != nil {
+		// lease が切れて他のワーカーが取り出し直した配信は、新しい方の結果を残す
+		if errors.Is(err, apperrors.ErrNotFound) {
+			d.Logger.WarnContext(ctx, "Webhook の送信結果を記録しませんでした", "delivery_id", delivery.ID, "error", err)
+			return
+		}
+		d.Logger.ErrorContext(ctx, "Webhook の送信結果を記録できませんでした", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	d.Metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+	if result == "failed" {
+		d.Logger.WarnContext(ctx, "Webhook の配信を諦めました", "webhook_id", delivery.WebhookID, "delivery_id", delivery.ID, "attempts", delivery.Attempts)
+	}
+}
+
+// 署名したペイロードを送信し、応答のステータスコードを返す（応答がなければ 0）
+func (d *webhookDispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	if delivery.Webhook == nil {
+		return 0, ErrWebhookNotFound
+	}
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chat-webhook/1")
+	req.Header.Set(webhook.HeaderEvent, delivery.Event)
+	req.Header.Set(webhook.HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, nil
+}
+
+// n 回目の失敗の後の待ち時間（BaseBackoff から倍々に増やし、MaxBackoff で止める）
+func (d *webhookDispatcher) backoff(n int) time.Duration {
+	wait := d.Config.BaseBackoff
+	for i := 1; i < n; i++ {
+		wait *= 2
+		if wait >= d.Config.MaxBackoff {
+			return d.Config.MaxBackoff
+		}
+	}
+	return min(wait, d.Config.MaxBackoff)
+}
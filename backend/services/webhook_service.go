@@ -0,0 +1,197 @@
+package services
+
+import (
+	"chat/apperrors"
+	"chat/models"
+	"chat/repositories"
+	"chat/tracing"
+	"chat/webhook"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 配信一覧で返す件数
+const webhookDeliveryListLimit = 50
+
+// Webhook で通知できるイベント
+var webhookEvents = map[string]bool{
+	models.EventMessageCreated: true,
+	models.EventMessageDeleted: true,
+}
+
+type webhookService struct {
+	Repo       repositories.WebhookRepository
+	SpaceRepo  repositories.SpaceRepository
+	UserRepo   repositories.UserRepository
+	Dispatcher WebhookDispatcher
+	Logger     *slog.Logger
+}
+
+func NewWebhookService(repo repositories.WebhookRepository, spaceRepo repositories.SpaceRepository, userRepo repositories.UserRepository, dispatcher WebhookDispatcher, logger *slog.Logger) WebhookService {
+	return &webhookService{Repo: repo, SpaceRepo: spaceRepo, UserRepo: userRepo, Dispatcher: dispatcher, Logger: logger}
+}
+
+// 送信する JSON
+type webhookPayload struct {
+	Event      string    `json:"event"`
+	SpaceID    int       `json:"space_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Webhook を登録する。署名の鍵はここで生成し、返した Webhook の Secret でのみ渡す
+func (s *webhookService) CreateWebhook(ctx context.Context, actor string, spaceID int, rawURL string, events []string) (hook models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateWebhook", attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if !validWebhook(rawURL, events) {
+		return hook, ErrWebhookInvalid
+	}
+	user, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID)
+	if err != nil {
+		return hook, err
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return hook, err
+	}
+	hook, err = s.Repo.CreateWebhook(ctx, models.Webhook{
+		SpaceID:   spaceID,
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    strings.Join(dedupe(events), ","),
+		CreatedBy: &user.ID,
+	})
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return hook, ErrSpaceNotFound.Wrap(err)
+	}
+	return hook, err
+}
+
+// スペースの Webhook 一覧
+func (s *webhookService) ListWebhooks(ctx context.Context, actor string, spaceID int) (hooks []models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListWebhooks", attribute.Int("space_id", spaceID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListWebhooks(ctx, spaceID)
+}
+
+// Webhook を削除する
+func (s *webhookService) DeleteWebhook(ctx context.Context, actor string, spaceID, webhookID int) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteWebhook", attribute.Int("space_id", spaceID), attribute.Int("webhook_id", webhookID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID); err != nil {
+		return err
+	}
+	err = s.Repo.DeleteWebhook(ctx, webhookID, spaceID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ErrWebhookNotFound.Wrap(err)
+	}
+	return err
+}
+
+// Webhook の最近の配信と送信の記録
+func (s *webhookService) ListDeliveries(ctx context.Context, actor string, spaceID, webhookID int) (deliveries []models.WebhookDelivery, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListDeliveries", attribute.Int("space_id", spaceID), attribute.Int("webhook_id", webhookID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeSpaceManager(ctx, s.UserRepo, s.SpaceRepo, actor, spaceID); err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.GetWebhook(ctx, webhookID, spaceID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, ErrWebhookNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+	return s.Repo.ListDeliveries(ctx, webhookID, webhookDeliveryListLimit)
+}
+
+// イベントを購読している Webhook の配信を登録し、ディスパッチャーを起こす
+//
+// 通知はメッセージの保存の後に行うため、リクエストが打ち切られても配信の登録は続ける。
+// 登録に失敗した場合はログに残し、呼び出し元の処理は成功のままにする。
+func (s *webhookService) Notify(ctx context.Context, event SpaceEvent) {
+	ctx = context.WithoutCancel(ctx)
+	ctx, span := tracing.Start(ctx, "WebhookService.Notify", attribute.String("event", event.Type), attribute.Int("space_id", event.SpaceID))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	hooks, err := s.Repo.ListWebhooks(ctx, event.SpaceID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Webhook の取得に失敗しました", "space_id", event.SpaceID, "error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	payload, err := json.Marshal(webhookPayload{Event: event.Type, SpaceID: event.SpaceID, OccurredAt: now, Data: event.Data})
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Webhook のペイロードを作成できませんでした", "event", event.Type, "error", err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, hook := range hooks {
+		if !hook.Subscribes(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	if err = s.Repo.CreateDeliveries(ctx, deliveries); err != nil {
+		s.Logger.ErrorContext(ctx, "Webhook の配信を登録できませんでした", "space_id", event.SpaceID, "event", event.Type, "error", err)
+		return
+	}
+	s.Dispatcher.Wake()
+}
+
+// http・https の URL で、通知できるイベントだけが指定されているか
+func validWebhook(rawURL string, events []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if len(events) == 0 {
+		return false
+	}
+	for _, e := range events {
+		if !webhookEvents[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// 重複を除く（順序は保つ）
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"chat/models"
+	"context"
+)
+
+// スペースで起きたイベント（Webhook で通知する）
+type SpaceEvent struct {
+	// models.EventMessageCreated など
+	Type    string
+	SpaceID int
+	// ペイロードの data に入れる内容
+	Data any
+}
+
+// スペースのイベントの通知先（WebhookService が実装する）
+// 通知の失敗は呼び出し元の処理の失敗にしないため、エラーは返さない。
+type EventNotifier interface {
+	Notify(ctx context.Context, event SpaceEvent)
+}
+
+// スペースの Webhook の管理。actor はログイン中のユーザー名で、スペースの所有者か管理者のみ操作できる
+type WebhookService interface {
+	EventNotifier
+	CreateWebhook(ctx context.Context, actor string, spaceID int, url string, events []string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, actor string, spaceID int) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, actor string, spaceID, webhookID int) error
+	ListDeliveries(ctx context.Context, actor string, spaceID, webhookID int) ([]models.WebhookDelivery, error)
+}
+
+// 送信待ちの配信をバックグラウンドで送る
+type WebhookDispatcher interface {
+	// 送信のループ（Shutdown まで戻らない）
+	Run()
+	// 新しい配信が登録されたことを知らせ、次の確認を待たずに送る
+	Wake()
+	Shutdown(ctx context.Context) error
+}
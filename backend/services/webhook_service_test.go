@@ -0,0 +1,358 @@
+package services_test
+
+import (
+	"chat/apperrors"
+	"chat/config"
+	"chat/logging"
+	"chat/metrics"
+	"chat/models"
+	"chat/services"
+	"chat/webhook"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockWebhookRepository は WebhookRepository のモック
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	args := m.Called(hook)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListWebhooks(ctx context.Context, spaceID int) ([]models.Webhook, error) {
+	args := m.Called(spaceID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhook(ctx context.Context, webhookID, spaceID int) (models.Webhook, error) {
+	args := m.Called(webhookID, spaceID)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, webhookID, spaceID int) error {
+	args := m.Called(webhookID, spaceID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	args := m.Called(deliveries)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(now, lease, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, leaseUntil time.Time, attempt models.WebhookAttempt) error {
+	args := m.Called(delivery, leaseUntil, attempt)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(webhookID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+// MockWebhookDispatcher は WebhookDispatcher のモック
+type MockWebhookDispatcher struct {
+	mock.Mock
+}
+
+func (m *MockWebhookDispatcher) Run() { m.Called() }
+
+func (m *MockWebhookDispatcher) Wake() { m.Called() }
+
+func (m *MockWebhookDispatcher) Shutdown(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func ownedSpace(ownerID int) models.Space {
+	return models.Space{ID: 1, Name: "general", OwnerID: &ownerID}
+}
+
+func TestCreateWebhook_Owner(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	service := services.NewWebhookService(repo, spaceRepo, userRepo, new(MockWebhookDispatcher), logging.Discard())
+
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice", Role: models.RoleMember}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+	repo.On("CreateWebhook", mock.MatchedBy(func(h models.Webhook) bool {
+		// 重複したイベントは1つにまとめ、鍵はサーバーで生成する
+		return h.SpaceID == 1 && h.URL == "https://example.com/hook" && h.Events == "message.created,message.deleted" &&
+			len(h.Secret) == 64 && *h.CreatedBy == 7
+	})).Return(models.Webhook{ID: 3, SpaceID: 1, Secret: "s3cret"}, nil)
+
+	hook, err := service.CreateWebhook(context.Background(), "alice", 1, "https://example.com/hook",
+		[]string{models.EventMessageCreated, models.EventMessageDeleted, models.EventMessageCreated})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, hook.ID)
+
+	repo.AssertExpectations(t)
+}
+
+// 所有者・管理者以外は操作できない
+func TestWebhookService_Forbidden(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	service := services.NewWebhookService(repo, spaceRepo, userRepo, new(MockWebhookDispatcher), logging.Discard())
+
+	userRepo.On("GetUserByUsername", "bob").Return(models.User{ID: 8, Username: "bob", Role: models.RoleMember}, nil)
+	userRepo.On("GetUserByUsername", "admin").Return(models.User{ID: 1, Username: "admin", Role: models.RoleAdmin}, nil)
+	userRepo.On("GetUserByUsername", "ghost").Return(models.User{}, apperrors.ErrNotFound)
+	spaceRepo.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+	spaceRepo.On("GetSpaceByID", 2).Return(models.Space{ID: 2}, nil)
+	spaceRepo.On("GetSpaceByID", 99).Return(models.Space{}, apperrors.ErrNotFound)
+	repo.On("ListWebhooks", 1).Return([]models.Webhook{{ID: 3, SpaceID: 1}}, nil)
+
+	_, err := service.ListWebhooks(context.Background(), "bob", 1)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	_, err = service.ListWebhooks(context.Background(), "ghost", 1)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	// 所有者のいないスペースは管理者のみ
+	err = service.DeleteWebhook(context.Background(), "bob", 2, 3)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	_, err = service.ListWebhooks(context.Background(), "bob", 99)
+	assert.ErrorIs(t, err, services.ErrSpaceNotFound)
+
+	hooks, err := service.ListWebhooks(context.Background(), "admin", 1)
+	assert.NoError(t, err)
+	assert.Len(t, hooks, 1)
+
+	repo.AssertNotCalled(t, "DeleteWebhook", mock.Anything, mock.Anything)
+}
+
+func TestCreateWebhook_Invalid(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	service := services.NewWebhookService(repo, new(MockSpaceRepository), new(MockUserRepository), new(MockWebhookDispatcher), logging.Discard())
+
+	for _, tc := range []struct {
+		url    string
+		events []string
+	}{
+		{"ftp://example.com/hook", []string{models.EventMessageCreated}},
+		{"https://example.com/hook", nil},
+		{"https://example.com/hook", []string{"member.joined"}},
+	} {
+		_, err := service.CreateWebhook(context.Background(), "alice", 1, tc.url, tc.events)
+		assert.ErrorIs(t, err, services.ErrWebhookInvalid, "url=%s events=%v", tc.url, tc.events)
+	}
+	repo.AssertNotCalled(t, "CreateWebhook", mock.Anything)
+}
+
+func TestDeleteWebhook_NotFound(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	spaceRepo := new(MockSpaceRepository)
+	userRepo := new(MockUserRepository)
+	service := services.NewWebhookService(repo, spaceRepo, userRepo, new(MockWebhookDispatcher), logging.Discard())
+
+	userRepo.On("GetUserByUsername", "alice").Return(models.User{ID: 7, Username: "alice"}, nil)
+	spaceRepo.On("GetSpaceByID", 1).Return(ownedSpace(7), nil)
+	repo.On("DeleteWebhook", 5, 1).Return(apperrors.ErrNotFound)
+	repo.On("GetWebhook", 5, 1).Return(models.Webhook{}, apperrors.ErrNotFound)
+
+	err := service.DeleteWebhook(context.Background(), "alice", 1, 5)
+	assert.ErrorIs(t, err, services.ErrWebhookNotFound)
+
+	_, err = service.ListDeliveries(context.Background(), "alice", 1, 5)
+	assert.ErrorIs(t, err, services.ErrWebhookNotFound)
+}
+
+// イベントを購読している Webhook にだけ配信を登録し、ディスパッチャーを起こす
+func TestWebhookService_Notify(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	dispatcher := new(MockWebhookDispatcher)
+	service := services.NewWebhookService(repo, new(MockSpaceRepository), new(MockUserRepository), dispatcher, logging.Discard())
+
+	repo.On("ListWebhooks", 1).Return([]models.Webhook{
+		{ID: 3, SpaceID: 1, Events: "message.created"},
+		{ID: 4, SpaceID: 1, Events: "message.deleted"},
+		{ID: 5, SpaceID: 1, Events: "message.created,message.deleted"},
+	}, nil)
+	repo.On("CreateDeliveries", mock.MatchedBy(func(ds []models.WebhookDelivery) bool {
+		if len(ds) != 2 || ds[0].WebhookID != 3 || ds[1].WebhookID != 5 {
+			return false
+		}
+		var payload struct {
+			Event   string         `json:"event"`
+			SpaceID int            `json:"space_id"`
+			Data    models.Message `json:"data"`
+		}
+		return json.Unmarshal([]byte(ds[0].Payload), &payload) == nil &&
+			payload.Event == models.EventMessageCreated && payload.SpaceID == 1 && payload.Data.Text == "Hello" &&
+			ds[0].Status == models.DeliveryPending
+	})).Return(nil).Once()
+	dispatcher.On("Wake").Once()
+
+	// リクエストが終わっていても登録する
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.Notify(ctx, services.SpaceEvent{Type: models.EventMessageCreated, SpaceID: 1, Data: models.Message{ID: 10, SpaceID: 1, Text: "Hello"}})
+
+	repo.AssertExpectations(t)
+	dispatcher.AssertExpectations(t)
+}
+
+func TestWebhookService_Notify_Error(t *testing.T) {
+	repo := new(MockWebhookRepository)
+	dispatcher := new(MockWebhookDispatcher)
+	service := services.NewWebhookService(repo, new(MockSpaceRepository), new(MockUserRepository), dispatcher, logging.Discard())
+
+	repo.On("ListWebhooks", 2).Return([]models.Webhook{}, nil)
+	repo.On("ListWebhooks", 1).Return([]models.Webhook{{ID: 3, SpaceID: 1, Events: "message.created"}}, nil)
+	repo.On("CreateDeliveries", mock.Anything).Return(errors.New("DB error"))
+
+	// 購読がなければ何もしない。登録に失敗しても呼び出し元には返さない
+	service.Notify(context.Background(), services.SpaceEvent{Type: models.EventMessageCreated, SpaceID: 2})
+	service.Notify(context.Background(), services.SpaceEvent{Type: models.EventMessageCreated, SpaceID: 1})
+
+	repo.AssertNumberOfCalls(t, "CreateDeliveries", 1)
+	dispatcher.AssertNotCalled(t, "Wake")
+}
+
+// 失敗した配信は待ち時間を空けて再送し、成功したら完了にする
+func TestWebhookDispatcher_RetryThenSuccess(t *testing.T) {
+	const secret = "s3cret"
+	payload := `{"event":"message.created","space_id":1,"data":{"id":10}}`
+
+	requests := make(chan *http.Request, 2)
+	statuses := []int{http.StatusInternalServerError, http.StatusNoContent}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		assert.True(t, webhook.Verify(secret, ts, body, r.Header.Get(webhook.HeaderSignature)), "署名が一致しない")
+		assert.Equal(t, payload, string(body))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, models.EventMessageCreated, r.Header.Get(webhook.HeaderEvent))
+		assert.Equal(t, "10", r.Header.Get(webhook.HeaderDelivery))
+
+		w.WriteHeader(statuses[len(requests)])
+		requests <- r
+	}))
+	defer server.Close()
+
+	cfg := config.Default().Webhook
+	cfg.AllowPrivateNetworks = true
+	cfg.PollInterval = time.Hour
+	m := metrics.New()
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo, cfg, logging.Discard(), m)
+
+	hook := &models.Webhook{ID: 3, SpaceID: 1, URL: server.URL, Secret: secret}
+	leaseUntil := time.Now().Add(2 * cfg.Timeout).Truncate(time.Microsecond)
+	delivery := models.WebhookDelivery{ID: 10, WebhookID: 3, Webhook: hook, Event: models.EventMessageCreated, Payload: payload, Status: models.DeliveryPending, NextAttemptAt: leaseUntil}
+	retried := delivery
+	retried.Attempts = 1
+
+	recorded := make(chan models.WebhookDelivery, 2)
+	repo.On("ClaimDueDeliveries", mock.Anything, 2*cfg.Timeout, cfg.Concurrency).Return([]models.WebhookDelivery{delivery}, nil).Once()
+	repo.On("ClaimDueDeliveries", mock.Anything, 2*cfg.Timeout, cfg.Concurrency).Return([]models.WebhookDelivery{retried}, nil).Once()
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]models.WebhookDelivery{}, nil)
+	// 取り出した時の送信時刻で照合して記録する
+	repo.On("RecordAttempt", mock.Anything, leaseUntil, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		recorded <- args.Get(0).(models.WebhookDelivery)
+	})
+
+	start := time.Now()
+	go dispatcher.Run()
+
+	first := <-recorded
+	assert.Equal(t, models.DeliveryPending, first.Status)
+	assert.Equal(t, 1, first.Attempts)
+	assert.WithinDuration(t, start.Add(cfg.BaseBackoff), first.NextAttemptAt, 5*time.Second)
+
+	// 新しい配信の登録（Wake）で次の確認を待たずに送る
+	dispatcher.Wake()
+	second := <-recorded
+	assert.Equal(t, models.DeliverySucceeded, second.Status)
+	assert.Equal(t, 2, second.Attempts)
+
+	require.NoError(t, dispatcher.Shutdown(context.Background()))
+	assert.Len(t, requests, 2)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebhookDeliveries.WithLabelValues("retry")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebhookDeliveries.WithLabelValues("succeeded")))
+}
+
+// 上限に達したら諦める。送信先に接続できなかった場合はステータスコードなしで記録する
+func TestWebhookDispatcher_GiveUp(t *testing.T) {
+	cfg := config.Default().Webhook
+	cfg.PollInterval = time.Hour
+	m := metrics.New()
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo, cfg, logging.Discard(), m)
+
+	// プライベートアドレスへの送信は拒否される
+	hook := &models.Webhook{ID: 3, URL: "http://127.0.0.1:9/hook", Secret: "s3cret"}
+	delivery := models.WebhookDelivery{ID: 10, WebhookID: 3, Webhook: hook, Event: models.EventMessageCreated, Payload: `{}`, Attempts: cfg.MaxAttempts - 1}
+
+	recorded := make(chan mock.Arguments, 1)
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil).Once()
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]models.WebhookDelivery{}, nil)
+	repo.On("RecordAttempt", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) { recorded <- args })
+
+	go dispatcher.Run()
+	args := <-recorded
+	require.NoError(t, dispatcher.Shutdown(context.Background()))
+
+	d := args.Get(0).(models.WebhookDelivery)
+	attempt := args.Get(2).(models.WebhookAttempt)
+	assert.Equal(t, models.DeliveryFailed, d.Status)
+	assert.Equal(t, cfg.MaxAttempts, attempt.Attempt)
+	assert.Nil(t, attempt.StatusCode)
+	assert.Contains(t, attempt.Error, webhook.ErrForbiddenAddress.Error())
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebhookDeliveries.WithLabelValues("failed")))
+}
+
+// lease が切れて他のワーカーが取り出し直した配信は記録せず、結果も数えない
+func TestWebhookDispatcher_LostLease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := config.Default().Webhook
+	cfg.AllowPrivateNetworks = true
+	cfg.PollInterval = time.Hour
+	m := metrics.New()
+	repo := new(MockWebhookRepository)
+	dispatcher := services.NewWebhookDispatcher(repo, cfg, logging.Discard(), m)
+
+	hook := &models.Webhook{ID: 3, URL: server.URL, Secret: "s3cret"}
+	delivery := models.WebhookDelivery{ID: 10, WebhookID: 3, Webhook: hook, Event: models.EventMessageCreated, Payload: `{}`, Status: models.DeliveryPending}
+
+	recorded := make(chan struct{}, 1)
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil).Once()
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]models.WebhookDelivery{}, nil)
+	repo.On("RecordAttempt", mock.Anything, mock.Anything, mock.Anything).
+		Return(apperrors.ErrNotFound).
+		Run(func(args mock.Arguments) { recorded <- struct{}{} })
+
+	go dispatcher.Run()
+	<-recorded
+	require.NoError(t, dispatcher.Shutdown(context.Background()))
+
+	assert.Equal(t, 0, testutil.CollectAndCount(m.WebhookDeliveries))
+}
@@ -1,79 +1,349 @@
 package services
 
 import (
+	"chat/config"
+	"chat/metrics"
 	"chat/models"
-	"chat/repositories"
+	"chat/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type webSocketService struct {
-	Repo      repositories.MessageRepository
-	Clients   map[*websocket.Conn]bool
+	Clients map[*websocket.Conn]bool
+	// 接続ごとの情報（ログの接続 ID、メトリクスのスペース）
+	Info      map[*websocket.Conn]Client
 	Broadcast chan models.Message
-	Mutex     sync.Mutex
-	Upgrader  websocket.Upgrader
+	// メッセージ以外のイベント（メッセージと同じく HandleMessages で配信する）
+	Events   chan HubEvent
+	Mutex    sync.Mutex
+	Upgrader websocket.Upgrader
+	Config   config.WebSocketConfig
+	Logger   *slog.Logger
+	Metrics  *metrics.Metrics
+
+	// SSE の購読者（BroadcastMessage で同じスペースのメッセージを渡す）
+	subscribers map[*subscriber]struct{}
+	subBuffer   int
+
+	// ヘルスチェックからの応答確認
+	pings    chan chan struct{}
+	quit     chan struct{}
+	stopped  chan struct{}
+	quitOnce sync.Once
+}
+
+// SSE の購読者
+type subscriber struct {
+	spaceID int
+	ch      chan models.Message
 }
 
-func NewWebSocketService(repo repositories.MessageRepository) WebSocketService {
+func NewWebSocketService(cfg config.WebSocketConfig, sse config.SSEConfig, logger *slog.Logger, m *metrics.Metrics) WebSocketService {
 	return &webSocketService{
-		Repo:      repo,
-		Clients:   make(map[*websocket.Conn]bool),
-		Broadcast: make(chan models.Message),
+		Clients:     make(map[*websocket.Conn]bool),
+		Info:        make(map[*websocket.Conn]Client),
+		Broadcast:   make(chan models.Message, cfg.BroadcastBuffer),
+		Events:      make(chan HubEvent, cfg.BroadcastBuffer),
+		Config:      cfg,
+		Logger:      logger,
+		Metrics:     m,
+		subscribers: make(map[*subscriber]struct{}),
+		subBuffer:   sse.Buffer,
+		pings:       make(chan chan struct{}),
+		quit:        make(chan struct{}),
+		stopped:     make(chan struct{}),
 	}
 }
 
 // クライアントを追加
-func (s *webSocketService) AddClient(ws *websocket.Conn) {
+func (s *webSocketService) AddClient(ws *websocket.Conn, client Client) {
 	s.Mutex.Lock()
 	defer s.Mutex.Unlock()
+	if !s.Clients[ws] {
+		s.Metrics.WSConnections.WithLabelValues(metrics.SpaceLabel(client.SpaceID)).Inc()
+	}
 	s.Clients[ws] = true
+	s.Info[ws] = client
 }
 
 // クライアントを削除
 func (s *webSocketService) RemoveClient(ws *websocket.Conn) {
 	s.Mutex.Lock()
 	defer s.Mutex.Unlock()
-	delete(s.Clients, ws)
+	s.removeLocked(ws)
 }
 
-// **メッセージをDBに保存**
-func (s *webSocketService) SaveMessage(msg models.Message) error {
-	_, err := s.Repo.CreateMessage(msg)
-	return err
+// サーバー側の都合（レート制限など）でクライアントを切断する
+func (s *webSocketService) DropClient(ws *websocket.Conn, reason string) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.dropLocked(ws, reason)
+}
+
+// REST API で保存したメッセージを配信する（配信はハブのゴルーチンで行い、リクエストを待たせない）
+func (s *webSocketService) Publish(ctx context.Context, msg models.Message) error {
+	// Broadcast に空きがあっても、停止後は受け付けない
+	select {
+	case <-s.quit:
+		return errors.New("WebSocket ハブは停止しています")
+	default:
+	}
+
+	select {
+	case s.Broadcast <- msg:
+		return nil
+	case <-s.quit:
+		return errors.New("WebSocket ハブは停止しています")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ピン留めの変更などのイベントを配信する（Publish と同じくハブのゴルーチンで送る）
+func (s *webSocketService) PublishEvent(ctx context.Context, event HubEvent) error {
+	select {
+	case <-s.quit:
+		return errors.New("WebSocket ハブは停止しています")
+	default:
+	}
+
+	select {
+	case s.Events <- event:
+		return nil
+	case <-s.quit:
+		return errors.New("WebSocket ハブは停止しています")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// スペースのメッセージを SSE で受け取るチャネルを登録する
+// 配信に追いつけない購読者はチャネルを閉じて解除する（クライアントは Last-Event-ID で再接続する）。
+// 返した関数で購読を解除する（何度呼んでもよい）。
+func (s *webSocketService) Subscribe(spaceID int) (<-chan models.Message, func()) {
+	sub := &subscriber{spaceID: spaceID, ch: make(chan models.Message, s.subBuffer)}
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.subscribers[sub] = struct{}{}
+	s.Metrics.SSEConnections.WithLabelValues(metrics.SpaceLabel(spaceID)).Inc()
+
+	return sub.ch, func() {
+		s.Mutex.Lock()
+		defer s.Mutex.Unlock()
+		s.unsubscribeLocked(sub)
+	}
 }
 
-// **メッセージを全クライアントにブロードキャスト**
-func (s *webSocketService) BroadcastMessage(msg models.Message) {
+// **メッセージをそのスペースのクライアントにブロードキャスト**
+// イベントと同じく、スペース未指定の接続には全スペースのメッセージを送る。
+func (s *webSocketService) BroadcastMessage(ctx context.Context, msg models.Message) {
+	ctx, span := tracing.Start(ctx, "WebSocketService.BroadcastMessage", attribute.Int("space_id", msg.SpaceID))
+	defer span.End()
+
 	s.Mutex.Lock()
 	defer s.Mutex.Unlock()
+	span.SetAttributes(attribute.Int("ws.clients", len(s.Clients)))
 
+	start := time.Now()
 	for client := range s.Clients {
+		if spaceID := s.Info[client].SpaceID; spaceID != 0 && spaceID != msg.SpaceID {
+			continue
+		}
+		client.SetWriteDeadline(time.Now().Add(s.Config.WriteTimeout))
 		err := client.WriteJSON(msg)
 		if err != nil {
-			client.Close()
-			delete(s.Clients, client)
+			s.Logger.WarnContext(ctx, "送信に失敗したクライアントを切断しました", "conn_id", s.Info[client].ConnID, "error", err)
+			s.dropLocked(client, "write_error")
+		}
+	}
+	s.Metrics.BroadcastDuration.Observe(time.Since(start).Seconds())
+
+	for sub := range s.subscribers {
+		if sub.spaceID != msg.SpaceID {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			s.Metrics.DroppedClients.WithLabelValues("sse_buffer_full").Inc()
+			s.unsubscribeLocked(sub)
+		}
+	}
+}
+
+// イベントをそのスペースの WebSocket クライアント（スペース未指定の接続を含む）に送る
+// SSE は ID で再送できるメッセージのみ流すため、イベントは送らない（再接続時に一覧を取り直す）。
+func (s *webSocketService) BroadcastEvent(ctx context.Context, event HubEvent) {
+	ctx, span := tracing.Start(ctx, "WebSocketService.BroadcastEvent", attribute.String("event", event.Type), attribute.Int("space_id", event.SpaceID))
+	defer span.End()
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	for client := range s.Clients {
+		if spaceID := s.Info[client].SpaceID; spaceID != 0 && spaceID != event.SpaceID {
+			continue
+		}
+		client.SetWriteDeadline(time.Now().Add(s.Config.WriteTimeout))
+		if err := client.WriteJSON(event); err != nil {
+			s.Logger.WarnContext(ctx, "送信に失敗したクライアントを切断しました", "conn_id", s.Info[client].ConnID, "error", err)
+			s.dropLocked(client, "write_error")
+		}
+	}
+}
+
+// 1つの接続にだけ送る（コマンドの応答など）。配信と同じロックで書き込みを直列化する
+func (s *webSocketService) Send(ws *websocket.Conn, v any) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	if !s.Clients[ws] {
+		return errors.New("切断済みの接続です")
+	}
+
+	ws.SetWriteDeadline(time.Now().Add(s.Config.WriteTimeout))
+	if err := ws.WriteJSON(v); err != nil {
+		s.dropLocked(ws, "write_error")
+		return err
+	}
+	return nil
+}
+
+// Mutex を保持した状態で呼ぶ
+func (s *webSocketService) removeLocked(ws *websocket.Conn) {
+	if !s.Clients[ws] {
+		return
+	}
+	spaceID := s.Info[ws].SpaceID
+	delete(s.Clients, ws)
+	delete(s.Info, ws)
+
+	// 接続がなくなったスペースのラベルは消す（削除されたスペースのラベルが残り続けないように）
+	for _, client := range s.Info {
+		if client.SpaceID == spaceID {
+			s.Metrics.WSConnections.WithLabelValues(metrics.SpaceLabel(spaceID)).Dec()
+			return
 		}
 	}
+	s.Metrics.WSConnections.DeleteLabelValues(metrics.SpaceLabel(spaceID))
 }
 
+// Mutex を保持した状態で呼ぶ
+func (s *webSocketService) unsubscribeLocked(sub *subscriber) {
+	if _, ok := s.subscribers[sub]; !ok {
+		return
+	}
+	delete(s.subscribers, sub)
+	close(sub.ch)
+
+	for other := range s.subscribers {
+		if other.spaceID == sub.spaceID {
+			s.Metrics.SSEConnections.WithLabelValues(metrics.SpaceLabel(sub.spaceID)).Dec()
+			return
+		}
+	}
+	s.Metrics.SSEConnections.DeleteLabelValues(metrics.SpaceLabel(sub.spaceID))
+}
+
+// Mutex を保持した状態で呼ぶ
+func (s *webSocketService) dropLocked(ws *websocket.Conn, reason string) {
+	if s.Clients[ws] {
+		s.Metrics.DroppedClients.WithLabelValues(reason).Inc()
+	}
+	ws.Close()
+	s.removeLocked(ws)
+}
+
+// Broadcast チャネルのメッセージを配信する。Shutdown が呼ばれると
+// チャネルに残っているメッセージを配信し終えてから戻る。
 func (s *webSocketService) HandleMessages() {
+	defer close(s.stopped)
+
 	for {
-		// メッセージをチャネルから受け取る
-		msg := <-s.Broadcast
-
-		// 全クライアントにメッセージを送信（ブロードキャスト）
-		for client := range s.Clients {
-			err := client.WriteJSON(msg)
-			if err != nil {
-				client.Close()
-				delete(s.Clients, client) // 接続が切れたクライアントを削除
-			}
+		select {
+		case msg := <-s.Broadcast:
+			// 全クライアントにメッセージを送信（ブロードキャスト）
+			s.BroadcastMessage(context.Background(), msg)
+		case event := <-s.Events:
+			s.BroadcastEvent(context.Background(), event)
+		case reply := <-s.pings:
+			close(reply)
+		case <-s.quit:
+			s.drainBroadcast()
+			return
 		}
 	}
 }
 
+// チャネルに溜まっているメッセージ・イベントをすべて配信する
+func (s *webSocketService) drainBroadcast() {
+	for {
+		select {
+		case msg := <-s.Broadcast:
+			s.BroadcastMessage(context.Background(), msg)
+		case event := <-s.Events:
+			s.BroadcastEvent(context.Background(), event)
+		default:
+			return
+		}
+	}
+}
+
+// ハブのゴルーチン（HandleMessages）が動いていて、配信で詰まっていないか確認する
+func (s *webSocketService) Ping(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case s.pings <- reply:
+	case <-s.stopped:
+		return errors.New("WebSocket ハブは停止しています")
+	case <-ctx.Done():
+		return fmt.Errorf("WebSocket ハブが応答しません: %w", ctx.Err())
+	}
+	<-reply
+	return nil
+}
+
+// **WebSocket ハブを停止**
+// HandleMessages の終了（Broadcast の排出）を ctx の期限まで待ち、
+// その後すべてのクライアントに close フレームを送って切断する。SSE の購読もすべて解除する。
+func (s *webSocketService) Shutdown(ctx context.Context) error {
+	s.quitOnce.Do(func() { close(s.quit) })
+
+	var err error
+	select {
+	case <-s.stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	deadline := time.Now().Add(s.Config.WriteTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for client := range s.Clients {
+		_ = client.WriteControl(websocket.CloseMessage, closeMsg, deadline)
+		client.Close()
+		s.removeLocked(client)
+	}
+	for sub := range s.subscribers {
+		s.unsubscribeLocked(sub)
+	}
+
+	return err
+}
+
 func (s *webSocketService) GetClients() map[*websocket.Conn]bool {
 	return s.Clients
 }
@@ -2,15 +2,36 @@ package services
 
 import (
 	"chat/models"
+	"context"
 
 	"github.com/gorilla/websocket"
 )
 
+// 接続中のクライアントの情報
+type Client struct {
+	// ログに出す接続 ID
+	ConnID string
+	// 接続時に指定されたスペース（未指定なら 0）
+	SpaceID int
+	// 接続時に認証したユーザー名（未ログインなら空。コマンドの権限の確認に使う）
+	Username string
+	// 接続時に決めたレスポンスの言語（コマンドのエラーの表示に使う）
+	Language string
+}
+
+// WebSocket・SSE のクライアントへメッセージを配信するハブ
 type WebSocketService interface {
-	AddClient(ws *websocket.Conn)
+	AddClient(ws *websocket.Conn, client Client)
 	RemoveClient(ws *websocket.Conn)
-	SaveMessage(msg models.Message) error
-	BroadcastMessage(msg models.Message)
+	DropClient(ws *websocket.Conn, reason string)
+	BroadcastMessage(ctx context.Context, msg models.Message)
+	Send(ws *websocket.Conn, v any) error
+	Publish(ctx context.Context, msg models.Message) error
+	PublishEvent(ctx context.Context, event HubEvent) error
+	BroadcastEvent(ctx context.Context, event HubEvent)
+	Subscribe(spaceID int) (<-chan models.Message, func())
 	GetClients() map[*websocket.Conn]bool
 	HandleMessages()
+	Ping(ctx context.Context) error
+	Shutdown(ctx context.Context) error
 }
@@ -1,16 +1,20 @@
 package services_test
 
 import (
+	"chat/config"
+	"chat/logging"
+	"chat/metrics"
 	"chat/models"
 	"chat/services"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
 // **ダミーの WebSocket 接続を作成**
@@ -34,15 +38,36 @@ func newMockWebSocketConn(t *testing.T) *websocket.Conn {
 	return conn
 }
 
+// サービスに渡すサーバー側の接続と、送られたフレームを読むクライアント側の接続を作成する
+func newWebSocketPair(t *testing.T) (server, client *websocket.Conn) {
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("WebSocket upgrade error: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+srv.URL[len("http"):], nil)
+	if err != nil {
+		t.Fatalf("WebSocket connection error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return <-conns, client
+}
+
 func TestWebSocketService(t *testing.T) {
-	mockRepo := new(MockMessageRepository)
-	service := services.NewWebSocketService(mockRepo)
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
 
 	t.Run("AddClient and RemoveClient", func(t *testing.T) {
 		mockConn := newMockWebSocketConn(t)
 
 		// クライアント追加
-		service.AddClient(mockConn)
+		service.AddClient(mockConn, services.Client{ConnID: "conn-1", SpaceID: 1})
 
 		// **構造体にキャストして Clients へアクセス**
 		wsService := service.(services.WebSocketService)
@@ -55,25 +80,9 @@ func TestWebSocketService(t *testing.T) {
 		assert.False(t, wsService.GetClients()[mockConn])
 	})
 
-	t.Run("SaveMessage", func(t *testing.T) {
-		msg := models.Message{
-			SpaceID:   1,
-			Username:  "testuser",
-			Text:      "Test message",
-			CreatedAt: time.Now(),
-		}
-
-		mockRepo.On("CreateMessage", mock.AnythingOfType("models.Message")).Return(1, nil)
-
-		err := service.SaveMessage(msg)
-
-		assert.NoError(t, err)
-		mockRepo.AssertExpectations(t)
-	})
-
 	t.Run("BroadcastMessage", func(t *testing.T) {
 		mockConn := newMockWebSocketConn(t) // *websocket.Conn を返す
-		service.AddClient(mockConn)
+		service.AddClient(mockConn, services.Client{ConnID: "conn-1", SpaceID: 1})
 
 		msg := models.Message{
 			SpaceID:   1,
@@ -85,11 +94,236 @@ func TestWebSocketService(t *testing.T) {
 		// WebSocket の WriteMessage の呼び出しをモック
 		mockConn.WriteMessage(websocket.TextMessage, []byte("Test"))
 
-		service.BroadcastMessage(msg)
+		service.BroadcastMessage(context.Background(), msg)
 
 		// 少し待機して、ブロードキャストされるのを待つ
 		time.Sleep(time.Millisecond * 10)
 
-		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestWebSocketService_Shutdown(t *testing.T) {
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
+
+	done := make(chan struct{})
+	go func() {
+		service.HandleMessages()
+		close(done)
+	}()
+
+	mockConn := newMockWebSocketConn(t)
+	service.AddClient(mockConn, services.Client{ConnID: "conn-1", SpaceID: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := service.Shutdown(ctx)
+	assert.NoError(t, err)
+
+	// HandleMessages が終了していること
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("HandleMessages が終了しませんでした")
+	}
+
+	// すべてのクライアントが切断されていること
+	assert.Empty(t, service.GetClients())
+
+	// 二重呼び出しでも panic しないこと
+	assert.NoError(t, service.Shutdown(ctx))
+}
+
+func TestWebSocketService_Metrics(t *testing.T) {
+	m := metrics.New()
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), m)
+
+	conn1 := newMockWebSocketConn(t)
+	conn2 := newMockWebSocketConn(t)
+	conn3 := newMockWebSocketConn(t)
+	service.AddClient(conn1, services.Client{ConnID: "conn-1", SpaceID: 1})
+	service.AddClient(conn2, services.Client{ConnID: "conn-2", SpaceID: 1})
+	service.AddClient(conn3, services.Client{ConnID: "conn-3", SpaceID: 2})
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.WSConnections.WithLabelValues("1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WSConnections.WithLabelValues("2")))
+
+	service.RemoveClient(conn1)
+	service.DropClient(conn3, "rate_limited")
+	// 二重に削除しても数は変わらない
+	service.RemoveClient(conn3)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WSConnections.WithLabelValues("1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DroppedClients.WithLabelValues("rate_limited")))
+	// 接続がなくなったスペースのラベルは消す
+	assert.Equal(t, 1, testutil.CollectAndCount(m.WSConnections))
+
+	service.BroadcastMessage(context.Background(), models.Message{SpaceID: 1, Username: "testuser", Text: "hi"})
+	assert.Equal(t, 1, testutil.CollectAndCount(m.BroadcastDuration))
+}
+
+// メッセージはそのスペースを表示中の接続と、スペース未指定の接続にだけ送る
+func TestWebSocketService_BroadcastMessage_Space(t *testing.T) {
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
+
+	server1, client1 := newWebSocketPair(t)
+	server2, client2 := newWebSocketPair(t)
+	serverAll, clientAll := newWebSocketPair(t)
+	service.AddClient(server1, services.Client{ConnID: "conn-1", SpaceID: 1})
+	service.AddClient(server2, services.Client{ConnID: "conn-2", SpaceID: 2})
+	service.AddClient(serverAll, services.Client{ConnID: "conn-all"})
+
+	service.BroadcastMessage(context.Background(), models.Message{ID: 1, SpaceID: 1, Text: "space 1"})
+	service.BroadcastMessage(context.Background(), models.Message{ID: 2, SpaceID: 2, Text: "space 2"})
+
+	read := func(conn *websocket.Conn) []int {
+		var ids []int
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		for {
+			var msg models.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return ids
+			}
+			ids = append(ids, msg.ID)
+		}
+	}
+	assert.Equal(t, []int{1}, read(client1))
+	assert.Equal(t, []int{2}, read(client2))
+	assert.Equal(t, []int{1, 2}, read(clientAll))
+}
+
+func TestWebSocketService_Ping(t *testing.T) {
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
+
+	// HandleMessages が動いていなければ応答しない
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, service.Ping(ctx), context.DeadlineExceeded)
+
+	go service.HandleMessages()
+	assert.NoError(t, service.Ping(context.Background()))
+
+	assert.NoError(t, service.Shutdown(context.Background()))
+	assert.Error(t, service.Ping(context.Background()))
+}
+
+func TestWebSocketService_Subscribe(t *testing.T) {
+	m := metrics.New()
+	sse := config.Default().SSE
+	sse.Buffer = 1
+	service := services.NewWebSocketService(config.Default().WebSocket, sse, logging.Discard(), m)
+
+	events, unsubscribe := service.Subscribe(1)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.SSEConnections.WithLabelValues("1")))
+
+	// 同じスペースのメッセージだけを受け取る
+	service.BroadcastMessage(context.Background(), models.Message{ID: 1, SpaceID: 2, Text: "other"})
+	service.BroadcastMessage(context.Background(), models.Message{ID: 2, SpaceID: 1, Text: "hi"})
+	assert.Equal(t, 2, (<-events).ID)
+
+	// 受け取りが追いつかない購読者は解除される
+	service.BroadcastMessage(context.Background(), models.Message{ID: 3, SpaceID: 1})
+	service.BroadcastMessage(context.Background(), models.Message{ID: 4, SpaceID: 1})
+	assert.Equal(t, 3, (<-events).ID)
+	_, ok := <-events
+	assert.False(t, ok, "チャネルが閉じられていること")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DroppedClients.WithLabelValues("sse_buffer_full")))
+	assert.Equal(t, 0, testutil.CollectAndCount(m.SSEConnections))
+
+	// 解除済みでも呼んでよい
+	unsubscribe()
+	assert.Equal(t, 0, testutil.CollectAndCount(m.SSEConnections))
+}
+
+func TestWebSocketService_Publish(t *testing.T) {
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
+	go service.HandleMessages()
+
+	events, unsubscribe := service.Subscribe(1)
+	defer unsubscribe()
+
+	// REST API で保存したメッセージもハブから配信される
+	assert.NoError(t, service.Publish(context.Background(), models.Message{ID: 5, SpaceID: 1, Text: "via REST"}))
+	select {
+	case msg := <-events:
+		assert.Equal(t, "via REST", msg.Text)
+	case <-time.After(time.Second):
+		t.Fatal("配信されませんでした")
+	}
+
+	// 停止すると購読も解除され、以降は配信できない
+	assert.NoError(t, service.Shutdown(context.Background()))
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Error(t, service.Publish(context.Background(), models.Message{ID: 6, SpaceID: 1}))
+}
+
+// コマンドの応答は接続中のクライアントにだけ送る
+func TestWebSocketService_Send(t *testing.T) {
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
+
+	conn := newMockWebSocketConn(t)
+	assert.Error(t, service.Send(conn, services.CommandResponse{Command: "help"}))
+
+	service.AddClient(conn, services.Client{ConnID: "conn-1", SpaceID: 1})
+	assert.NoError(t, service.Send(conn, services.CommandResponse{Command: "help"}))
+}
+
+// 書き込まれたフレームを受け取る WebSocket 接続を作成（ハブが書き込む側を返す）
+func newRecordingWebSocketConn(t *testing.T) (*websocket.Conn, <-chan string) {
+	t.Helper()
+	frames := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			frames <- string(data)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[len("http"):], nil)
+	if err != nil {
+		t.Fatalf("WebSocket connection error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, frames
+}
+
+// ピン留めの変更はそのスペース（とスペース未指定）の接続にだけ type 付きで配信する
+func TestWebSocketService_PublishEvent(t *testing.T) {
+	service := services.NewWebSocketService(config.Default().WebSocket, config.Default().SSE, logging.Discard(), metrics.New())
+	go service.HandleMessages()
+
+	inSpace, inSpaceFrames := newRecordingWebSocketConn(t)
+	otherSpace, otherSpaceFrames := newRecordingWebSocketConn(t)
+	anySpace, anySpaceFrames := newRecordingWebSocketConn(t)
+	service.AddClient(inSpace, services.Client{ConnID: "conn-1", SpaceID: 1})
+	service.AddClient(otherSpace, services.Client{ConnID: "conn-2", SpaceID: 2})
+	service.AddClient(anySpace, services.Client{ConnID: "conn-3"})
+
+	event := services.HubEvent{Type: models.EventMessageUnpinned, SpaceID: 1, Data: map[string]int{"id": 5, "space_id": 1}}
+	assert.NoError(t, service.PublishEvent(context.Background(), event))
+
+	want := `{"type":"message.unpinned","space_id":1,"data":{"id":5,"space_id":1}}`
+	for _, frames := range []<-chan string{inSpaceFrames, anySpaceFrames} {
+		select {
+		case frame := <-frames:
+			assert.JSONEq(t, want, frame)
+		case <-time.After(time.Second):
+			t.Fatal("配信されませんでした")
+		}
+	}
+
+	// 停止時に残っているイベントも配信してから止まる
+	assert.NoError(t, service.Shutdown(context.Background()))
+	assert.Empty(t, otherSpaceFrames)
+	assert.Error(t, service.PublishEvent(context.Background(), event))
+}
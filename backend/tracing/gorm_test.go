@@ -0,0 +1,54 @@
+package tracing_test
+
+import (
+	"chat/models"
+	"chat/tracing"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormPlugin(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(tracing.GormPlugin()))
+
+	ctx, parent := tracing.Start(context.Background(), "SpaceService.GetSpaces")
+
+	mock.ExpectQuery(`SELECT \* FROM "spaces"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "general"))
+	var spaces []models.Space
+	require.NoError(t, db.WithContext(ctx).Find(&spaces).Error)
+
+	mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnError(errors.New("connection refused"))
+	var user models.User
+	require.Error(t, db.WithContext(ctx).Where("username = ?", "alice").First(&user).Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	query := spans[0]
+	assert.Equal(t, "db.query", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Contains(t, query.Attributes(), attribute.String("db.collection.name", "spaces"))
+
+	// パラメーターの値はスパンに残さない
+	failed := spans[1]
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	for _, attr := range failed.Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "alice")
+	}
+}
@@ -0,0 +1,81 @@
+package tracing_test
+
+import (
+	"bytes"
+	"chat/apperrors"
+	"chat/config"
+	"chat/tracing"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// テスト中に記録されたスパンを返すレコーダーをグローバルに設定する
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	_, ok := tracing.Start(ctx, "ok")
+	tracing.End(ok, nil)
+	_, failed := tracing.Start(ctx, "failed")
+	tracing.End(failed, errors.New("connection reset"))
+	_, invalid := tracing.Start(ctx, "invalid")
+	tracing.End(invalid, apperrors.New(apperrors.ErrValidation, "message_invalid", "不正です"))
+	tracing.End(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for _, span := range spans[:3] {
+		assert.Equal(t, spans[3].SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1)
+
+	// 利用者の入力によるエラーは失敗扱いにしない
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+	assert.Contains(t, spans[2].Attributes(), attribute.String("error.code", "message_invalid"))
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var buf bytes.Buffer
+	shutdown, err := tracing.Setup(context.Background(), config.TracingConfig{
+		Enabled: true, Exporter: "stdout", ServiceName: "chat-test", SampleRatio: 1,
+	}, &buf)
+	require.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "MessageService.CreateMessage")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, buf.String(), `"Name":"MessageService.CreateMessage"`)
+	assert.Contains(t, buf.String(), "chat-test")
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := tracing.Setup(context.Background(), config.TracingConfig{}, &buf)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Empty(t, buf.String())
+}
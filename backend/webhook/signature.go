@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// 送信する Webhook のヘッダー
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// 本文の署名を返す
+//
+//	sha256=<hex(HMAC-SHA256(secret, "<timestamp>.<body>"))>
+//
+// 時刻も署名に含めるため、受信側は古い時刻のリクエストを拒否すれば再送攻撃を防げる。
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 署名を検証する（受信側の実装例・テスト用）
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// 受信用 Webhook のトークンを保存用のハッシュにする（トークン自体は保存しない）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// トークンがハッシュと一致するか（定数時間で比較する）
+func VerifyToken(token, hash string) bool {
+	return hmac.Equal([]byte(HashToken(token)), []byte(hash))
+}
+
+// 署名の鍵・受信用 Webhook のトークンを生成する
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,49 @@
+package webhook_test
+
+import (
+	"chat/webhook"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"message.created"}`)
+	sig := webhook.Sign("secret", 1700000000, body)
+
+	// echo -n '1700000000.{"event":"message.created"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=39e442eaff327dcb8b1928c5e20f0eb2ae9df15a96a515e319999417b326c228", sig)
+	assert.True(t, webhook.Verify("secret", 1700000000, body, sig))
+	assert.False(t, webhook.Verify("other", 1700000000, body, sig), "鍵が違う")
+	assert.False(t, webhook.Verify("secret", 1700000001, body, sig), "時刻が違う")
+	assert.False(t, webhook.Verify("secret", 1700000000, []byte(`{}`), sig), "本文が違う")
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := webhook.NewSecret()
+	require.NoError(t, err)
+	b, err := webhook.NewSecret()
+	require.NoError(t, err)
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, b)
+}
+
+func TestNewClient_PrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// ループバックへの送信は拒否する
+	_, err := webhook.NewClient(time.Second, false).Get(server.URL)
+	assert.ErrorIs(t, err, webhook.ErrForbiddenAddress)
+
+	resp, err := webhook.NewClient(time.Second, true).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
@@ -16,7 +16,8 @@ const App = () => {
   const [showSpaceForm, setShowSpaceForm] = useState(false);
   const { sendMessage, lastMessage, readyState } = useWebSocket(
     token && selectedSpace ? `ws://chat-elb-2056070132.ap-northeast-1.elb.amazonaws.com/ws?spaceId=${selectedSpace}` : null,
-    { shouldReconnect: () => true }
+    // ブラウザの WebSocket はヘッダーを付けられないため、トークンはサブプロトコルで送る
+    { protocols: ['bearer', token], shouldReconnect: () => true }
   );
 
   // スペースが変更されたらローカルストレージに保存
@@ -41,11 +42,37 @@ const App = () => {
     }
   };
 
+  // 同じ ID のメッセージは追加しない（自分の投稿は POST の応答と WebSocket の両方で届く）
+  const appendMessage = (message) => {
+    setMessages((prev) => {
+      if (!Array.isArray(prev)) {
+        return [message];
+      }
+      if (message.id && prev.some((msg) => msg.id === message.id)) {
+        return prev;
+      }
+      return [...prev, message];
+    });
+  };
+
   // WebSocketで受信したメッセージを処理
   useEffect(() => {
     if (lastMessage !== null) {
-      const newMessage = JSON.parse(lastMessage.data);
-      setMessages((prev) => (Array.isArray(prev) ? [...prev, newMessage] : [newMessage]));
+      const data = JSON.parse(lastMessage.data);
+      // ピン留めの変更（type 付きのイベント）は表示中のメッセージに反映する
+      if (data.type === 'message.pinned' || data.type === 'message.unpinned') {
+        const pinned = data.type === 'message.pinned';
+        setMessages((prev) =>
+          Array.isArray(prev)
+            ? prev.map((msg) => (msg.id === data.data.id ? { ...msg, pinned } : msg))
+            : prev
+        );
+        return;
+      }
+      if (data.type) {
+        return;
+      }
+      appendMessage(data);
     }
   }, [lastMessage]);
 
@@ -83,7 +110,7 @@ const App = () => {
       }
 
       const newMessage = await response.json();
-      setMessages((prevMessages) => [...prevMessages, newMessage]);
+      appendMessage(newMessage);
     } catch (error) {
       console.error('通信エラー:', error);
     }
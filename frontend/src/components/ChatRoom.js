@@ -17,9 +17,16 @@ const ChatRoom = ({ messages, onDeleteMessage }) => {
       <ul className="space-y-2">
         {messages.map((msg) => (
           <li key={msg.id} className="flex justify-between items-center">
-            <span className="text-gray-700">
-              <strong className="text-blue-500">{msg.username}</strong>: {msg.text}
-            </span>
+            <div className="text-gray-700">
+              {msg.pinned && <span title="ピン留め">📌 </span>}
+              <strong className="text-blue-500">{msg.username}</strong>:{' '}
+              {msg.html ? (
+                // サーバーで Markdown を変換・サニタイズ済み（以前のメッセージは html が空なので平文で表示）
+                <div className="message-body" dangerouslySetInnerHTML={{ __html: msg.html }} />
+              ) : (
+                msg.text
+              )}
+            </div>
             <button
               onClick={() => {
                 console.log(`削除対象メッセージ:`, msg); // デバッグ用ログ